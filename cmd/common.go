@@ -9,6 +9,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap/data"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	log "github.com/sirupsen/logrus"
 )
@@ -25,6 +26,8 @@ func Init() {
 
 func Release() {
 	op.StopDeviceCleanupScheduler()
+	traffic.Flush()
+	op.CloseDirCache()
 	db.Close()
 }
 
@@ -14,10 +14,15 @@ import (
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
+	"github.com/OpenListTeam/OpenList/v4/internal/bandwidth"
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/job"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
+	"github.com/OpenListTeam/OpenList/v4/internal/tus"
+	"github.com/OpenListTeam/OpenList/v4/internal/webhook"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server"
 	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
@@ -49,6 +54,13 @@ the address is defined in config file`,
 		bootstrap.InitTaskManager()
 		go op.PeriodicCleanExpiredUsers(context.Background(), time.Minute)
 		op.StartDeviceCleanupScheduler()
+		op.StartRecycleBinPurgeScheduler()
+		op.StartFileVersionPurgeScheduler()
+		job.Start()
+		webhook.Start()
+		tus.StartExpirationScheduler()
+		bandwidth.Start()
+		traffic.Start()
 		if !flags.Debug && !flags.Dev {
 			gin.SetMode(gin.ReleaseMode)
 		}
@@ -545,4 +545,26 @@ func (d *Alias) ResolveLinkCacheMode(path string) driver.LinkCacheMode {
 	return 0
 }
 
+// Identify returns the key of the object the path points to in the aliased storages,
+// the first one holding it like in Get
+func (d *Alias) Identify(ctx context.Context, path string) (string, error) {
+	root, sub := d.getRootAndPath(path)
+	dsts, ok := d.pathMap[root]
+	if !ok {
+		return "", errs.ObjectNotFound
+	}
+	for _, dst := range dsts {
+		rawPath := stdpath.Join(dst, sub)
+		if _, err := fs.Get(ctx, rawPath, &fs.GetArgs{NoLog: true}); err != nil {
+			continue
+		}
+		storage, actualPath, err := op.GetStorageAndActualPath(rawPath)
+		if err != nil {
+			continue
+		}
+		return op.Identify(ctx, storage, actualPath), nil
+	}
+	return "", errs.ObjectNotFound
+}
+
 var _ driver.Driver = (*Alias)(nil)
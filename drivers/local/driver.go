@@ -3,8 +3,11 @@ package local
 import (
 	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/http"
 	"os"
@@ -18,8 +21,10 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/times"
@@ -433,6 +438,73 @@ func (d *Local) Put(ctx context.Context, dstDir model.Obj, stream model.FileStre
 	return nil
 }
 
+// partPath returns where the upload to fullPath is staged, in the parts folder of the storage
+// which is on the same disk as the destination in most cases, so the part is renamed to it
+func (d *Local) partPath(fullPath string) string {
+	h := sha1.Sum([]byte(fullPath))
+	return filepath.Join(d.GetRootPath(), filepath.FromSlash(op.PartsDir), hex.EncodeToString(h[:]))
+}
+
+// PutResume stages the file in the parts folder, the staged part is kept
+// when the upload is interrupted and renamed to the file once it's complete
+func (d *Local) PutResume(ctx context.Context, dstDir model.Obj, file model.FileStreamer, resume bool, up driver.UpdateProgress) error {
+	fullPath := filepath.Join(dstDir.GetPath(), file.GetName())
+	partPath := d.partPath(fullPath)
+	if err := os.MkdirAll(filepath.Dir(partPath), 0o777); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(partPath, os.O_WRONLY|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	var offset int64
+	if resume {
+		if fi, err := out.Stat(); err == nil && fi.Size() <= file.GetSize() {
+			offset = fi.Size()
+		}
+	}
+	if err = out.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err = out.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	var in io.Reader = file
+	if offset > 0 {
+		in, err = file.RangeRead(http_range.Range{Start: offset, Length: -1})
+		if err != nil {
+			return err
+		}
+		up = model.UpdateProgressWithRange(up, float64(offset)*100/float64(file.GetSize()), 100)
+	}
+	if err = utils.CopyWithCtx(ctx, out, in, file.GetSize()-offset, up); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(partPath, fullPath); err != nil {
+		return err
+	}
+	if err = os.Chtimes(fullPath, file.ModTime(), file.ModTime()); err != nil {
+		log.Errorf("[local] failed to change time of %s: %s", fullPath, err)
+	}
+	if d.directoryMap.Has(dstDir.GetPath()) {
+		d.directoryMap.UpdateDirSize(dstDir.GetPath())
+		d.directoryMap.UpdateDirParents(dstDir.GetPath())
+	}
+	return nil
+}
+
+func (d *Local) DiscardResume(ctx context.Context, dstDir model.Obj, name string) error {
+	err := os.Remove(d.partPath(filepath.Join(dstDir.GetPath(), name)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 func (d *Local) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	du, err := getDiskUsage(d.RootFolderPath)
 	if err != nil {
@@ -443,4 +515,13 @@ func (d *Local) GetDetails(ctx context.Context) (*model.StorageDetails, error) {
 	}, nil
 }
 
+// Identify returns the path of the file on the disk, with the symlinks resolved
+func (d *Local) Identify(ctx context.Context, path string) (string, error) {
+	path = filepath.Join(d.GetRootPath(), path)
+	if p, err := filepath.EvalSymlinks(path); err == nil {
+		path = p
+	}
+	return "local:" + path, nil
+}
+
 var _ driver.Driver = (*Local)(nil)
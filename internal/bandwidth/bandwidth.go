@@ -0,0 +1,233 @@
+// Package bandwidth applies the time-of-day schedule of the traffic settings, a period
+// of the schedule overrides the speed limits and the task threads while it's active
+package bandwidth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Keys are the settings a period can override
+var Keys = []string{
+	conf.StreamMaxClientDownloadSpeed,
+	conf.StreamMaxClientUploadSpeed,
+	conf.StreamMaxServerDownloadSpeed,
+	conf.StreamMaxServerUploadSpeed,
+	conf.TaskOfflineDownloadThreadsNum,
+	conf.TaskOfflineDownloadTransferThreadsNum,
+	conf.TaskUploadThreadsNum,
+	conf.TaskCopyThreadsNum,
+	conf.TaskMoveThreadsNum,
+	conf.TaskDecompressDownloadThreadsNum,
+	conf.TaskDecompressUploadThreadsNum,
+	conf.TaskSyncThreadsNum,
+	conf.TaskDedupeThreadsNum,
+	conf.TaskChecksumThreadsNum,
+}
+
+// Period is a daily time range from Start to End, like "09:00" and "18:00". It goes over
+// midnight when End is before Start and lasts all day when they are equal. Weekdays,
+// 0 for Sunday, are the days it starts on, every day when empty.
+// Limits are the values of the settings in the period, in the units of the settings.
+type Period struct {
+	Name     string         `json:"name"`
+	Start    string         `json:"start"`
+	End      string         `json:"end"`
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+	Limits   map[string]int `json:"limits"`
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("invalid time [%s], expect hh:mm", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (p *Period) label(i int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// Active reports whether the period covers now
+func (p *Period) Active(now time.Time) bool {
+	start, err := parseClock(p.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(p.End)
+	if err != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+	switch {
+	case start == end:
+	case start < end:
+		if minute < start || minute >= end {
+			return false
+		}
+	case minute < end:
+		// it started the day before
+		day = (day + 6) % 7
+	case minute < start:
+		return false
+	}
+	return len(p.Weekdays) == 0 || utils.SliceContains(p.Weekdays, day)
+}
+
+// Parse parses and checks the schedule, an empty one has no period
+func Parse(s string) ([]Period, error) {
+	var periods []Period
+	if s == "" {
+		return periods, nil
+	}
+	if err := utils.Json.UnmarshalFromString(s, &periods); err != nil {
+		return nil, errors.Wrap(err, "invalid bandwidth schedule")
+	}
+	for i, p := range periods {
+		name := p.label(i)
+		if _, err := parseClock(p.Start); err != nil {
+			return nil, errors.WithMessagef(err, "period %s", name)
+		}
+		if _, err := parseClock(p.End); err != nil {
+			return nil, errors.WithMessagef(err, "period %s", name)
+		}
+		for _, d := range p.Weekdays {
+			if d < time.Sunday || d > time.Saturday {
+				return nil, errors.Errorf("period %s: invalid weekday %d", name, d)
+			}
+		}
+		for key := range p.Limits {
+			if !utils.SliceContains(Keys, key) {
+				return nil, errors.Errorf("period %s: [%s] can't be scheduled", name, key)
+			}
+		}
+	}
+	return periods, nil
+}
+
+var (
+	parsedMu  sync.Mutex
+	parsedRaw string
+	parsed    []Period
+)
+
+func schedule() []Period {
+	raw := setting.GetStr(conf.BandwidthSchedule)
+	parsedMu.Lock()
+	defer parsedMu.Unlock()
+	if raw != parsedRaw {
+		periods, err := Parse(raw)
+		if err != nil {
+			log.Warnf("ignore the bandwidth schedule: %+v", err)
+		}
+		parsedRaw, parsed = raw, periods
+	}
+	return parsed
+}
+
+// ActivePeriod returns the first period of the schedule which covers now and its index,
+// or nil and -1
+func ActivePeriod(now time.Time) (*Period, int) {
+	periods := schedule()
+	for i := range periods {
+		if periods[i].Active(now) {
+			return &periods[i], i
+		}
+	}
+	return nil, -1
+}
+
+// GetInt returns the value of the setting in the active period, or the setting itself
+func GetInt(key string, defaultVal int) int {
+	if p, _ := ActivePeriod(time.Now()); p != nil {
+		if v, ok := p.Limits[key]; ok {
+			return v
+		}
+	}
+	return setting.GetInt(key, defaultVal)
+}
+
+var (
+	callbacks   []func()
+	activeIndex = -1
+	checkCron   *cron.Cron
+)
+
+// OnChange registers f to be called when the settings or the active period change,
+// f reads the limits with GetInt
+func OnChange(f func()) {
+	callbacks = append(callbacks, f)
+	op.RegisterSettingChangingCallback(f)
+}
+
+// check calls the callbacks when another period is active, the changes
+// of the schedule itself call them as the other settings do
+func check() {
+	_, i := ActivePeriod(time.Now())
+	if i == activeIndex {
+		return
+	}
+	activeIndex = i
+	log.Infof("bandwidth schedule: switch to %s", Status().Period)
+	for _, f := range callbacks {
+		f()
+	}
+}
+
+// Start checks every minute whether another period is active
+func Start() {
+	if checkCron != nil {
+		return
+	}
+	check()
+	checkCron = cron.NewCron(time.Minute)
+	checkCron.Do(check)
+}
+
+type StatusResp struct {
+	// Period is the name of the active period, "default" when none is
+	Period string         `json:"period"`
+	Limits map[string]int `json:"limits"`
+}
+
+// Status returns the active period and the limits in force
+func Status() StatusResp {
+	resp := StatusResp{Period: "default", Limits: make(map[string]int, len(Keys))}
+	p, i := ActivePeriod(time.Now())
+	if p != nil {
+		resp.Period = p.label(i)
+	}
+	for _, key := range Keys {
+		v, ok := 0, false
+		if p != nil {
+			v, ok = p.Limits[key]
+		}
+		if !ok {
+			v = setting.GetInt(key, -1)
+		}
+		resp.Limits[key] = v
+	}
+	return resp
+}
+
+func init() {
+	op.RegisterSettingItemHook(conf.BandwidthSchedule, func(item *model.SettingItem) error {
+		_, err := Parse(item.Value)
+		return err
+	})
+}
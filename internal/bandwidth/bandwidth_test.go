@@ -0,0 +1,85 @@
+package bandwidth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestPeriodActive(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day int, clock string) time.Time {
+		c, _ := time.Parse("15:04", clock)
+		return time.Date(2024, 1, day, c.Hour(), c.Minute(), 0, 0, time.Local)
+	}
+	office := Period{Start: "09:00", End: "18:00", Weekdays: []time.Weekday{time.Monday, time.Friday}}
+	night := Period{Start: "22:00", End: "06:00", Weekdays: []time.Weekday{time.Sunday}}
+	cases := []struct {
+		p    Period
+		now  time.Time
+		want bool
+	}{
+		{office, at(1, "09:00"), true},
+		{office, at(1, "18:00"), false},
+		{office, at(2, "12:00"), false},
+		{night, at(7, "23:00"), true},
+		// the night of Sunday goes on on Monday
+		{night, at(8, "05:59"), true},
+		{night, at(2, "05:59"), false},
+		{night, at(7, "12:00"), false},
+		{Period{Start: "00:00", End: "00:00"}, at(3, "12:00"), true},
+	}
+	for i, c := range cases {
+		if got := c.p.Active(c.now); got != c.want {
+			t.Errorf("case %d: expect %v, got %v", i, c.want, got)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	for _, s := range []string{
+		`[{"start":"25:00","end":"18:00"}]`,
+		`[{"start":"09:00","end":"18:00","weekdays":[7]}]`,
+		`[{"start":"09:00","end":"18:00","limits":{"token":1}}]`,
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("expect %s to be invalid", s)
+		}
+	}
+	items := []model.SettingItem{
+		{Key: conf.StreamMaxServerUploadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC},
+		{Key: conf.BandwidthSchedule, Value: `[{"name":"always","start":"00:00","end":"00:00","limits":{"max_server_upload_speed":10240}}]`, Type: conf.TypeText, Group: model.TRAFFIC},
+	}
+	if err := op.SaveSettingItems(items); err != nil {
+		t.Fatal(err)
+	}
+	if v := GetInt(conf.StreamMaxServerUploadSpeed, -1); v != 10240 {
+		t.Errorf("expect the limit of the period, got %d", v)
+	}
+	if v := GetInt(conf.StreamMaxClientUploadSpeed, -1); v != -1 {
+		t.Errorf("expect the setting out of the limits of the period, got %d", v)
+	}
+	if s := Status(); s.Period != "always" || s.Limits[conf.StreamMaxServerUploadSpeed] != 10240 {
+		t.Errorf("unexpected status %+v", s)
+	}
+	// the invalid schedules are not saved
+	items[1].Value = `[{"start":"09:00"}]`
+	if err := op.SaveSettingItems(items[1:]); err == nil {
+		t.Errorf("expect the invalid schedule to be refused")
+	}
+}
@@ -0,0 +1,243 @@
+// Package blockcache keeps the blocks of the proxied files on the local disk, so that the
+// repeated and overlapping range reads of a file don't fetch it from the upstream again.
+// The blocks of a file are keyed by its storage, path, size and modified time, and the least
+// recently used blocks are removed once the cache is over its size.
+package blockcache
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type block struct {
+	key  string // the path of the block file relative to the cache dir
+	size int64
+}
+
+// fetch is a block being fetched by a reader, the other readers of the block wait for it
+type fetch struct {
+	done chan struct{}
+	err  error
+}
+
+type Cache struct {
+	dir       string
+	blockSize int64
+	maxSize   int64
+
+	mu       sync.Mutex
+	lru      *list.List // of *block, the least recently used first
+	blocks   map[string]*list.Element
+	size     int64
+	fetching map[string]*fetch
+}
+
+// New returns the cache of the blocks in dir, which are found there already too
+func New(dir string, blockSize, maxSize int64) (*Cache, error) {
+	if blockSize <= 0 {
+		return nil, errors.New("the block size must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c := &Cache{
+		dir:       dir,
+		blockSize: blockSize,
+		maxSize:   maxSize,
+		lru:       list.New(),
+		blocks:    make(map[string]*list.Element),
+		fetching:  make(map[string]*fetch),
+	}
+	c.load()
+	return c, nil
+}
+
+// load adds the blocks left in the dir, the least recently modified are the least recently used
+func (c *Cache) load() {
+	type found struct {
+		block
+		modified time.Time
+	}
+	var blocks []found
+	_ = filepath.WalkDir(c.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(p, ".tmp") {
+			_ = os.Remove(p)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(c.dir, p)
+		if err != nil {
+			return nil
+		}
+		blocks = append(blocks, found{
+			block:    block{key: filepath.ToSlash(rel), size: info.Size()},
+			modified: info.ModTime(),
+		})
+		return nil
+	})
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].modified.Before(blocks[j].modified)
+	})
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range blocks {
+		b := b.block
+		c.blocks[b.key] = c.lru.PushBack(&b)
+		c.size += b.size
+	}
+	c.evict()
+}
+
+func (c *Cache) BlockSize() int64 {
+	return c.blockSize
+}
+
+// FileKey returns the key of the blocks of a file, which changes with the file
+func (c *Cache) FileKey(storageID uint, path string, size int64, modified time.Time) string {
+	h := sha1.Sum(fmt.Appendf(nil, "%d\x00%s\x00%d\x00%d\x00%d", storageID, path, size, modified.UnixNano(), c.blockSize))
+	return hex.EncodeToString(h[:])
+}
+
+func blockKey(file string, index int64) string {
+	return file[:2] + "/" + file + "/" + strconv.FormatInt(index, 10)
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key))
+}
+
+// read reads the block into buf, which is as long as the block, and marks it as recently used
+func (c *Cache) read(key string, buf []byte) bool {
+	c.mu.Lock()
+	e, ok := c.blocks[key]
+	if ok {
+		c.lru.MoveToBack(e)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	f, err := os.Open(c.path(key))
+	if err == nil {
+		_, err = io.ReadFull(f, buf)
+		_ = f.Close()
+	}
+	if err != nil {
+		log.Warnf("failed read the cached block %s: %+v", key, err)
+		c.mu.Lock()
+		if cur, ok := c.blocks[key]; ok && cur == e {
+			c.remove(e)
+		}
+		c.mu.Unlock()
+		return false
+	}
+	return true
+}
+
+// startFetch returns the fetch of the block and whether the caller is to fetch it,
+// or nil if the block is cached already
+func (c *Cache) startFetch(key string) (*fetch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.blocks[key]; ok {
+		return nil, false
+	}
+	if f, ok := c.fetching[key]; ok {
+		return f, false
+	}
+	f := &fetch{done: make(chan struct{})}
+	c.fetching[key] = f
+	return f, true
+}
+
+// endFetch adds the fetched block and wakes up the readers waiting for it
+func (c *Cache) endFetch(key string, f *fetch, data []byte, err error) {
+	if err == nil {
+		err = c.put(key, data)
+		if err != nil {
+			log.Warnf("failed cache the block %s: %+v", key, err)
+		}
+	}
+	c.mu.Lock()
+	delete(c.fetching, key)
+	c.mu.Unlock()
+	f.err = err
+	close(f.done)
+}
+
+func (c *Cache) put(key string, data []byte) error {
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o777); err != nil {
+		return errors.WithStack(err)
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		_ = os.Remove(tmp)
+		return errors.WithStack(err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		_ = os.Remove(tmp)
+		return errors.WithStack(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.blocks[key]; ok {
+		c.remove(e)
+	}
+	c.blocks[key] = c.lru.PushBack(&block{key: key, size: int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+	return nil
+}
+
+// evict removes the least recently used blocks until the cache is within its size,
+// it must be called with c.mu held
+func (c *Cache) evict() {
+	for c.size > c.maxSize {
+		e := c.lru.Front()
+		if e == nil {
+			return
+		}
+		c.remove(e)
+	}
+}
+
+// remove must be called with c.mu held
+func (c *Cache) remove(e *list.Element) {
+	b := c.lru.Remove(e).(*block)
+	delete(c.blocks, b.key)
+	c.size -= b.size
+	p := c.path(b.key)
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		log.Warnf("failed remove the cached block %s: %+v", b.key, err)
+	}
+	// the dir of the file is removed with its last block
+	_ = os.Remove(filepath.Dir(p))
+}
+
+// Size returns the size of the cached blocks
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
@@ -0,0 +1,179 @@
+package blockcache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+)
+
+const testBlockSize = 16
+
+type upstream struct {
+	data     []byte
+	requests atomic.Int64
+	read     atomic.Int64
+}
+
+func (u *upstream) RangeRead(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	u.requests.Add(1)
+	r := io.NewSectionReader(bytes.NewReader(u.data), httpRange.Start, httpRange.Length)
+	return io.NopCloser(&countingReader{Reader: r, n: &u.read}), nil
+}
+
+type countingReader struct {
+	io.Reader
+	n *atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+func newUpstream(size int) *upstream {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return &upstream{data: data}
+}
+
+func readRange(t *testing.T, c *Cache, u *upstream, file string, start, length int64) {
+	t.Helper()
+	rc, err := c.Wrap(u, file, int64(len(u.data))).RangeRead(context.Background(), http_range.Range{Start: start, Length: length})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	end := int64(len(u.data))
+	if length >= 0 && start+length < end {
+		end = start + length
+	}
+	if !bytes.Equal(got, u.data[start:end]) {
+		t.Errorf("unexpected data of [%d, %d)", start, end)
+	}
+}
+
+func TestRangeRead(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, testBlockSize, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newUpstream(10*testBlockSize - 3)
+	file := c.FileKey(1, "/a/b.mp4", int64(len(u.data)), time.Unix(1, 0))
+
+	// the blocks 0-4 are fetched in one request
+	readRange(t, c, u, file, 5, 70)
+	if u.requests.Load() != 1 || u.read.Load() != 5*testBlockSize {
+		t.Errorf("expect the covered blocks to be fetched at once, got %d requests of %d bytes", u.requests.Load(), u.read.Load())
+	}
+	readRange(t, c, u, file, 20, 40)
+	if u.requests.Load() != 1 {
+		t.Errorf("expect the cached blocks to be read from the disk")
+	}
+	readRange(t, c, u, file, 0, -1)
+	if u.requests.Load() != 2 || u.read.Load() != int64(len(u.data)) {
+		t.Errorf("expect only the blocks not cached to be fetched, got %d requests of %d bytes", u.requests.Load(), u.read.Load())
+	}
+
+	// another version of the file has other blocks
+	other := c.FileKey(1, "/a/b.mp4", int64(len(u.data)), time.Unix(2, 0))
+	readRange(t, c, u, other, 0, 1)
+	if u.requests.Load() != 3 {
+		t.Errorf("expect the modified file not to be read from the cache")
+	}
+
+	// the blocks are found again
+	reopened, err := New(dir, testBlockSize, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Size() != c.Size() || c.Size() != int64(len(u.data))+testBlockSize {
+		t.Errorf("expect the blocks to be loaded, got %d of %d bytes", reopened.Size(), c.Size())
+	}
+}
+
+func TestSharedFetch(t *testing.T) {
+	c, err := New(t.TempDir(), testBlockSize, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newUpstream(64 * testBlockSize)
+	file := c.FileKey(1, "/video.mkv", int64(len(u.data)), time.Time{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			readRange(t, c, u, file, 0, -1)
+		}()
+	}
+	wg.Wait()
+	if u.read.Load() != int64(len(u.data)) {
+		t.Errorf("expect each block to be fetched once, got %d bytes of %d", u.read.Load(), len(u.data))
+	}
+}
+
+func TestEvict(t *testing.T) {
+	c, err := New(t.TempDir(), testBlockSize, 3*testBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newUpstream(8 * testBlockSize)
+	file := c.FileKey(1, "/a.bin", int64(len(u.data)), time.Time{})
+	readRange(t, c, u, file, 0, -1)
+	if c.Size() != 3*testBlockSize {
+		t.Errorf("expect the cache to be within its size, got %d", c.Size())
+	}
+	// the last blocks are kept
+	requests := u.requests.Load()
+	readRange(t, c, u, file, 5*testBlockSize, -1)
+	if u.requests.Load() != requests {
+		t.Errorf("expect the recently used blocks to be kept")
+	}
+	readRange(t, c, u, file, 0, 1)
+	if u.requests.Load() != requests+1 {
+		t.Errorf("expect the least recently used blocks to be removed")
+	}
+}
+
+func TestLinkType(t *testing.T) {
+	c, err := New(t.TempDir(), testBlockSize, 8*testBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultMu.Lock()
+	defaultCache, defaultLoaded = c, true
+	defaultMu.Unlock()
+	defer func() {
+		defaultMu.Lock()
+		defaultCache, defaultLoaded = nil, false
+		defaultMu.Unlock()
+	}()
+	storage := &model.Storage{ID: 1}
+	storage.BlockCache = true
+	file := &model.Object{Name: "a.bin", Size: 4 * testBlockSize}
+	u := newUpstream(4 * testBlockSize)
+	link := &model.Link{RangeReader: u, Concurrency: 2}
+	if got := Link(storage, "/a.bin", file, "thumb", link); got != link {
+		t.Errorf("expect the thumbnail link not to be cached")
+	}
+	if got := Link(storage, "/a.bin", file, "", link); got == link || got.ContentLength != int64(len(u.data)) {
+		t.Errorf("expect the file link to be read through the cache")
+	}
+}
@@ -0,0 +1,201 @@
+package blockcache
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+type rangeReader struct {
+	c    *Cache
+	rr   model.RangeReaderIF
+	file string
+	size int64
+}
+
+// Wrap returns a RangeReader which reads the blocks of the file from the cache,
+// and those not cached from rr
+func (c *Cache) Wrap(rr model.RangeReaderIF, file string, size int64) model.RangeReaderIF {
+	return &rangeReader{c: c, rr: rr, file: file, size: size}
+}
+
+func (r *rangeReader) RangeRead(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	start, length := httpRange.Start, httpRange.Length
+	if start > r.size {
+		start = r.size
+	}
+	if length < 0 || start+length > r.size {
+		length = r.size - start
+	}
+	return &reader{rangeReader: r, ctx: ctx, pos: start, end: start + length, index: -1}, nil
+}
+
+type reader struct {
+	*rangeReader
+	ctx   context.Context
+	pos   int64
+	end   int64
+	buf   []byte // the data of the block index
+	index int64
+	// up reads the blocks not cached from upPos on
+	up    io.ReadCloser
+	upPos int64
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.pos >= r.end {
+		return 0, io.EOF
+	}
+	index := r.pos / r.c.blockSize
+	if index != r.index {
+		if err := r.load(index); err != nil {
+			return 0, err
+		}
+	}
+	off := r.pos - index*r.c.blockSize
+	n := copy(p, r.buf[off:min(int64(len(r.buf)), off+r.end-r.pos)])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// load reads the block from the cache, or fetches it if it's not cached or being fetched
+func (r *reader) load(index int64) error {
+	start := index * r.c.blockSize
+	length := min(r.c.blockSize, r.size-start)
+	if int64(cap(r.buf)) < length {
+		r.buf = make([]byte, length)
+	}
+	buf := r.buf[:length]
+	key := blockKey(r.file, index)
+	for {
+		if r.c.read(key, buf) {
+			break
+		}
+		f, fetcher := r.c.startFetch(key)
+		if f == nil {
+			continue
+		}
+		if !fetcher {
+			select {
+			case <-f.done:
+			case <-r.ctx.Done():
+				return r.ctx.Err()
+			}
+			// read it from the cache, or fetch it if the fetch failed
+			continue
+		}
+		err := r.fetch(start, buf)
+		r.c.endFetch(key, f, buf, err)
+		if err != nil {
+			return err
+		}
+		break
+	}
+	r.buf, r.index = buf, index
+	return nil
+}
+
+// fetch reads the block at start from the upstream, which goes on to the end of the range
+// in one request unless the blocks in between are read from the cache
+func (r *reader) fetch(start int64, buf []byte) error {
+	if r.up != nil && r.upPos != start {
+		_ = r.up.Close()
+		r.up = nil
+	}
+	if r.up == nil {
+		end := min(r.size, (r.end+r.c.blockSize-1)/r.c.blockSize*r.c.blockSize)
+		rc, err := r.rr.RangeRead(r.ctx, http_range.Range{Start: start, Length: end - start})
+		if err != nil {
+			return err
+		}
+		r.up, r.upPos = rc, start
+	}
+	n, err := io.ReadFull(r.up, buf)
+	r.upPos += int64(n)
+	if err != nil {
+		_ = r.up.Close()
+		r.up = nil
+		return err
+	}
+	return nil
+}
+
+func (r *reader) Close() error {
+	if r.up != nil {
+		err := r.up.Close()
+		r.up = nil
+		return err
+	}
+	return nil
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultCache  *Cache
+	defaultLoaded bool
+)
+
+// Default returns the cache in the config, it's created on the first use.
+// It returns nil if the cache is disabled by a size not over zero.
+func Default() *Cache {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultLoaded {
+		return defaultCache
+	}
+	defaultLoaded = true
+	cfg := conf.Conf.BlockCache
+	if cfg.MaxSize <= 0 || cfg.BlockSize <= 0 {
+		return nil
+	}
+	c, err := New(cfg.Dir, int64(cfg.BlockSize)*utils.MB, int64(cfg.MaxSize)*utils.MB)
+	if err != nil {
+		log.Errorf("failed init the block cache: %+v", err)
+		return nil
+	}
+	defaultCache = c
+	return c
+}
+
+// Link returns a link which reads the file through the block cache if the storage enables it,
+// the returned link closes the given one. The path is the full path of the file, linkType is
+// the type of the link args, the links of a type such as the thumbnails are not the file and
+// aren't cached.
+// The concurrency and the part size of the link apply to the reads of the blocks not cached,
+// they are not kept in the returned link as the cached blocks are read locally.
+func Link(storage *model.Storage, path string, file model.Obj, linkType string, link *model.Link) *model.Link {
+	if !storage.BlockCache || linkType != "" {
+		return link
+	}
+	c := Default()
+	if c == nil {
+		return link
+	}
+	size := link.ContentLength
+	if size <= 0 {
+		size = file.GetSize()
+	}
+	if size <= 0 {
+		return link
+	}
+	rr, err := stream.GetRangeReaderFromLink(size, link)
+	if err != nil {
+		return link
+	}
+	// the local files are read as they are
+	if _, ok := rr.(*model.FileRangeReader); ok {
+		return link
+	}
+	return &model.Link{
+		RangeReader:   c.Wrap(rr, c.FileKey(storage.ID, path, size, file.ModTime()), size),
+		ContentLength: size,
+		SyncClosers:   utils.NewSyncClosers(link),
+	}
+}
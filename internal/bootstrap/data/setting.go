@@ -177,9 +177,17 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.ShareArchivePreview, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PUBLIC},
 		{Key: conf.ShareForceProxy, Value: "true", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE},
 		{Key: conf.ShareSummaryContent, Value: "@{{creator}} shared {{#each files}}{{#if @first}}\"{{filename this}}\"{{/if}}{{#if @last}}{{#unless (eq @index 0)}} and {{@index}} more files{{/unless}}{{/if}}{{/each}} from {{site_title}}: {{base_url}}/@s/{{id}}{{#if pwd}} , the share code is {{pwd}}{{/if}}{{#if expires}}, please access before {{dateLocaleString expires}}.{{/if}}", Type: conf.TypeText, Group: model.GLOBAL, Flag: model.PUBLIC},
+		{Key: conf.ShareAccessLog, Value: "true", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `When enabled, every access, download and upload of a share is recorded for its creator`},
+		{Key: conf.ShareFirstOpenLog, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `When enabled, a system log is added for the creator the first time a share is opened`},
+		{Key: conf.RecycleBinEnabled, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `When enabled, deleted objects are moved into the hidden .openlist_trash folder of their storage and can be restored`},
+		{Key: conf.RecycleBinRetentionDays, Value: "30", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `Days before recycled objects are deleted for good, 0 to keep them forever`},
 		{Key: conf.HandleHookAfterWriting, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE},
 		{Key: conf.HandleHookRateLimit, Value: "0", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE},
+		{Key: conf.RefreshAheadRateLimit, Value: "1", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `Background refreshes per second of the often accessed directories about to expire in the storages with refresh ahead, 0 for no limit`},
 		{Key: conf.IgnoreSystemFiles, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `When enabled, ignores common system files during upload (.DS_Store, desktop.ini, Thumbs.db, and files starting with ._)`},
+		{Key: conf.TusUploadExpiration, Value: "24", Type: conf.TypeNumber, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `Hours an unfinished resumable upload is kept after its last chunk`},
+		{Key: conf.Require2FAForAdmin, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `When enabled, admin users without 2FA or WebAuthn must enroll before they can use the site, WebDAV, FTP or SFTP, the S3 access keys are not affected`},
+		{Key: conf.Require2FAForAll, Value: "false", Type: conf.TypeBool, Group: model.GLOBAL, Flag: model.PRIVATE, Help: `When enabled, all users without 2FA or WebAuthn must enroll before they can use the site, WebDAV, FTP or SFTP, the S3 access keys are not affected`},
 
 		// single settings
 		{Key: conf.Token, Value: token, Type: conf.TypeString, Group: model.SINGLE, Flag: model.PRIVATE},
@@ -204,6 +212,8 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.SSODefaultDir, Value: "/", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE},
 		{Key: conf.SSODefaultPermission, Value: "0", Type: conf.TypeNumber, Group: model.SSO, Flag: model.PRIVATE},
 		{Key: conf.SSOCompatibilityMode, Value: "false", Type: conf.TypeBool, Group: model.SSO, Flag: model.PUBLIC},
+		{Key: conf.SCIMEnabled, Value: "false", Type: conf.TypeBool, Group: model.SSO, Flag: model.PRIVATE, Help: `SCIM 2.0 provisioning endpoint at /scim/v2`},
+		{Key: conf.SCIMToken, Value: "", Type: conf.TypeString, Group: model.SSO, Flag: model.PRIVATE, Help: `bearer token used by the identity provider, SCIM is disabled when empty`},
 
 		// ldap settings
 		{Key: conf.LdapLoginEnabled, Value: "false", Type: conf.TypeBool, Group: model.LDAP, Flag: model.PUBLIC},
@@ -215,11 +225,17 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.LdapDefaultDir, Value: "/", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE},
 		{Key: conf.LdapDefaultPermission, Value: "0", Type: conf.TypeNumber, Group: model.LDAP, Flag: model.PRIVATE},
 		{Key: conf.LdapLoginTips, Value: "login with ldap", Type: conf.TypeString, Group: model.LDAP, Flag: model.PUBLIC},
+		{Key: conf.LdapGroupAttribute, Value: "memberOf", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE, Help: `user attribute listing the groups of the user, leave empty to only use the group search`},
+		{Key: conf.LdapGroupSearchBase, Value: "", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE, Help: `when set, groups are also searched under this base`},
+		{Key: conf.LdapGroupSearchFilter, Value: "(|(member=%s)(uniqueMember=%s))", Type: conf.TypeString, Group: model.LDAP, Flag: model.PRIVATE, Help: `%s is replaced by the user DN`},
+		{Key: conf.LdapGroupRules, Value: "[]", Type: conf.TypeText, Group: model.LDAP, Flag: model.PRIVATE, Help: `JSON array of {"group","admin","permission","base_path"}, re-evaluated on every LDAP login. Permissions of matched rules are combined, the first non-empty base_path wins, users matching no rule get the default permission and dir`},
+		{Key: conf.LdapAdoptUsers, Value: "false", Type: conf.TypeBool, Group: model.LDAP, Flag: model.PRIVATE, Help: `mark the existing users logging in with LDAP as LDAP users so that the group rules apply to them, enable it only if no local user shares the name of an LDAP user`},
 
 		// s3 settings
 		{Key: conf.S3AccessKeyId, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
 		{Key: conf.S3SecretAccessKey, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
 		{Key: conf.S3Buckets, Value: "[]", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE},
+		{Key: conf.S3TrafficUser, Value: "", Type: conf.TypeString, Group: model.S3, Flag: model.PRIVATE, Help: `The username whose speed limits and traffic quota apply to the S3 server, the admin if empty. It only counts the traffic, the access keys keep their rights`},
 
 		// ftp settings
 		{Key: conf.FTPPublicHost, Value: "127.0.0.1", Type: conf.TypeString, Group: model.FTP, Flag: model.PRIVATE},
@@ -237,10 +253,16 @@ func InitialSettings() []model.SettingItem {
 		{Key: conf.TaskCopyThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Copy.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.TaskDecompressDownloadThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Decompress.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.TaskDecompressUploadThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.DecompressUpload.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: conf.TaskSyncThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Sync.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: conf.TaskDedupeThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Dedupe.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: conf.TaskChecksumThreadsNum, Value: strconv.Itoa(conf.Conf.Tasks.Checksum.Workers), Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: conf.PauseTransfers, Value: "false", Type: conf.TypeBool, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.StreamMaxClientDownloadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.StreamMaxClientUploadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.StreamMaxServerDownloadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
 		{Key: conf.StreamMaxServerUploadSpeed, Value: "-1", Type: conf.TypeNumber, Group: model.TRAFFIC, Flag: model.PRIVATE},
+		{Key: conf.BandwidthSchedule, Value: "[]", Type: conf.TypeText, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: `Periods of the day overriding the speed limits and task threads, e.g. [{"name":"office hours","start":"09:00","end":"18:00","weekdays":[1,2,3,4,5],"limits":{"max_server_upload_speed":10240}}]`},
+		{Key: conf.RoleTrafficLimits, Value: "{}", Type: conf.TypeText, Group: model.TRAFFIC, Flag: model.PRIVATE, Help: `Default speed limits in KB/s and traffic quotas in MB of the users by role, e.g. {"guest":{"max_download_speed":1024,"traffic_quota":10240,"traffic_quota_period":"day"}}`},
 	}
 	additionalSettingItems := tool.Tools.Items()
 	// 固定顺序
@@ -1,6 +1,7 @@
 package bootstrap
 
 import (
+	"github.com/OpenListTeam/OpenList/v4/internal/bandwidth"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
@@ -18,35 +19,52 @@ func taskFilterNegative(num int) int64 {
 }
 
 func InitTaskManager() {
-	fs.UploadTaskManager = tache.NewManager[*fs.UploadTask](tache.WithWorks(setting.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)), tache.WithMaxRetry(conf.Conf.Tasks.Upload.MaxRetry)) //upload will not support persist
-	op.RegisterSettingChangingCallback(func() {
-		fs.UploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)))
+	fs.UploadTaskManager = tache.NewManager[*fs.UploadTask](tache.WithWorks(bandwidth.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)), tache.WithMaxRetry(conf.Conf.Tasks.Upload.MaxRetry)) //upload will not support persist
+	bandwidth.OnChange(func() {
+		fs.UploadTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)))
 	})
-	fs.CopyTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(setting.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("copy", conf.Conf.Tasks.Copy.TaskPersistant), db.UpdateTaskDataFunc("copy", conf.Conf.Tasks.Copy.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Copy.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		fs.CopyTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)))
+	fs.CopyTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(bandwidth.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("copy", conf.Conf.Tasks.Copy.TaskPersistant), db.UpdateTaskDataFunc("copy", conf.Conf.Tasks.Copy.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Copy.MaxRetry))
+	bandwidth.OnChange(func() {
+		fs.CopyTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)))
 	})
-	fs.MoveTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(setting.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("move", conf.Conf.Tasks.Move.TaskPersistant), db.UpdateTaskDataFunc("move", conf.Conf.Tasks.Move.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Move.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		fs.MoveTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)))
+	fs.MoveTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(bandwidth.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("move", conf.Conf.Tasks.Move.TaskPersistant), db.UpdateTaskDataFunc("move", conf.Conf.Tasks.Move.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Move.MaxRetry))
+	bandwidth.OnChange(func() {
+		fs.MoveTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)))
 	})
-	tool.DownloadTaskManager = tache.NewManager[*tool.DownloadTask](tache.WithWorks(setting.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("download", conf.Conf.Tasks.Download.TaskPersistant), db.UpdateTaskDataFunc("download", conf.Conf.Tasks.Download.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Download.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		tool.DownloadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)))
+	tool.DownloadTaskManager = tache.NewManager[*tool.DownloadTask](tache.WithWorks(bandwidth.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("download", conf.Conf.Tasks.Download.TaskPersistant), db.UpdateTaskDataFunc("download", conf.Conf.Tasks.Download.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Download.MaxRetry))
+	bandwidth.OnChange(func() {
+		tool.DownloadTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)))
 	})
-	tool.TransferTaskManager = tache.NewManager[*tool.TransferTask](tache.WithWorks(setting.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("transfer", conf.Conf.Tasks.Transfer.TaskPersistant), db.UpdateTaskDataFunc("transfer", conf.Conf.Tasks.Transfer.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Transfer.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		tool.TransferTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)))
+	tool.TransferTaskManager = tache.NewManager[*tool.TransferTask](tache.WithWorks(bandwidth.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("transfer", conf.Conf.Tasks.Transfer.TaskPersistant), db.UpdateTaskDataFunc("transfer", conf.Conf.Tasks.Transfer.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Transfer.MaxRetry))
+	bandwidth.OnChange(func() {
+		tool.TransferTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)))
 	})
 	if len(tool.TransferTaskManager.GetAll()) == 0 { //prevent offline downloaded files from being deleted
 		CleanTempDir()
 	}
-	fs.ArchiveDownloadTaskManager = tache.NewManager[*fs.ArchiveDownloadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant), db.UpdateTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Decompress.MaxRetry))
-	op.RegisterSettingChangingCallback(func() {
-		fs.ArchiveDownloadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)))
+	fs.ArchiveDownloadTaskManager = tache.NewManager[*fs.ArchiveDownloadTask](tache.WithWorks(bandwidth.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant), db.UpdateTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Decompress.MaxRetry))
+	bandwidth.OnChange(func() {
+		fs.ArchiveDownloadTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)))
+	})
+	fs.ArchiveContentUploadTaskManager.Manager = tache.NewManager[*fs.ArchiveContentUploadTask](tache.WithWorks(bandwidth.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)), tache.WithMaxRetry(conf.Conf.Tasks.DecompressUpload.MaxRetry)) //decompress upload will not support persist
+	bandwidth.OnChange(func() {
+		fs.ArchiveContentUploadTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)))
+	})
+	fs.SyncTaskManager = tache.NewManager[*fs.SyncTask](tache.WithWorks(bandwidth.GetInt(conf.TaskSyncThreadsNum, conf.Conf.Tasks.Sync.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("sync", conf.Conf.Tasks.Sync.TaskPersistant), db.UpdateTaskDataFunc("sync", conf.Conf.Tasks.Sync.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Sync.MaxRetry))
+	bandwidth.OnChange(func() {
+		fs.SyncTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskSyncThreadsNum, conf.Conf.Tasks.Sync.Workers)))
+	})
+	fs.DedupeTaskManager = tache.NewManager[*fs.DedupeTask](tache.WithWorks(bandwidth.GetInt(conf.TaskDedupeThreadsNum, conf.Conf.Tasks.Dedupe.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("dedupe", conf.Conf.Tasks.Dedupe.TaskPersistant), db.UpdateTaskDataFunc("dedupe", conf.Conf.Tasks.Dedupe.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Dedupe.MaxRetry))
+	bandwidth.OnChange(func() {
+		fs.DedupeTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskDedupeThreadsNum, conf.Conf.Tasks.Dedupe.Workers)))
+	})
+	fs.ChecksumTaskManager = tache.NewManager[*fs.ChecksumTask](tache.WithWorks(bandwidth.GetInt(conf.TaskChecksumThreadsNum, conf.Conf.Tasks.Checksum.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("checksum", conf.Conf.Tasks.Checksum.TaskPersistant), db.UpdateTaskDataFunc("checksum", conf.Conf.Tasks.Checksum.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Checksum.MaxRetry))
+	bandwidth.OnChange(func() {
+		fs.ChecksumTaskManager.SetWorkersNumActive(taskFilterNegative(bandwidth.GetInt(conf.TaskChecksumThreadsNum, conf.Conf.Tasks.Checksum.Workers)))
 	})
-	fs.ArchiveContentUploadTaskManager.Manager = tache.NewManager[*fs.ArchiveContentUploadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)), tache.WithMaxRetry(conf.Conf.Tasks.DecompressUpload.MaxRetry)) //decompress upload will not support persist
+	// the switch for the maintenance windows, the transfers stay paused over a restart
+	fs.PauseTransfers(setting.GetBool(conf.PauseTransfers))
 	op.RegisterSettingChangingCallback(func() {
-		fs.ArchiveContentUploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)))
+		fs.PauseTransfers(setting.GetBool(conf.PauseTransfers))
 	})
 }
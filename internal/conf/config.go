@@ -73,6 +73,9 @@ type TasksConfig struct {
 	Move               TaskConfig `json:"move" envPrefix:"MOVE_"`
 	Decompress         TaskConfig `json:"decompress" envPrefix:"DECOMPRESS_"`
 	DecompressUpload   TaskConfig `json:"decompress_upload" envPrefix:"DECOMPRESS_UPLOAD_"`
+	Sync               TaskConfig `json:"sync" envPrefix:"SYNC_"`
+	Dedupe             TaskConfig `json:"dedupe" envPrefix:"DEDUPE_"`
+	Checksum           TaskConfig `json:"checksum" envPrefix:"CHECKSUM_"`
 	AllowRetryCanceled bool       `json:"allow_retry_canceled" env:"ALLOW_RETRY_CANCELED"`
 }
 
@@ -106,6 +109,20 @@ type SFTP struct {
 	Listen string `json:"listen" env:"LISTEN"`
 }
 
+type DirCache struct {
+	// Persist keeps the cached directory listings in File so that they survive restarts
+	Persist bool   `json:"persist" env:"PERSIST"`
+	File    string `json:"file" env:"FILE"`
+}
+
+type BlockCache struct {
+	// Dir keeps the blocks of the proxied files read from the storages enabling the block cache,
+	// the least recently used blocks are removed when they are over MaxSize
+	Dir       string `json:"dir" env:"DIR"`
+	MaxSize   int    `json:"max_sizeMB" env:"MAX_SIZE_MB"`
+	BlockSize int    `json:"block_sizeMB" env:"BLOCK_SIZE_MB"`
+}
+
 type Config struct {
 	Force                 bool        `json:"force" env:"FORCE"`
 	SiteURL               string      `json:"site_url" env:"SITE_URL"`
@@ -130,6 +147,8 @@ type Config struct {
 	S3                    S3          `json:"s3" envPrefix:"S3_"`
 	FTP                   FTP         `json:"ftp" envPrefix:"FTP_"`
 	SFTP                  SFTP        `json:"sftp" envPrefix:"SFTP_"`
+	DirCache              DirCache    `json:"dir_cache" envPrefix:"DIR_CACHE_"`
+	BlockCache            BlockCache  `json:"block_cache" envPrefix:"BLOCK_CACHE_"`
 	LastLaunchedVersion   string      `json:"last_launched_version"`
 	ProxyAddress          string      `json:"proxy_address" env:"PROXY_ADDRESS"`
 }
@@ -139,6 +158,8 @@ func DefaultConfig(dataDir string) *Config {
 	indexDir := filepath.Join(dataDir, "bleve")
 	logPath := filepath.Join(dataDir, "log/log.log")
 	dbPath := filepath.Join(dataDir, "data.db")
+	dirCachePath := filepath.Join(dataDir, "dir_cache.db")
+	blockCacheDir := filepath.Join(dataDir, "block_cache")
 	return &Config{
 		Scheme: Scheme{
 			Address:    "0.0.0.0",
@@ -216,6 +237,19 @@ func DefaultConfig(dataDir string) *Config {
 				Workers:  5,
 				MaxRetry: 2,
 			},
+			Sync: TaskConfig{
+				Workers:  2,
+				MaxRetry: 1,
+				// TaskPersistant: true,
+			},
+			Dedupe: TaskConfig{
+				Workers: 1,
+				// TaskPersistant: true,
+			},
+			Checksum: TaskConfig{
+				Workers: 1,
+				// TaskPersistant: true,
+			},
 			AllowRetryCanceled: false,
 		},
 		Cors: Cors{
@@ -244,6 +278,15 @@ func DefaultConfig(dataDir string) *Config {
 			Enable: false,
 			Listen: ":5222",
 		},
+		DirCache: DirCache{
+			Persist: false,
+			File:    dirCachePath,
+		},
+		BlockCache: BlockCache{
+			Dir:       blockCacheDir,
+			MaxSize:   1024,
+			BlockSize: 4,
+		},
 		LastLaunchedVersion: "",
 		ProxyAddress:        "",
 	}
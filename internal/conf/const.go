@@ -56,9 +56,17 @@ const (
 	ShareArchivePreview     = "share_archive_preview"
 	ShareForceProxy         = "share_force_proxy"
 	ShareSummaryContent     = "share_summary_content"
+	ShareAccessLog          = "share_access_log"
+	ShareFirstOpenLog       = "share_first_open_log"
+	RecycleBinEnabled       = "recycle_bin_enabled"
+	RecycleBinRetentionDays = "recycle_bin_retention_days"
 	HandleHookAfterWriting  = "handle_hook_after_writing"
 	HandleHookRateLimit     = "handle_hook_rate_limit"
+	RefreshAheadRateLimit   = "refresh_ahead_rate_limit"
 	IgnoreSystemFiles       = "ignore_system_files"
+	TusUploadExpiration     = "tus_upload_expiration"
+	Require2FAForAdmin      = "require_2fa_for_admin"
+	Require2FAForAll        = "require_2fa_for_all"
 
 	// index
 	SearchIndex     = "search_index"
@@ -111,6 +119,8 @@ const (
 	SSODefaultDir        = "sso_default_dir"
 	SSODefaultPermission = "sso_default_permission"
 	SSOCompatibilityMode = "sso_compatibility_mode"
+	SCIMEnabled          = "scim_enabled"
+	SCIMToken            = "scim_token"
 
 	// ldap
 	LdapLoginEnabled      = "ldap_login_enabled"
@@ -122,11 +132,17 @@ const (
 	LdapDefaultPermission = "ldap_default_permission"
 	LdapDefaultDir        = "ldap_default_dir"
 	LdapLoginTips         = "ldap_login_tips"
+	LdapGroupAttribute    = "ldap_group_attribute"
+	LdapGroupSearchBase   = "ldap_group_search_base"
+	LdapGroupSearchFilter = "ldap_group_search_filter"
+	LdapGroupRules        = "ldap_group_rules"
+	LdapAdoptUsers        = "ldap_adopt_users"
 
 	// s3
 	S3Buckets         = "s3_buckets"
 	S3AccessKeyId     = "s3_access_key_id"
 	S3SecretAccessKey = "s3_secret_access_key"
+	S3TrafficUser     = "s3_traffic_user"
 
 	// qbittorrent
 	QbittorrentUrl      = "qbittorrent_url"
@@ -153,10 +169,16 @@ const (
 	TaskMoveThreadsNum                    = "move_task_threads_num"
 	TaskDecompressDownloadThreadsNum      = "decompress_download_task_threads_num"
 	TaskDecompressUploadThreadsNum        = "decompress_upload_task_threads_num"
+	TaskSyncThreadsNum                    = "sync_task_threads_num"
+	TaskDedupeThreadsNum                  = "dedupe_task_threads_num"
+	TaskChecksumThreadsNum                = "checksum_task_threads_num"
+	PauseTransfers                        = "pause_transfers"
 	StreamMaxClientDownloadSpeed          = "max_client_download_speed"
 	StreamMaxClientUploadSpeed            = "max_client_upload_speed"
 	StreamMaxServerDownloadSpeed          = "max_server_download_speed"
 	StreamMaxServerUploadSpeed            = "max_server_upload_speed"
+	BandwidthSchedule                     = "bandwidth_schedule"
+	RoleTrafficLimits                     = "role_traffic_limits"
 )
 
 const (
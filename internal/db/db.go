@@ -21,12 +21,21 @@ func Init(d *gorm.DB) {
 		new(model.TaskItem),
 		new(model.SSHPublicKey),
 		new(model.SharingDB),
+		new(model.SharingAccessLog),
 		new(model.WebdavSession),
 		new(model.WebdavBlock),
 		new(model.LoginLog),
 		new(model.UploadLog),
 		new(model.SystemLog),
 		new(model.Device),
+		new(model.RecycleItem),
+		new(model.FileVersion),
+		new(model.ScheduledJob),
+		new(model.ScheduledJobRun),
+		new(model.Webhook),
+		new(model.WebhookDelivery),
+		new(model.UserTraffic),
+		new(model.TrafficStat),
 	)
 	if err != nil {
 		log.Fatalf("failed migrate database: %s", err.Error())
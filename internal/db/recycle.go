@@ -0,0 +1,44 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateRecycleItem(item *model.RecycleItem) error {
+	return errors.WithStack(db.Create(item).Error)
+}
+
+func GetRecycleItemById(id uint) (*model.RecycleItem, error) {
+	var item model.RecycleItem
+	if err := db.First(&item, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get recycle item")
+	}
+	return &item, nil
+}
+
+// GetRecycleItems lists the items deleted by the user, or by anyone if userId is 0
+func GetRecycleItems(userId uint, pageIndex, pageSize int) (items []model.RecycleItem, count int64, err error) {
+	itemDB := db.Model(&model.RecycleItem{})
+	if userId != 0 {
+		itemDB = itemDB.Where("user_id = ?", userId)
+	}
+	if err := itemDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get recycle items count")
+	}
+	if err := itemDB.Order("deleted_at DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find recycle items")
+	}
+	return items, count, nil
+}
+
+func GetRecycleItemsBefore(before time.Time) (items []model.RecycleItem, err error) {
+	err = db.Where("deleted_at < ?", before).Find(&items).Error
+	return items, errors.Wrapf(err, "failed find expired recycle items")
+}
+
+func DeleteRecycleItemById(id uint) error {
+	return errors.WithStack(db.Delete(&model.RecycleItem{}, id).Error)
+}
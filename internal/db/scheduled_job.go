@@ -0,0 +1,79 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateScheduledJob(job *model.ScheduledJob) error {
+	return errors.WithStack(db.Create(job).Error)
+}
+
+func UpdateScheduledJob(job *model.ScheduledJob) error {
+	return errors.WithStack(db.Save(job).Error)
+}
+
+// UpdateScheduledJobStatus only saves the columns about the last run,
+// so that it doesn't overwrite a concurrent edit of the job
+func UpdateScheduledJobStatus(job *model.ScheduledJob) error {
+	return errors.WithStack(db.Model(job).Select("last_run_at", "last_status", "last_error").Updates(job).Error)
+}
+
+func GetScheduledJobById(id uint) (*model.ScheduledJob, error) {
+	var job model.ScheduledJob
+	if err := db.First(&job, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get scheduled job")
+	}
+	return &job, nil
+}
+
+func GetScheduledJobs() (jobs []model.ScheduledJob, err error) {
+	err = db.Order("id").Find(&jobs).Error
+	return jobs, errors.Wrapf(err, "failed find scheduled jobs")
+}
+
+func DeleteScheduledJobById(id uint) error {
+	if err := db.Where("job_id = ?", id).Delete(&model.ScheduledJobRun{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Delete(&model.ScheduledJob{}, id).Error)
+}
+
+func CreateScheduledJobRun(run *model.ScheduledJobRun) error {
+	return errors.WithStack(db.Create(run).Error)
+}
+
+func UpdateScheduledJobRun(run *model.ScheduledJobRun) error {
+	return errors.WithStack(db.Save(run).Error)
+}
+
+func GetScheduledJobRuns(jobId uint, pageIndex, pageSize int) (runs []model.ScheduledJobRun, count int64, err error) {
+	runDB := db.Model(&model.ScheduledJobRun{}).Where("job_id = ?", jobId)
+	if err := runDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get scheduled job runs count")
+	}
+	if err := runDB.Order("id DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find scheduled job runs")
+	}
+	return runs, count, nil
+}
+
+// GetUnfinishedScheduledJobRuns returns the runs still waiting for their end,
+// which are the ones interrupted by a restart at startup
+func GetUnfinishedScheduledJobRuns() (runs []model.ScheduledJobRun, err error) {
+	err = db.Where("finished_at IS NULL").Find(&runs).Error
+	return runs, errors.Wrapf(err, "failed find unfinished scheduled job runs")
+}
+
+// DeleteOldScheduledJobRuns keeps the latest keep runs of the job
+func DeleteOldScheduledJobRuns(jobId uint, keep int) error {
+	var ids []uint
+	if err := db.Model(&model.ScheduledJobRun{}).Where("job_id = ?", jobId).
+		Order("id DESC").Offset(keep).Limit(1<<20).Pluck("id", &ids).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.Delete(&model.ScheduledJobRun{}, ids).Error)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 func GetSharingById(id string) (*model.SharingDB, error) {
@@ -60,8 +61,34 @@ func CreateSharing(s *model.SharingDB) (string, error) {
 	}
 }
 
+// UpdateSharing never writes the traffic counters, which are only changed by UpdateSharingTraffic
 func UpdateSharing(s *model.SharingDB) error {
-	return errors.WithStack(db.Save(s).Error)
+	return errors.WithStack(db.Omit("traffic", "daily_traffic", "traffic_date", "uploaded").Save(s).Error)
+}
+
+// ReserveSharingUpload adds size to the uploaded bytes of the sharing in one statement,
+// it returns false if that would exceed the total upload size of the sharing
+func ReserveSharingUpload(id string, size int64) (bool, error) {
+	res := db.Model(&model.SharingDB{}).
+		Where("id = ? AND (upload_max_total_size <= 0 OR uploaded + ? <= upload_max_total_size)", id, size).
+		UpdateColumn("uploaded", gorm.Expr("uploaded + ?", size))
+	if res.Error != nil {
+		return false, errors.WithStack(res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func ReleaseSharingUpload(id string, size int64) error {
+	return errors.WithStack(db.Model(&model.SharingDB{}).Where("id = ?", id).
+		UpdateColumn("uploaded", gorm.Expr("uploaded - ?", size)).Error)
+}
+
+func UpdateSharingTraffic(id string, traffic, dailyTraffic int64, trafficDate string) error {
+	return errors.WithStack(db.Model(&model.SharingDB{ID: id}).Updates(map[string]any{
+		"traffic":       traffic,
+		"daily_traffic": dailyTraffic,
+		"traffic_date":  trafficDate,
+	}).Error)
 }
 
 func DeleteSharingById(id string) error {
@@ -69,6 +96,11 @@ func DeleteSharingById(id string) error {
 	return errors.WithStack(db.Where(s).Delete(&s).Error)
 }
 
+func GetSharingIdsByCreatorId(creatorId uint) (ids []string, err error) {
+	err = db.Model(&model.SharingDB{}).Where("creator_id = ?", creatorId).Pluck("id", &ids).Error
+	return ids, errors.WithStack(err)
+}
+
 func DeleteSharingsByCreatorId(creatorId uint) error {
 	return errors.WithStack(db.Where("creator_id = ?", creatorId).Delete(&model.SharingDB{}).Error)
 }
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateSharingAccessLog(l *model.SharingAccessLog) error {
+	return errors.WithStack(db.Create(l).Error)
+}
+
+func GetSharingAccessLogs(sid string, pageIndex, pageSize int) (logs []model.SharingAccessLog, count int64, err error) {
+	logDB := db.Model(&model.SharingAccessLog{}).Where("sharing_id = ?", sid)
+	if err := logDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get sharing access logs count")
+	}
+	if err := logDB.Order("created_at DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find sharing access logs")
+	}
+	return logs, count, nil
+}
+
+// GetSharingAccessLogsSince returns the logs of a share created after since, oldest first
+func GetSharingAccessLogsSince(sid string, since time.Time) (logs []model.SharingAccessLog, err error) {
+	err = db.Select("type", "bytes", "created_at").
+		Where("sharing_id = ? AND created_at >= ?", sid, since).
+		Order("created_at").Find(&logs).Error
+	return logs, errors.Wrapf(err, "failed find sharing access logs")
+}
+
+func GetSharingTopFiles(sid string, since time.Time, limit int) (files []model.SharingTopFile, err error) {
+	err = db.Model(&model.SharingAccessLog{}).
+		Select("path, count(*) AS downloads, sum(bytes) AS bytes").
+		Where("sharing_id = ? AND created_at >= ? AND type IN ?", sid, since,
+			[]string{model.SharingLogDownload, model.SharingLogRedirect}).
+		Group("path").Order("downloads DESC").Limit(limit).
+		Scan(&files).Error
+	return files, errors.Wrapf(err, "failed get sharing top files")
+}
+
+func DeleteSharingAccessLogs(sid string) error {
+	return errors.WithStack(db.Where("sharing_id = ?", sid).Delete(&model.SharingAccessLog{}).Error)
+}
+
+func DeleteSharingAccessLogsByCreatorId(creatorId uint) error {
+	sub := db.Model(&model.SharingDB{}).Select("id").Where("creator_id = ?", creatorId)
+	return errors.WithStack(db.Where("sharing_id IN (?)", sub).Delete(&model.SharingAccessLog{}).Error)
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddUserTraffic adds the traffic to the traffic of the same user and day
+func AddUserTraffic(t *model.UserTraffic) error {
+	return errors.WithStack(db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"download": gorm.Expr("download + ?", t.Download),
+			"upload":   gorm.Expr("upload + ?", t.Upload),
+		}),
+	}).Create(t).Error)
+}
+
+// SumUserTraffic sums the traffic of the user from the day since on
+func SumUserTraffic(userID uint, since string) (download, upload int64, err error) {
+	var sum struct {
+		Download int64
+		Upload   int64
+	}
+	err = db.Model(&model.UserTraffic{}).
+		Select("COALESCE(SUM(download), 0) AS download, COALESCE(SUM(upload), 0) AS upload").
+		Where("user_id = ? AND day >= ?", userID, since).Scan(&sum).Error
+	return sum.Download, sum.Upload, errors.Wrapf(err, "failed sum user traffic")
+}
+
+// AddTrafficStat adds the traffic to the stat of the same hour, user, storage, protocol and share
+func AddTrafficStat(s *model.TrafficStat) error {
+	return errors.WithStack(db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "hour"}, {Name: "user_id"}, {Name: "storage_id"}, {Name: "protocol"}, {Name: "sharing_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"download": gorm.Expr("download + ?", s.Download),
+			"upload":   gorm.Expr("upload + ?", s.Upload),
+		}),
+	}).Create(s).Error)
+}
+
+// TrafficStatFilter selects the traffic stats, the zero fields select all
+type TrafficStatFilter struct {
+	Start     *time.Time
+	End       *time.Time
+	UserID    uint
+	StorageID uint
+	Protocol  string
+	SharingID string
+}
+
+func trafficStatQuery(f TrafficStatFilter) *gorm.DB {
+	q := db.Model(&model.TrafficStat{})
+	if f.Start != nil {
+		q = q.Where("hour >= ?", f.Start.UTC())
+	}
+	if f.End != nil {
+		q = q.Where("hour < ?", f.End.UTC())
+	}
+	if f.UserID != 0 {
+		q = q.Where("user_id = ?", f.UserID)
+	}
+	if f.StorageID != 0 {
+		q = q.Where("storage_id = ?", f.StorageID)
+	}
+	if f.Protocol != "" {
+		q = q.Where("protocol = ?", f.Protocol)
+	}
+	if f.SharingID != "" {
+		q = q.Where("sharing_id = ?", f.SharingID)
+	}
+	return q
+}
+
+// GetTrafficSeries sums the selected traffic by hour
+func GetTrafficSeries(f TrafficStatFilter) (stats []model.TrafficStat, err error) {
+	err = trafficStatQuery(f).
+		Select("hour, SUM(download) AS download, SUM(upload) AS upload").
+		Group("hour").Order("hour").Scan(&stats).Error
+	return stats, errors.Wrapf(err, "failed get traffic series")
+}
+
+type TrafficTopItem struct {
+	Key      string `json:"key"`
+	Download int64  `json:"download"`
+	Upload   int64  `json:"upload"`
+}
+
+// GetTrafficTop sums the selected traffic by the column, user_id, storage_id, protocol or sharing_id,
+// and returns the limit ones with the most traffic in the order, download, upload or both of them
+func GetTrafficTop(f TrafficStatFilter, column, order string, limit int) (items []TrafficTopItem, err error) {
+	orderBy := "SUM(download) + SUM(upload) DESC"
+	switch order {
+	case "download":
+		orderBy = "SUM(download) DESC"
+	case "upload":
+		orderBy = "SUM(upload) DESC"
+	}
+	err = trafficStatQuery(f).
+		Select(column + " AS " + columnName("key") + ", SUM(download) AS download, SUM(upload) AS upload").
+		Group(column).Order(orderBy).Limit(limit).Scan(&items).Error
+	return items, errors.Wrapf(err, "failed get traffic top")
+}
@@ -9,9 +9,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+// GetUserByRole returns the first created user of the role, so that the
+// built-in admin is still found when other users are granted the admin role
 func GetUserByRole(role int) (*model.User, error) {
 	user := model.User{Role: role}
-	if err := db.Where(user).Take(&user).Error; err != nil {
+	if err := db.Where(user).Order(columnName("id")).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -60,7 +62,29 @@ func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err err
 	return users, count, nil
 }
 
+// FindUsers returns the users of the given role, optionally narrowed down
+// by exact username or sso id
+func FindUsers(role int, username, ssoID string, offset, limit int) (users []model.User, count int64, err error) {
+	userDB := db.Model(&model.User{}).Where(columnName("role")+" = ?", role)
+	if username != "" {
+		userDB = userDB.Where(columnName("username")+" = ?", username)
+	}
+	if ssoID != "" {
+		userDB = userDB.Where(columnName("sso_id")+" = ?", ssoID)
+	}
+	if err := userDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get users count")
+	}
+	if err := userDB.Order(columnName("id")).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find users")
+	}
+	return users, count, nil
+}
+
 func DeleteUserById(id uint) error {
+	if err := db.Where("user_id = ?", id).Delete(&model.UserTraffic{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
 	return errors.WithStack(db.Delete(&model.User{}, id).Error)
 }
 
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateFileVersion(v *model.FileVersion) error {
+	return errors.WithStack(db.Create(v).Error)
+}
+
+func GetFileVersionById(id uint) (*model.FileVersion, error) {
+	var v model.FileVersion
+	if err := db.First(&v, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get file version")
+	}
+	return &v, nil
+}
+
+// GetFileVersions returns the versions of a file, newest first
+func GetFileVersions(storageId uint, actualPath string) (versions []model.FileVersion, err error) {
+	err = db.Where("storage_id = ? AND actual_path = ?", storageId, actualPath).
+		Order("created_at DESC").Order("id DESC").Find(&versions).Error
+	return versions, errors.Wrapf(err, "failed find file versions")
+}
+
+func DeleteFileVersionById(id uint) error {
+	return errors.WithStack(db.Delete(&model.FileVersion{}, id).Error)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func CreateWebhook(hook *model.Webhook) error {
+	return errors.WithStack(db.Create(hook).Error)
+}
+
+func UpdateWebhook(hook *model.Webhook) error {
+	return errors.WithStack(db.Save(hook).Error)
+}
+
+func GetWebhookById(id uint) (*model.Webhook, error) {
+	var hook model.Webhook
+	if err := db.First(&hook, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get webhook")
+	}
+	return &hook, nil
+}
+
+func GetWebhooks() (hooks []model.Webhook, err error) {
+	err = db.Order("id").Find(&hooks).Error
+	return hooks, errors.Wrapf(err, "failed find webhooks")
+}
+
+func DeleteWebhookById(id uint) error {
+	if err := db.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(db.Delete(&model.Webhook{}, id).Error)
+}
+
+func CreateWebhookDelivery(delivery *model.WebhookDelivery) error {
+	return errors.WithStack(db.Create(delivery).Error)
+}
+
+func UpdateWebhookDelivery(delivery *model.WebhookDelivery) error {
+	return errors.WithStack(db.Save(delivery).Error)
+}
+
+func GetWebhookDeliveryById(id uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := db.First(&delivery, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get webhook delivery")
+	}
+	return &delivery, nil
+}
+
+func GetWebhookDeliveries(hookId uint, pageIndex, pageSize int) (deliveries []model.WebhookDelivery, count int64, err error) {
+	deliveryDB := db.Model(&model.WebhookDelivery{}).Where("webhook_id = ?", hookId)
+	if err := deliveryDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get webhook deliveries count")
+	}
+	if err := deliveryDB.Order("id DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find webhook deliveries")
+	}
+	return deliveries, count, nil
+}
+
+// GetUnfinishedWebhookDeliveries returns the deliveries still retried, which are
+// the ones interrupted by a restart at startup
+func GetUnfinishedWebhookDeliveries() (deliveries []model.WebhookDelivery, err error) {
+	err = db.Where("finished_at IS NULL").Find(&deliveries).Error
+	return deliveries, errors.Wrapf(err, "failed find unfinished webhook deliveries")
+}
+
+// DeleteOldWebhookDeliveries keeps the latest keep deliveries of the webhook
+func DeleteOldWebhookDeliveries(hookId uint, keep int) error {
+	var ids []uint
+	if err := db.Model(&model.WebhookDelivery{}).Where("webhook_id = ?", hookId).
+		Order("id DESC").Offset(keep).Limit(1<<20).Pluck("id", &ids).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return errors.WithStack(db.Delete(&model.WebhookDelivery{}, ids).Error)
+}
@@ -103,6 +103,18 @@ type Put interface {
 	Put(ctx context.Context, dstDir model.Obj, file model.FileStreamer, up UpdateProgress) error
 }
 
+type PutResume interface {
+	// PutResume is a Put which keeps what was uploaded when it's interrupted, for example by
+	// staging the file or by a multipart upload which is not completed. When resume is true,
+	// it carries on the interrupted upload of the same file and reads only the rest of it
+	// with file.RangeRead, otherwise it starts over.
+	// It's used instead of Put when the caller asks for it by op.WithResumablePut.
+	PutResume(ctx context.Context, dstDir model.Obj, file model.FileStreamer, resume bool, up UpdateProgress) error
+	// DiscardResume removes what an interrupted PutResume of the file named name in dstDir kept,
+	// when the upload is given up instead of being resumed
+	DiscardResume(ctx context.Context, dstDir model.Obj, name string) error
+}
+
 type PutURL interface {
 	// PutURL directly put a URL into the storage
 	// Applicable to index-based drivers like URL-Tree or drivers that support uploading files as URLs
@@ -214,6 +226,13 @@ type Reference interface {
 	InitReference(storage Driver) error
 }
 
+type Identifier interface {
+	// Identify returns a key of the object at the path which is the same whichever storage it's
+	// reached through, like the path on the disk for a local storage, so that the storages
+	// overlapping each other, like an alias or the same folder mounted twice, can be told apart
+	Identify(ctx context.Context, path string) (string, error)
+}
+
 type LinkCacheModeResolver interface {
 	// ResolveLinkCacheMode returns the LinkCacheMode for the given path.
 	ResolveLinkCacheMode(path string) LinkCacheMode
@@ -23,9 +23,14 @@ var (
 	WrongArchivePassword      = errors.New("wrong archive password")
 	DriverExtractNotSupported = errors.New("driver extraction not supported")
 
-	WrongShareCode  = errors.New("wrong share code")
-	InvalidSharing  = errors.New("invalid sharing")
-	SharingNotFound = errors.New("sharing not found")
+	WrongShareCode          = errors.New("wrong share code")
+	InvalidSharing          = errors.New("invalid sharing")
+	SharingNotFound         = errors.New("sharing not found")
+	SharingUploadOnly       = errors.New("the share only accepts uploads")
+	SharingNotUploadable    = errors.New("the share does not accept uploads")
+	SharingUploadRejected   = errors.New("the upload is rejected by the share")
+	SharingTrafficExceeded  = errors.New("the share has reached its traffic limit")
+	SharingTooManyDownloads = errors.New("too many downloads of the share at the same time")
 )
 
 // NewErr wrap constant error with an extra message
@@ -3,8 +3,9 @@ package errs
 import "errors"
 
 var (
-	EmptyUsername      = errors.New("username is empty")
-	EmptyPassword      = errors.New("password is empty")
-	WrongPassword      = errors.New("password is incorrect")
-	DeleteAdminOrGuest = errors.New("cannot delete admin or guest")
+	EmptyUsername        = errors.New("username is empty")
+	EmptyPassword        = errors.New("password is empty")
+	WrongPassword        = errors.New("password is incorrect")
+	DeleteAdminOrGuest   = errors.New("cannot delete admin or guest")
+	TrafficQuotaExceeded = errors.New("traffic quota exceeded, wait for it to be reset")
 )
@@ -30,6 +30,22 @@ import (
 type ArchiveDownloadTask struct {
 	TaskData
 	model.ArchiveDecompressArgs
+	report *conflictReport
+}
+
+// GetResult returns the conflicts met putting the content of the archive
+func (t *ArchiveDownloadTask) GetResult() ConflictResult {
+	return t.report.get()
+}
+
+func (t *ArchiveDownloadTask) conflictPolicy() string {
+	if t.ConflictPolicy != "" {
+		return t.ConflictPolicy
+	}
+	if t.Overwrite {
+		return ConflictOverwrite
+	}
+	return ConflictFail
 }
 
 func (t *ArchiveDownloadTask) GetName() string {
@@ -37,7 +53,15 @@ func (t *ArchiveDownloadTask) GetName() string {
 		t.InnerPath, t.DstStorageMp, t.DstActualPath, t.Password)
 }
 
-func (t *ArchiveDownloadTask) Run() error {
+func (t *ArchiveDownloadTask) Run() (err error) {
+	if err := t.BeforeRun(); err != nil {
+		return err
+	}
+	defer func() {
+		if t.IsPaused() {
+			err = task.ErrPaused
+		}
+	}()
 	if err := t.ReinitCtx(); err != nil {
 		return err
 	}
@@ -53,6 +77,9 @@ func (t *ArchiveDownloadTask) Run() error {
 			return err
 		}
 	}
+	if t.report == nil {
+		t.report = &conflictReport{}
+	}
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
@@ -119,13 +146,14 @@ func (t *ArchiveDownloadTask) RunWithoutPushUploadTask() (*ArchiveContentUploadT
 			Creator: t.Creator,
 			ApiUrl:  t.ApiUrl,
 		},
-		ObjName:       baseName,
-		InPlace:       !t.PutIntoNewDir,
-		FilePath:      dir,
-		DstActualPath: t.DstActualPath,
-		dstStorage:    t.DstStorage,
-		DstStorageMp:  t.DstStorageMp,
-		overwrite:     t.Overwrite,
+		ObjName:        baseName,
+		InPlace:        !t.PutIntoNewDir,
+		FilePath:       dir,
+		DstActualPath:  t.DstActualPath,
+		dstStorage:     t.DstStorage,
+		DstStorageMp:   t.DstStorageMp,
+		conflictPolicy: t.conflictPolicy(),
+		report:         t.report,
 	}
 	return uploadTask, nil
 }
@@ -143,7 +171,13 @@ type ArchiveContentUploadTask struct {
 	DstStorageMp  string
 	finalized     bool
 	groupID       string
-	overwrite     bool
+	// the name the object is put under and whether its upload was started,
+	// a task run again keeps the name and carries on the upload
+	dstName    string
+	putStarted bool
+	// conflictPolicy and report are kept in memory only, like the task
+	conflictPolicy string
+	report         *conflictReport
 }
 
 func (t *ArchiveContentUploadTask) GetName() string {
@@ -154,7 +188,15 @@ func (t *ArchiveContentUploadTask) GetStatus() string {
 	return t.status
 }
 
-func (t *ArchiveContentUploadTask) Run() error {
+func (t *ArchiveContentUploadTask) Run() (err error) {
+	if err := t.BeforeRun(); err != nil {
+		return err
+	}
+	defer func() {
+		if t.IsPaused() {
+			err = task.ErrPaused
+		}
+	}()
 	if err := t.ReinitCtx(); err != nil {
 		return err
 	}
@@ -172,6 +214,9 @@ func (t *ArchiveContentUploadTask) OnSucceeded() {
 }
 
 func (t *ArchiveContentUploadTask) OnFailed() {
+	if t.IsPaused() {
+		return
+	}
 	task_group.TransferCoordinator.Done(t.groupID, false)
 }
 
@@ -179,12 +224,24 @@ func (t *ArchiveContentUploadTask) SetRetry(retry int, maxRetry int) {
 	t.TaskExtension.SetRetry(retry, maxRetry)
 	if retry == 0 &&
 		(len(t.groupID) == 0 || // 重启恢复
-			(t.GetErr() == nil && t.GetState() != tache.StatePending)) { // 手动重试
+			(!t.Resuming() && t.GetErr() == nil && t.GetState() != tache.StatePending)) { // 手动重试
 		t.groupID = stdpath.Join(t.DstStorageMp, t.DstActualPath)
 		task_group.TransferCoordinator.AddTask(t.groupID, nil)
 	}
 }
 
+// resolveName applies the conflict policy to obj, or returns the name it got in a former run
+func (t *ArchiveContentUploadTask) resolveName(obj model.Obj) (string, error) {
+	if t.dstName == "" {
+		name, err := applyConflictPolicy(t.Ctx(), t.conflictPolicy, t.dstStorage, t.DstActualPath, obj, t.report)
+		if err != nil || name == "" {
+			return name, err
+		}
+		t.dstName = name
+	}
+	return t.dstName, nil
+}
+
 func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *ArchiveContentUploadTask) error) error {
 	info, err := os.Stat(t.FilePath)
 	if err != nil {
@@ -194,7 +251,15 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 		t.status = "src object is dir, listing objs"
 		nextDstActualPath := t.DstActualPath
 		if !t.InPlace {
-			nextDstActualPath = stdpath.Join(nextDstActualPath, t.ObjName)
+			name, err := t.resolveName(&model.Object{Name: t.ObjName, IsFolder: true, Modified: info.ModTime()})
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				t.deleteSrcFile()
+				return nil
+			}
+			nextDstActualPath = stdpath.Join(nextDstActualPath, name)
 			err = op.MakeDir(t.Ctx(), t.dstStorage, nextDstActualPath)
 			if err != nil {
 				return err
@@ -227,14 +292,15 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 					Creator: t.Creator,
 					ApiUrl:  t.ApiUrl,
 				},
-				ObjName:       entry.Name(),
-				InPlace:       false,
-				FilePath:      nextFilePath,
-				DstActualPath: nextDstActualPath,
-				dstStorage:    t.dstStorage,
-				DstStorageMp:  t.DstStorageMp,
-				groupID:       t.groupID,
-				overwrite:     t.overwrite,
+				ObjName:        entry.Name(),
+				InPlace:        false,
+				FilePath:       nextFilePath,
+				DstActualPath:  nextDstActualPath,
+				dstStorage:     t.dstStorage,
+				DstStorageMp:   t.DstStorageMp,
+				groupID:        t.groupID,
+				conflictPolicy: t.conflictPolicy,
+				report:         t.report,
 			})
 			if err != nil {
 				es = stderrors.Join(es, err)
@@ -244,11 +310,13 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 			return es
 		}
 	} else {
-		if !t.overwrite {
-			dstPath := stdpath.Join(t.DstActualPath, t.ObjName)
-			if res, _ := op.Get(t.Ctx(), t.dstStorage, dstPath); res != nil {
-				return errs.ObjectAlreadyExists
-			}
+		name, err := t.resolveName(&model.Object{Name: t.ObjName, Size: info.Size(), Modified: info.ModTime()})
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			t.deleteSrcFile()
+			return nil
 		}
 		file, err := os.Open(t.FilePath)
 		if err != nil {
@@ -257,17 +325,19 @@ func (t *ArchiveContentUploadTask) RunWithNextTaskCallback(f func(nextTask *Arch
 		t.SetTotalBytes(info.Size())
 		fs := &stream.FileStream{
 			Obj: &model.Object{
-				Name:     t.ObjName,
+				Name:     name,
 				Size:     info.Size(),
 				Modified: time.Now(),
 			},
-			Mimetype:     utils.GetMimeType(stdpath.Ext(t.ObjName)),
+			Mimetype:     utils.GetMimeType(stdpath.Ext(name)),
 			WebPutAsTask: true,
 			Reader:       file,
 		}
 		fs.Closers.Add(file)
 		t.status = "uploading"
-		err = op.Put(t.Ctx(), t.dstStorage, t.DstActualPath, fs, t.SetProgress, true)
+		ctx := op.WithResumablePut(t.Ctx(), t.putStarted)
+		t.putStarted = true
+		err = op.Put(ctx, t.dstStorage, t.DstActualPath, fs, t.SetProgress, true)
 		if err != nil {
 			return err
 		}
@@ -280,6 +350,17 @@ func (t *ArchiveContentUploadTask) Cancel() {
 	t.TaskExtension.Cancel()
 	if !conf.Conf.Tasks.AllowRetryCanceled {
 		t.deleteSrcFile()
+		t.Discard()
+	}
+}
+
+// Discard removes the upload kept to be carried on, if it was started
+func (t *ArchiveContentUploadTask) Discard() {
+	if !t.putStarted || t.dstStorage == nil {
+		return
+	}
+	if err := op.DiscardResumablePut(context.Background(), t.dstStorage, t.DstActualPath, t.dstName); err != nil {
+		log.Warnf("failed discard the upload of [%s](%s): %+v", t.DstStorageMp, stdpath.Join(t.DstActualPath, t.dstName), err)
 	}
 }
 
@@ -380,7 +461,8 @@ func archiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get dst storage")
 	}
-	if srcStorage.GetStorage() == dstStorage.GetStorage() {
+	// the storages decompress overwriting, or as they wish with the former Overwrite
+	if srcStorage.GetStorage() == dstStorage.GetStorage() && (args.ConflictPolicy == "" || args.ConflictPolicy == ConflictOverwrite) {
 		err = op.ArchiveDecompress(ctx, srcStorage, srcObjActualPath, dstDirActualPath, args, lazyCache...)
 		if !errors.Is(err, errs.NotImplement) {
 			return nil, err
@@ -396,6 +478,7 @@ func archiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 			DstStorageMp:  dstStorage.GetStorage().MountPath,
 		},
 		ArchiveDecompressArgs: args,
+		report:                &conflictReport{},
 	}
 	if ctx.Value(conf.NoTaskKey) != nil {
 		tsk.Base.SetCtx(ctx)
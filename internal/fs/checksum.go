@@ -0,0 +1,428 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	stdpath "path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+)
+
+// formats of checksum manifests
+const (
+	// ChecksumBSD lines are like `SHA256 (dir/file) = <hash>`
+	ChecksumBSD = "bsd"
+	// ChecksumGNU lines are like `<hash>  dir/file`, as written and checked by sha256sum and the like
+	ChecksumGNU = "gnu"
+)
+
+// ChecksumArgs describe a checksum task. It writes the manifest of the files under Path
+// when the manifest doesn't exist or Rewrite is set, otherwise it verifies the files.
+type ChecksumArgs struct {
+	Path string `json:"path"`
+	// HashType is md5, sha1 or sha256, by default the extension of Manifest if it's one of them, or sha256
+	HashType string `json:"hash_type"`
+	Format   string `json:"format"` // bsd or gnu, bsd by default
+	// Manifest is the name of the manifest file in Path, checksums.<hash_type> by default
+	Manifest string `json:"manifest"`
+	Rewrite  bool   `json:"rewrite"`
+	// Compute always reads the files instead of using the hashes provided by their storages,
+	// which may be stored metadata not detecting the corruption of the content
+	Compute bool `json:"compute"`
+}
+
+type ChecksumResult struct {
+	Verify   bool   `json:"verify"`
+	Manifest string `json:"manifest"`
+	Files    int    `json:"files"`
+	Verified int    `json:"verified"`
+	// Missing are in the manifest but not under the path
+	Missing []string `json:"missing"`
+	// Changed don't match the manifest and were modified after it was written
+	Changed []string `json:"changed"`
+	// Corrupted don't match the manifest although they were not modified after it was written
+	Corrupted []string `json:"corrupted"`
+	// New are under the path but not in the manifest
+	New    []string    `json:"new"`
+	Errors []FileError `json:"errors"`
+}
+
+type ChecksumTask struct {
+	task.TaskExtension
+	ChecksumArgs
+	Status string         `json:"-"`
+	Result ChecksumResult `json:"result"`
+	mu     sync.Mutex
+}
+
+func (t *ChecksumTask) GetName() string {
+	return fmt.Sprintf("checksum %s", t.Path)
+}
+
+func (t *ChecksumTask) GetStatus() string {
+	return t.Status
+}
+
+// GetResult returns a copy of the report
+func (t *ChecksumTask) GetResult() ChecksumResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.Result
+	r.Missing = append([]string(nil), r.Missing...)
+	r.Changed = append([]string(nil), r.Changed...)
+	r.Corrupted = append([]string(nil), r.Corrupted...)
+	r.New = append([]string(nil), r.New...)
+	r.Errors = append([]FileError(nil), r.Errors...)
+	return r
+}
+
+func (t *ChecksumTask) ctx() context.Context {
+	return context.WithValue(t.Ctx(), conf.UserKey, t.Creator)
+}
+
+func (t *ChecksumTask) addError(path string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Result.Errors = append(t.Result.Errors, FileError{Path: path, Error: err.Error()})
+}
+
+func (t *ChecksumTask) hashType() *utils.HashType {
+	ht, _ := utils.GetHashByName(t.HashType)
+	return ht
+}
+
+func (t *ChecksumTask) manifestName() string {
+	if t.Manifest != "" {
+		return t.Manifest
+	}
+	return "checksums." + t.hashType().Name
+}
+
+type checksumFile struct {
+	rel string
+	obj model.Obj
+}
+
+func (t *ChecksumTask) Run() error {
+	if err := t.ReinitCtx(); err != nil {
+		return err
+	}
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	manifestPath := stdpath.Join(t.Path, t.manifestName())
+	t.mu.Lock()
+	t.Result = ChecksumResult{Manifest: manifestPath}
+	t.mu.Unlock()
+
+	var manifest model.Obj
+	if !t.Rewrite {
+		obj, err := Get(t.ctx(), manifestPath, &GetArgs{NoLog: true})
+		if err == nil {
+			manifest = obj
+		} else if !errs.IsObjectNotFound(err) {
+			return errors.WithMessage(err, "failed get manifest")
+		}
+	}
+	t.mu.Lock()
+	t.Result.Verify = manifest != nil
+	t.mu.Unlock()
+
+	t.Status = "listing"
+	files, err := t.walk()
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.Result.Files = len(files)
+	t.mu.Unlock()
+	if manifest != nil {
+		return t.verify(files, manifest)
+	}
+	return t.write(files)
+}
+
+// walk lists the files under the path but the manifest
+func (t *ChecksumTask) walk() ([]checksumFile, error) {
+	root, err := Get(t.ctx(), t.Path, &GetArgs{NoLog: true})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed get [%s]", t.Path)
+	}
+	if !root.IsDir() {
+		return nil, errors.WithStack(errs.NotFolder)
+	}
+	prefix := strings.TrimSuffix(t.Path, "/") + "/"
+	var files []checksumFile
+	var total int64
+	err = walkFiles(t.ctx(), t.Path, &ListArgs{Refresh: true, NoLog: true}, func(reqPath string, f model.Obj) error {
+		if utils.IsCanceled(t.Ctx()) {
+			return t.Ctx().Err()
+		}
+		rel := strings.TrimPrefix(reqPath, prefix)
+		if rel == t.manifestName() {
+			return nil
+		}
+		files = append(files, checksumFile{rel: rel, obj: f})
+		total += f.GetSize()
+		t.Status = fmt.Sprintf("listed %d files", len(files))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].rel < files[j].rel })
+	t.SetTotalBytes(total)
+	return files, nil
+}
+
+// hashAll hashes the files, the ones failing are reported and left out
+func (t *ChecksumTask) hashAll(files []checksumFile) (map[string]string, error) {
+	ht := t.hashType()
+	hashes := make(map[string]string, len(files))
+	var done int64
+	for i, f := range files {
+		if utils.IsCanceled(t.Ctx()) {
+			return nil, t.Ctx().Err()
+		}
+		t.Status = fmt.Sprintf("hashing %d/%d %s", i+1, len(files), f.rel)
+		h := ""
+		if !t.Compute {
+			h = f.obj.GetHash().GetHash(ht)
+		}
+		if h == "" {
+			var err error
+			if h, err = hashFile(t.ctx(), stdpath.Join(t.Path, f.rel), ht); err != nil {
+				t.addError(f.rel, err)
+				continue
+			}
+		}
+		hashes[f.rel] = strings.ToLower(h)
+		done += f.obj.GetSize()
+		t.SetProgress(float64(done) / float64(max(t.GetTotalBytes(), 1)) * 100)
+	}
+	return hashes, nil
+}
+
+func (t *ChecksumTask) write(files []checksumFile) error {
+	hashes, err := t.hashAll(files)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	name := strings.ToUpper(t.hashType().Name)
+	for _, f := range files {
+		h, ok := hashes[f.rel]
+		if !ok {
+			continue
+		}
+		if t.Format == ChecksumGNU {
+			fmt.Fprintf(&buf, "%s  %s\n", h, f.rel)
+		} else {
+			fmt.Fprintf(&buf, "%s (%s) = %s\n", name, f.rel, h)
+		}
+	}
+	t.Status = "writing manifest"
+	storage, dirActualPath, err := op.GetStorageAndActualPath(t.Path)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	file := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     t.manifestName(),
+			Size:     int64(buf.Len()),
+			Modified: time.Now(),
+		},
+		Reader:   io.NopCloser(&buf),
+		Mimetype: "text/plain",
+	}
+	if err = putKeepingVersion(t.ctx(), storage, dirActualPath, file, nil, t.Creator); err != nil {
+		return errors.WithMessage(err, "failed put manifest")
+	}
+	t.mu.Lock()
+	t.Result.Verified = len(hashes)
+	failed := len(t.Result.Errors)
+	t.mu.Unlock()
+	t.SetProgress(100)
+	if failed > 0 {
+		t.Status = fmt.Sprintf("wrote %d checksums, %d files failed", len(hashes), failed)
+		return errors.Errorf("failed to hash %d files", failed)
+	}
+	t.Status = fmt.Sprintf("wrote %d checksums", len(hashes))
+	return nil
+}
+
+func (t *ChecksumTask) verify(files []checksumFile, manifest model.Obj) error {
+	t.Status = "reading manifest"
+	expected, err := readManifest(t.ctx(), stdpath.Join(t.Path, manifest.GetName()), t.hashType())
+	if err != nil {
+		return err
+	}
+	// hash only the files in the manifest
+	var listed []checksumFile
+	present := make(map[string]struct{}, len(files))
+	var newFiles []string
+	var total int64
+	for _, f := range files {
+		present[f.rel] = struct{}{}
+		if _, ok := expected[f.rel]; ok {
+			listed = append(listed, f)
+			total += f.obj.GetSize()
+		} else {
+			newFiles = append(newFiles, f.rel)
+		}
+	}
+	t.SetTotalBytes(total)
+	hashes, err := t.hashAll(listed)
+	if err != nil {
+		return err
+	}
+	var missing, changed, corrupted []string
+	verified := 0
+	for _, f := range listed {
+		h, ok := hashes[f.rel]
+		if !ok {
+			continue
+		}
+		if h == expected[f.rel] {
+			verified++
+		} else if f.obj.ModTime().After(manifest.ModTime()) {
+			changed = append(changed, f.rel)
+		} else {
+			corrupted = append(corrupted, f.rel)
+		}
+	}
+	for rel := range expected {
+		if _, ok := present[rel]; !ok {
+			missing = append(missing, rel)
+		}
+	}
+	sort.Strings(missing)
+	t.mu.Lock()
+	t.Result.Verified = verified
+	t.Result.Missing = missing
+	t.Result.Changed = changed
+	t.Result.Corrupted = corrupted
+	t.Result.New = newFiles
+	failed := len(t.Result.Errors)
+	t.mu.Unlock()
+	t.SetProgress(100)
+	t.Status = fmt.Sprintf("%d verified, %d missing, %d changed, %d corrupted, %d new",
+		verified, len(missing), len(changed), len(corrupted), len(newFiles))
+	if len(missing)+len(corrupted)+failed > 0 {
+		return errors.Errorf("verification failed: %s", t.Status)
+	}
+	return nil
+}
+
+var (
+	bsdLine = regexp.MustCompile(`^(\w+) \((.*)\) = ([0-9a-fA-F]+)$`)
+	gnuLine = regexp.MustCompile(`^([0-9a-fA-F]+) [ *](.+)$`)
+)
+
+// parseManifest reads the hashes of a manifest in bsd or gnu format by relative path,
+// the hashes of another type than ht are rejected instead of being reported as corrupted
+func parseManifest(r io.Reader, ht *utils.HashType) (map[string]string, error) {
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		var path, hash string
+		if m := bsdLine.FindStringSubmatch(line); m != nil {
+			if !strings.EqualFold(m[1], ht.Name) {
+				return nil, errors.Errorf("the manifest has %s hashes, set the hash type to %s to verify it",
+					m[1], strings.ToLower(m[1]))
+			}
+			path, hash = m[2], m[3]
+		} else if m = gnuLine.FindStringSubmatch(line); m != nil {
+			path, hash = m[2], m[1]
+		} else {
+			return nil, errors.Errorf("invalid manifest line: %s", line)
+		}
+		if len(hash) != ht.Width {
+			return nil, errors.Errorf("the hash of %s is not a %s hash", path, ht.Name)
+		}
+		hashes[path] = strings.ToLower(hash)
+	}
+	return hashes, scanner.Err()
+}
+
+func readManifest(ctx context.Context, path string, ht *utils.HashType) (map[string]string, error) {
+	link, obj, err := Link(ctx, path, model.LinkArgs{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get manifest link")
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		_ = link.Close()
+		return nil, errors.WithMessage(err, "failed get manifest stream")
+	}
+	defer ss.Close()
+	hashes, err := parseManifest(ss, ht)
+	return hashes, errors.WithMessage(err, "failed read manifest")
+}
+
+// hashFile reads the file to compute its hash
+func hashFile(ctx context.Context, path string, ht *utils.HashType) (string, error) {
+	link, obj, err := Link(ctx, path, model.LinkArgs{})
+	if err != nil {
+		return "", errors.WithMessage(err, "failed get link")
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{Obj: obj, Ctx: ctx}, link)
+	if err != nil {
+		_ = link.Close()
+		return "", errors.WithMessage(err, "failed get stream")
+	}
+	defer ss.Close()
+	return utils.HashReader(ht, ss)
+}
+
+func checksum(ctx context.Context, args ChecksumArgs) (task.TaskExtensionInfo, error) {
+	hashTypes := []string{utils.MD5.Name, utils.SHA1.Name, utils.SHA256.Name}
+	if args.HashType == "" {
+		args.HashType = utils.SHA256.Name
+		if ext := strings.ToLower(strings.TrimPrefix(stdpath.Ext(args.Manifest), ".")); utils.SliceContains(hashTypes, ext) {
+			args.HashType = ext
+		}
+	}
+	if !utils.SliceContains(hashTypes, args.HashType) {
+		return nil, errors.Errorf("unsupported hash type [%s]", args.HashType)
+	}
+	switch args.Format {
+	case "":
+		args.Format = ChecksumBSD
+	case ChecksumBSD, ChecksumGNU:
+	default:
+		return nil, errors.Errorf("unknown format [%s]", args.Format)
+	}
+	if strings.Contains(args.Manifest, "/") {
+		return nil, errors.New("the manifest must be a file name")
+	}
+	args.Path = utils.FixAndCleanPath(args.Path)
+	t := &ChecksumTask{ChecksumArgs: args}
+	t.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
+	t.ApiUrl = common.GetApiUrl(ctx)
+	ChecksumTaskManager.Add(t)
+	return t, nil
+}
+
+var ChecksumTaskManager *tache.Manager[*ChecksumTask]
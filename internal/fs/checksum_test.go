@@ -0,0 +1,105 @@
+package fs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func runChecksum(t *testing.T, args fs.ChecksumArgs) (fs.ChecksumResult, error) {
+	task := &fs.ChecksumTask{ChecksumArgs: args}
+	task.SetCtx(context.Background())
+	err := task.Run()
+	return task.GetResult(), err
+}
+
+func TestChecksumTask(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.txt":         "a",
+		"dir/b.txt":     "b",
+		"dir/c.txt":     "c",
+		"dir/gone.txt":  "gone",
+		"dir/stays.txt": "stays",
+	})
+	createLocal(t, "/checksum", root)
+	args := fs.ChecksumArgs{Path: "/checksum", HashType: "sha256", Format: fs.ChecksumGNU}
+
+	res, err := runChecksum(t, args)
+	if err != nil || res.Verify || res.Verified != 5 {
+		t.Fatalf("expect the manifest to be written, got %+v %v", res, err)
+	}
+	manifest, err := os.ReadFile(filepath.Join(root, "checksums.sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(manifest), utils.HashData(utils.SHA256, []byte("b"))+"  dir/b.txt\n") {
+		t.Errorf("unexpected manifest:\n%s", manifest)
+	}
+
+	res, err = runChecksum(t, args)
+	if err != nil || !res.Verify || res.Verified != 5 {
+		t.Fatalf("expect all the files to be verified, got %+v %v", res, err)
+	}
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	writeFiles(t, root, map[string]string{"dir/b.txt": "x", "dir/c.txt": "y", "new.txt": "new"})
+	if err = os.Chtimes(filepath.Join(root, "dir", "b.txt"), past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(filepath.Join(root, "dir", "c.txt"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(filepath.Join(root, "dir", "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	res, err = runChecksum(t, args)
+	if err == nil {
+		t.Errorf("expect the verification to fail")
+	}
+	expected := fs.ChecksumResult{
+		Verify:    true,
+		Manifest:  "/checksum/checksums.sha256",
+		Files:     5,
+		Verified:  2,
+		Missing:   []string{"dir/gone.txt"},
+		Changed:   []string{"dir/c.txt"},
+		Corrupted: []string{"dir/b.txt"},
+		New:       []string{"new.txt"},
+		Errors:    []fs.FileError{},
+	}
+	res.Errors = []fs.FileError{}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expect %+v, got %+v", expected, res)
+	}
+}
+
+func TestChecksumManifestHashType(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.txt":         "a",
+		"checksums.md5": "MD5 (a.txt) = " + utils.HashData(utils.MD5, []byte("a")) + "\n",
+		"gnu.sha1":      utils.HashData(utils.MD5, []byte("a")) + "  a.txt\n",
+	})
+	createLocal(t, "/checksum_type", root)
+
+	res, err := runChecksum(t, fs.ChecksumArgs{Path: "/checksum_type", HashType: "md5"})
+	if err != nil || !res.Verify || res.Verified != 1 {
+		t.Errorf("expect the md5 manifest to be verified, got %+v %v", res, err)
+	}
+	_, err = runChecksum(t, fs.ChecksumArgs{Path: "/checksum_type", HashType: "sha256", Manifest: "checksums.md5"})
+	if err == nil || !strings.Contains(err.Error(), "MD5 hashes") {
+		t.Errorf("expect the md5 manifest to be rejected for sha256, got %v", err)
+	}
+	_, err = runChecksum(t, fs.ChecksumArgs{Path: "/checksum_type", HashType: "sha1", Manifest: "gnu.sha1"})
+	if err == nil || !strings.Contains(err.Error(), "not a sha1 hash") {
+		t.Errorf("expect the md5 hashes to be rejected for sha1, got %v", err)
+	}
+}
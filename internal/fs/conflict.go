@@ -0,0 +1,233 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// the policies of a copy, move, upload or decompress for an object whose destination exists
+const (
+	ConflictOverwrite = "overwrite"
+	ConflictSkip      = "skip"
+	// the object is put under a free name like "name (1).ext"
+	ConflictRename = "rename"
+	// the files are overwritten when they are newer, or have another size, and skipped otherwise
+	ConflictIfNewer         = "overwrite_if_newer"
+	ConflictIfDifferentSize = "overwrite_if_different_size"
+	ConflictFail            = "fail"
+)
+
+var ConflictPolicies = []string{ConflictOverwrite, ConflictSkip, ConflictRename,
+	ConflictIfNewer, ConflictIfDifferentSize, ConflictFail}
+
+// CheckConflictPolicy validates the policy, "cancel" is the former name of fail
+func CheckConflictPolicy(policy string) (string, error) {
+	if policy == "cancel" {
+		return ConflictFail, nil
+	}
+	if !utils.SliceContains(ConflictPolicies, policy) {
+		return "", errors.Errorf("unknown conflict policy [%s]", policy)
+	}
+	return policy, nil
+}
+
+type conflictPolicyKey struct{}
+
+// WithConflictPolicy makes the copies and moves started with ctx resolve their conflicts
+// with the policy, they overwrite by default
+func WithConflictPolicy(ctx context.Context, policy string) context.Context {
+	return context.WithValue(ctx, conflictPolicyKey{}, policy)
+}
+
+func conflictPolicyOf(ctx context.Context) string {
+	if policy, ok := ctx.Value(conflictPolicyKey{}).(string); ok && policy != "" {
+		return policy
+	}
+	return ConflictOverwrite
+}
+
+type conflictAction uint8
+
+const (
+	// put src over dst, or into it when both are dirs
+	conflictPut conflictAction = iota
+	conflictSkip
+	conflictRename
+)
+
+// resolveConflict decides what to do with src whose namesake dst exists in the destination
+func resolveConflict(policy string, src, dst model.Obj) (conflictAction, error) {
+	switch policy {
+	case ConflictSkip:
+		return conflictSkip, nil
+	case ConflictRename:
+		return conflictRename, nil
+	case ConflictFail:
+		return conflictPut, errors.WithStack(errs.ObjectAlreadyExists)
+	}
+	// the dirs are merged, the files in them are compared one by one
+	if src.IsDir() || dst.IsDir() {
+		return conflictPut, nil
+	}
+	switch policy {
+	case ConflictIfNewer:
+		if !src.ModTime().After(dst.ModTime()) {
+			return conflictSkip, nil
+		}
+	case ConflictIfDifferentSize:
+		if src.GetSize() == dst.GetSize() {
+			return conflictSkip, nil
+		}
+	}
+	return conflictPut, nil
+}
+
+// freeName returns the first name like "name (1).ext" which is not in the dir
+func freeName(ctx context.Context, storage driver.Driver, dirPath, name string, isDir bool) (string, error) {
+	objs, err := op.List(ctx, storage, dirPath, model.ListArgs{Refresh: true})
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed list [%s]", dirPath)
+	}
+	names := make(map[string]struct{}, len(objs))
+	for _, obj := range objs {
+		names[obj.GetName()] = struct{}{}
+	}
+	base, ext := name, ""
+	if !isDir {
+		ext = stdpath.Ext(name)
+		base = strings.TrimSuffix(name, ext)
+	}
+	for i := 1; ; i++ {
+		newName := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, ok := names[newName]; !ok {
+			return newName, nil
+		}
+	}
+}
+
+type RenamedObj struct {
+	Path    string `json:"path"`
+	NewPath string `json:"new_path"`
+}
+
+// ConflictResult is what a task did with the objects whose destination existed,
+// the paths are the ones of the destinations
+type ConflictResult struct {
+	Skipped []string     `json:"skipped"`
+	Renamed []RenamedObj `json:"renamed"`
+}
+
+// conflictReport collects the conflicts of a task and of the ones it adds,
+// it's kept in memory only
+type conflictReport struct {
+	mu     sync.Mutex
+	result ConflictResult
+	// the payloads of a move run without task, for task_group.RefreshAndRemove
+	payloads []any
+}
+
+func (r *conflictReport) skip(path string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Skipped = append(r.result.Skipped, path)
+}
+
+func (r *conflictReport) rename(path, newPath string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Renamed = append(r.result.Renamed, RenamedObj{Path: path, NewPath: newPath})
+}
+
+func (r *conflictReport) addPayload(payload any) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = append(r.payloads, payload)
+}
+
+func (r *conflictReport) getPayloads() []any {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]any(nil), r.payloads...)
+}
+
+func (r *conflictReport) get() ConflictResult {
+	res := ConflictResult{Skipped: []string{}, Renamed: []RenamedObj{}}
+	if r == nil {
+		return res
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res.Skipped = append(res.Skipped, r.result.Skipped...)
+	res.Renamed = append(res.Renamed, r.result.Renamed...)
+	return res
+}
+
+// applyConflictPolicy looks for the namesake of src in dstDir and applies the policy to it.
+// It returns the name to put src under, or "" when src is skipped.
+func applyConflictPolicy(ctx context.Context, policy string, storage driver.Driver, dstDir string, src model.Obj, report *conflictReport) (string, error) {
+	name := src.GetName()
+	if policy == "" || policy == ConflictOverwrite {
+		return name, nil
+	}
+	dstPath := stdpath.Join(dstDir, name)
+	dst, _ := op.Get(ctx, storage, dstPath)
+	if dst == nil {
+		return name, nil
+	}
+	mountPath := storage.GetStorage().MountPath
+	action, err := resolveConflict(policy, src, dst)
+	if err != nil {
+		return "", errors.WithMessagef(err, "[%s]", stdpath.Join(mountPath, dstPath))
+	}
+	switch action {
+	case conflictSkip:
+		report.skip(stdpath.Join(mountPath, dstPath))
+		return "", nil
+	case conflictRename:
+		newName, err := freeName(ctx, storage, dstDir, name, src.IsDir())
+		if err != nil {
+			return "", err
+		}
+		if src.IsDir() {
+			// take the name at once, the content is put into it later
+			if err = op.MakeDir(ctx, storage, stdpath.Join(dstDir, newName)); err != nil {
+				return "", errors.WithMessagef(err, "failed make dir [%s]", newName)
+			}
+		}
+		report.rename(stdpath.Join(mountPath, dstPath), stdpath.Join(mountPath, dstDir, newName))
+		return newName, nil
+	}
+	return name, nil
+}
+
+// putConflict applies the policy to file about to be put into dstDirPath, it returns
+// the name to put the file under, or "" when the file should not be put
+func putConflict(ctx context.Context, dstDirPath string, file model.Obj, policy string) (string, error) {
+	storage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed get storage")
+	}
+	return applyConflictPolicy(ctx, policy, storage, dstDirActualPath, file, nil)
+}
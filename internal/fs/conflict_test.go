@@ -0,0 +1,102 @@
+package fs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/tache"
+)
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestConflictPolicies(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"a.txt":     "new content",
+		"b.txt":     "same",
+		"dir/c.txt": "c",
+	})
+	writeFiles(t, dst, map[string]string{
+		"a.txt":     "old",
+		"b.txt":     "diff",
+		"dir/d.txt": "d",
+	})
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dst, "a.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	createLocal(t, "/conflict_src", src)
+	createLocal(t, "/conflict_dst", dst)
+	noTask := context.WithValue(context.Background(), conf.NoTaskKey, struct{}{})
+	copyWith := func(policy, name string) error {
+		_, err := fs.Copy(fs.WithConflictPolicy(noTask, policy), "/conflict_src/"+name, "/conflict_dst")
+		return err
+	}
+
+	if err := copyWith(fs.ConflictSkip, "a.txt"); err != nil || readFile(t, filepath.Join(dst, "a.txt")) != "old" {
+		t.Errorf("expect a.txt to be skipped: %v", err)
+	}
+	if err := copyWith(fs.ConflictIfDifferentSize, "b.txt"); err != nil || readFile(t, filepath.Join(dst, "b.txt")) != "diff" {
+		t.Errorf("expect b.txt of the same size to be skipped: %v", err)
+	}
+	if err := copyWith(fs.ConflictFail, "b.txt"); !errors.Is(err, errs.ObjectAlreadyExists) {
+		t.Errorf("expect the copy to fail, got %v", err)
+	}
+	if err := copyWith(fs.ConflictIfNewer, "a.txt"); err != nil || readFile(t, filepath.Join(dst, "a.txt")) != "new content" {
+		t.Errorf("expect the newer a.txt to overwrite: %v", err)
+	}
+
+	// a move keeps the sources it skipped
+	_, err := fs.Move(fs.WithConflictPolicy(noTask, fs.ConflictSkip), "/conflict_src/b.txt", "/conflict_dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(src, "b.txt")); err != nil {
+		t.Errorf("expect the skipped b.txt to be kept: %v", err)
+	}
+	_, err = fs.Move(fs.WithConflictPolicy(noTask, fs.ConflictRename), "/conflict_src/b.txt", "/conflict_dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readFile(t, filepath.Join(dst, "b (1).txt")) != "same" || readFile(t, filepath.Join(dst, "b.txt")) != "diff" {
+		t.Errorf("expect b.txt to be moved as b (1).txt")
+	}
+	if _, err = os.Stat(filepath.Join(src, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("expect the moved b.txt to be removed: %v", err)
+	}
+
+	fs.CopyTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(2))
+	tsk, err := fs.Copy(fs.WithConflictPolicy(context.Background(), fs.ConflictRename), "/conflict_src/dir", "/conflict_dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		if len(fs.CopyTaskManager.GetByState(tache.StateSucceeded, tache.StateFailed)) == len(fs.CopyTaskManager.GetAll()) {
+			break
+		}
+		if i == 200 {
+			t.Fatal("the copy tasks are not over")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if readFile(t, filepath.Join(dst, "dir (1)", "c.txt")) != "c" {
+		t.Errorf("expect dir to be copied as dir (1)")
+	}
+	res := tsk.(*fs.FileTransferTask).GetResult()
+	if len(res.Renamed) != 1 || res.Renamed[0].Path != "/conflict_dst/dir" || res.Renamed[0].NewPath != "/conflict_dst/dir (1)" {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
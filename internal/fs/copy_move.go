@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
@@ -17,6 +18,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/tache"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type taskType uint8
@@ -39,15 +41,36 @@ const (
 
 type FileTransferTask struct {
 	TaskData
-	TaskType taskType
-	groupID  string
+	TaskType       taskType
+	ConflictPolicy string `json:"conflict_policy,omitempty"`
+	// the checkpoint of the task: the name the object is put under, the children of a dir
+	// which were handed over, and the source file whose upload can be carried on if it's unchanged
+	DstName     string    `json:"dst_name,omitempty"`
+	Finished    []string  `json:"finished,omitempty"`
+	SrcSize     int64     `json:"src_size,omitempty"`
+	SrcModified time.Time `json:"src_modified"`
+	groupID     string
+	report      *conflictReport
+}
+
+// GetResult returns the conflicts of the task and of the ones it added
+func (t *FileTransferTask) GetResult() ConflictResult {
+	return t.report.get()
 }
 
 func (t *FileTransferTask) GetName() string {
 	return fmt.Sprintf("%s [%s](%s) to [%s](%s)", t.TaskType, t.SrcStorageMp, t.SrcActualPath, t.DstStorageMp, t.DstActualPath)
 }
 
-func (t *FileTransferTask) Run() error {
+func (t *FileTransferTask) Run() (err error) {
+	if err := t.BeforeRun(); err != nil {
+		return err
+	}
+	defer func() {
+		if t.IsPaused() {
+			err = task.ErrPaused
+		}
+	}()
 	if err := t.ReinitCtx(); err != nil {
 		return err
 	}
@@ -64,6 +87,9 @@ func (t *FileTransferTask) Run() error {
 		}
 	}
 
+	if t.report == nil {
+		t.report = &conflictReport{}
+	}
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
@@ -79,11 +105,40 @@ func (t *FileTransferTask) Run() error {
 	})
 }
 
+// Cancel cancels the task, the staged upload is removed if the canceled task can't be retried
+func (t *FileTransferTask) Cancel() {
+	t.TaskExtension.Cancel()
+	if !conf.Conf.Tasks.AllowRetryCanceled {
+		t.Discard()
+	}
+}
+
+// Discard removes the upload kept to be carried on, if the file upload was started
+func (t *FileTransferTask) Discard() {
+	if t.SrcModified.IsZero() || t.DstName == "" {
+		return
+	}
+	storage := t.DstStorage
+	if storage == nil {
+		var err error
+		if storage, _, err = op.GetStorageAndActualPath(t.DstStorageMp); err != nil {
+			return
+		}
+	}
+	if err := op.DiscardResumablePut(context.Background(), storage, t.DstActualPath, t.DstName); err != nil {
+		log.Warnf("failed discard the upload of [%s](%s): %+v", t.DstStorageMp, stdpath.Join(t.DstActualPath, t.DstName), err)
+	}
+}
+
 func (t *FileTransferTask) OnSucceeded() {
 	task_group.TransferCoordinator.Done(t.groupID, true)
 }
 
 func (t *FileTransferTask) OnFailed() {
+	if t.IsPaused() {
+		// it's still one of the group, until it's resumed and over
+		return
+	}
 	task_group.TransferCoordinator.Done(t.groupID, false)
 }
 
@@ -91,7 +146,7 @@ func (t *FileTransferTask) SetRetry(retry int, maxRetry int) {
 	t.TaskExtension.SetRetry(retry, maxRetry)
 	if retry == 0 &&
 		(len(t.groupID) == 0 || // 重启恢复
-			(t.GetErr() == nil && t.GetState() != tache.StatePending)) { // 手动重试
+			(!t.Resuming() && t.GetErr() == nil && t.GetState() != tache.StatePending)) { // 手动重试
 		t.groupID = stdpath.Join(t.DstStorageMp, t.DstActualPath)
 		var payload any
 		if t.TaskType == move {
@@ -111,7 +166,12 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 		return nil, errors.WithMessage(err, "failed get dst storage")
 	}
 
-	if srcStorage.GetStorage() == dstStorage.GetStorage() {
+	policy := conflictPolicyOf(ctx)
+	if taskType == merge {
+		// merge has its own rule, the existing files are skipped
+		policy = ConflictOverwrite
+	}
+	if srcStorage.GetStorage() == dstStorage.GetStorage() && canTransferDirectly(ctx, policy, srcStorage, srcObjActualPath, dstDirActualPath) {
 		if taskType == copy || taskType == merge {
 			err = op.Copy(ctx, srcStorage, srcObjActualPath, dstDirActualPath, lazyCache...)
 			if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.NotSupport) {
@@ -135,7 +195,9 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 			SrcStorageMp:  srcStorage.GetStorage().MountPath,
 			DstStorageMp:  dstStorage.GetStorage().MountPath,
 		},
-		TaskType: taskType,
+		TaskType:       taskType,
+		ConflictPolicy: policy,
+		report:         &conflictReport{},
 	}
 
 	if ctx.Value(conf.NoTaskKey) != nil {
@@ -153,7 +215,8 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 		err = t.RunWithNextTaskCallback(callback)
 		if hasSuccess || err == nil {
 			if taskType == move {
-				task_group.RefreshAndRemove(dstDirPath, task_group.SrcPathToRemove(srcObjPath))
+				payloads := append(t.report.getPayloads(), task_group.SrcPathToRemove(srcObjPath))
+				task_group.RefreshAndRemove(dstDirPath, payloads...)
 			} else {
 				op.Cache.DeleteDirectory(t.DstStorage, dstDirActualPath)
 			}
@@ -180,6 +243,18 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 	if err != nil {
 		return errors.WithMessagef(err, "failed get src [%s] file", t.SrcActualPath)
 	}
+	name := t.DstName
+	if name == "" {
+		name, err = t.applyConflictPolicy(srcObj)
+		if err != nil || name == "" {
+			return err
+		}
+		// a renamed object keeps its name when the task is run again
+		t.DstName = name
+	}
+	if name != srcObj.GetName() {
+		srcObj = &model.ObjWrapName{Name: name, Obj: srcObj}
+	}
 
 	if srcObj.IsDir() {
 		t.Status = "src object is dir, listing objs"
@@ -206,10 +281,17 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 			}
 		}
 
+		finished := make(map[string]struct{}, len(t.Finished))
+		for _, name := range t.Finished {
+			finished[name] = struct{}{}
+		}
 		for _, obj := range objs {
 			if utils.IsCanceled(t.Ctx()) {
 				return nil
 			}
+			if _, ok := finished[obj.GetName()]; ok {
+				continue
+			}
 
 			if t.TaskType == merge && !obj.IsDir() && existedObjs[obj.GetName()] {
 				// skip existed file
@@ -217,7 +299,9 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 			}
 
 			err = f(&FileTransferTask{
-				TaskType: t.TaskType,
+				TaskType:       t.TaskType,
+				ConflictPolicy: t.ConflictPolicy,
+				report:         t.report,
 				TaskData: TaskData{
 					TaskExtension: task.TaskExtension{
 						Creator: t.Creator,
@@ -234,6 +318,8 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 			if err != nil {
 				return err
 			}
+			t.Finished = append(t.Finished, obj.GetName())
+			t.Persist()
 		}
 		t.Status = fmt.Sprintf("src object is dir, added all %s tasks of objs", t.TaskType)
 		return nil
@@ -254,7 +340,53 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 	}
 	t.SetTotalBytes(ss.GetSize())
 	t.Status = "uploading"
-	return op.Put(t.Ctx(), t.DstStorage, t.DstActualPath, ss, t.SetProgress, true)
+	ctx := t.Ctx()
+	if ctx.Value(conf.NoTaskKey) == nil {
+		// the upload is carried on when the task is run again for the same source file
+		resume := !t.SrcModified.IsZero() && t.SrcModified.Equal(srcObj.ModTime()) && t.SrcSize == srcObj.GetSize()
+		t.SrcSize, t.SrcModified = srcObj.GetSize(), srcObj.ModTime()
+		t.Persist()
+		ctx = op.WithResumablePut(ctx, resume)
+	}
+	return op.Put(ctx, t.DstStorage, t.DstActualPath, ss, t.SetProgress, true)
+}
+
+// canTransferDirectly reports whether the storage can copy or move the object itself,
+// which it does overwriting, so only when the policy would overwrite it too
+func canTransferDirectly(ctx context.Context, policy string, storage driver.Driver, srcActualPath, dstDirActualPath string) bool {
+	if policy == ConflictOverwrite {
+		return true
+	}
+	dstObj, _ := op.Get(ctx, storage, stdpath.Join(dstDirActualPath, stdpath.Base(srcActualPath)))
+	if dstObj == nil {
+		return true
+	}
+	srcObj, err := op.Get(ctx, storage, srcActualPath)
+	if err != nil || srcObj.IsDir() {
+		return false
+	}
+	action, err := resolveConflict(policy, srcObj, dstObj)
+	return err == nil && action == conflictPut
+}
+
+// applyConflictPolicy returns the name to transfer srcObj under, or "" when it's skipped
+func (t *FileTransferTask) applyConflictPolicy(srcObj model.Obj) (string, error) {
+	name, err := applyConflictPolicy(t.Ctx(), t.ConflictPolicy, t.DstStorage, t.DstActualPath, srcObj, t.report)
+	if err != nil || t.TaskType != move || name == srcObj.GetName() {
+		return name, err
+	}
+	// the source of a move is removed once it's found in the destination, under the same name
+	srcPath := stdpath.Join(t.SrcStorageMp, t.SrcActualPath)
+	var payload any = task_group.SrcPathToKeep(srcPath)
+	if name != "" {
+		payload = task_group.SrcPathRenamed{Path: srcPath, Name: name}
+	}
+	if len(t.groupID) > 0 {
+		task_group.TransferCoordinator.AppendPayload(t.groupID, payload)
+	} else {
+		t.report.addPayload(payload)
+	}
+	return name, nil
 }
 
 var (
@@ -0,0 +1,438 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	stdpath "path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+)
+
+// actions applied to the duplicates of a report, the first file of each group is kept
+const (
+	DedupeDelete = "delete"
+	// DedupeLink replaces each duplicate with an internet shortcut to the kept file
+	DedupeLink = "link"
+)
+
+type DedupeArgs struct {
+	Paths   []string `json:"paths"`
+	MinSize int64    `json:"min_size"`
+	// StreamHash downloads the files to hash them when their storages don't provide a common hash
+	StreamHash bool `json:"stream_hash"`
+}
+
+// DuplicateGroup is a set of files with the same content, Files[0] is the one kept
+type DuplicateGroup struct {
+	Size     int64    `json:"size"`
+	HashType string   `json:"hash_type"`
+	Hash     string   `json:"hash"`
+	Files    []string `json:"files"`
+}
+
+type DedupeResult struct {
+	Scanned int              `json:"scanned"`
+	Groups  []DuplicateGroup `json:"groups"`
+	// ReclaimableBytes is the size of all the duplicates but the kept files
+	ReclaimableBytes int64 `json:"reclaimable_bytes"`
+	// Unconfirmed is the number of files of the same size as others which couldn't be hashed
+	Unconfirmed int         `json:"unconfirmed"`
+	Errors      []FileError `json:"errors"`
+}
+
+// DedupeTask finds the duplicates in the paths, or applies the action to the groups of a report
+// when Action is set
+type DedupeTask struct {
+	task.TaskExtension
+	DedupeArgs
+	Action string           `json:"action"`
+	Groups []DuplicateGroup `json:"groups"`
+	Status string           `json:"-"`
+	Result DedupeResult     `json:"result"`
+	mu     sync.Mutex
+}
+
+func (t *DedupeTask) GetName() string {
+	if t.Action != "" {
+		return fmt.Sprintf("%s duplicates found in %s", t.Action, strings.Join(t.Paths, ", "))
+	}
+	return fmt.Sprintf("find duplicates in %s", strings.Join(t.Paths, ", "))
+}
+
+func (t *DedupeTask) GetStatus() string {
+	return t.Status
+}
+
+// GetResult returns a copy of the report
+func (t *DedupeTask) GetResult() DedupeResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := t.Result
+	r.Groups = append([]DuplicateGroup(nil), r.Groups...)
+	r.Errors = append([]FileError(nil), r.Errors...)
+	return r
+}
+
+// ctx is the task context carrying the creator, so that the listings hide what the creator can't see
+func (t *DedupeTask) ctx() context.Context {
+	return context.WithValue(t.Ctx(), conf.UserKey, t.Creator)
+}
+
+func (t *DedupeTask) addError(path string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Result.Errors = append(t.Result.Errors, FileError{Path: path, Error: err.Error()})
+}
+
+type dedupeFile struct {
+	path string
+	obj  model.Obj
+}
+
+func (t *DedupeTask) Run() error {
+	if err := t.ReinitCtx(); err != nil {
+		return err
+	}
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	t.mu.Lock()
+	t.Result = DedupeResult{}
+	t.mu.Unlock()
+	if t.Action != "" {
+		return t.runApply()
+	}
+
+	t.Status = "scanning"
+	bySize, scanned, err := t.scan()
+	if err != nil {
+		return err
+	}
+	var candidates [][]dedupeFile
+	var total int64
+	for _, files := range bySize {
+		if len(files) > 1 {
+			candidates = append(candidates, files)
+			total += files[0].obj.GetSize() * int64(len(files))
+		}
+	}
+	t.SetTotalBytes(total)
+
+	var groups []DuplicateGroup
+	unconfirmed := 0
+	var done int64
+	for i, files := range candidates {
+		if utils.IsCanceled(t.Ctx()) {
+			return t.Ctx().Err()
+		}
+		t.Status = fmt.Sprintf("comparing %d/%d sizes", i+1, len(candidates))
+		g, n := t.confirm(files)
+		groups = append(groups, g...)
+		unconfirmed += n
+		done += files[0].obj.GetSize() * int64(len(files))
+		if total > 0 {
+			t.SetProgress(float64(done) / float64(total) * 100)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Files[0] < groups[j].Files[0]
+	})
+	var reclaimable int64
+	for _, g := range groups {
+		reclaimable += g.Size * int64(len(g.Files)-1)
+	}
+	t.mu.Lock()
+	t.Result.Scanned = scanned
+	t.Result.Groups = groups
+	t.Result.ReclaimableBytes = reclaimable
+	t.Result.Unconfirmed = unconfirmed
+	t.mu.Unlock()
+	t.SetProgress(100)
+	t.Status = fmt.Sprintf("found %d groups of duplicates", len(groups))
+	return nil
+}
+
+// scan walks the paths and groups the files by size, a file reached through several
+// overlapping storages is only counted once
+func (t *DedupeTask) scan() (map[int64][]dedupeFile, int, error) {
+	bySize := make(map[int64][]dedupeFile)
+	seen := make(map[string]struct{})
+	scanned := 0
+	for _, path := range t.Paths {
+		obj, err := Get(t.ctx(), path, &GetArgs{NoLog: true})
+		if err != nil {
+			return nil, 0, errors.WithMessagef(err, "failed get [%s]", path)
+		}
+		fn := func(reqPath string, f model.Obj) error {
+			if utils.IsCanceled(t.Ctx()) {
+				return t.Ctx().Err()
+			}
+			storage, actualPath, err := op.GetStorageAndActualPath(reqPath)
+			if err != nil {
+				t.addError(reqPath, err)
+				return nil
+			}
+			id := op.Identify(t.ctx(), storage, actualPath)
+			if _, ok := seen[id]; ok {
+				return nil
+			}
+			seen[id] = struct{}{}
+			scanned++
+			t.Status = fmt.Sprintf("scanned %d files", scanned)
+			if f.GetSize() <= 0 || f.GetSize() < t.MinSize {
+				return nil
+			}
+			bySize[f.GetSize()] = append(bySize[f.GetSize()], dedupeFile{path: reqPath, obj: f})
+			return nil
+		}
+		if obj.IsDir() {
+			err = walkFiles(t.ctx(), path, &ListArgs{Refresh: true, NoLog: true}, fn)
+		} else {
+			err = fn(path, obj)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return bySize, scanned, nil
+}
+
+// confirm groups the files of the same size by hash, the files whose hash is not
+// provided by their storage are streamed, or left unconfirmed
+func (t *DedupeTask) confirm(files []dedupeFile) ([]DuplicateGroup, int) {
+	counts := make(map[*utils.HashType]int)
+	for _, f := range files {
+		for ht, h := range f.obj.GetHash().All() {
+			if h != "" {
+				counts[ht]++
+			}
+		}
+	}
+	// the type provided for the most files among the ones which can be computed,
+	// unless a type is provided for all of them
+	ht := utils.MD5
+	for _, s := range []*utils.HashType{utils.SHA1, utils.SHA256} {
+		if counts[s] > counts[ht] {
+			ht = s
+		}
+	}
+	for _, s := range utils.Supported {
+		if counts[s] == len(files) {
+			ht = s
+			break
+		}
+	}
+	byHash := make(map[string][]string)
+	unconfirmed := 0
+	for _, f := range files {
+		h := f.obj.GetHash().GetHash(ht)
+		if h == "" {
+			if !t.StreamHash {
+				unconfirmed++
+				continue
+			}
+			t.Status = fmt.Sprintf("hashing %s", f.path)
+			var err error
+			if h, err = hashFile(t.ctx(), f.path, ht); err != nil {
+				unconfirmed++
+				t.addError(f.path, err)
+				continue
+			}
+		}
+		h = strings.ToLower(h)
+		byHash[h] = append(byHash[h], f.path)
+	}
+	var groups []DuplicateGroup
+	for h, paths := range byHash {
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		groups = append(groups, DuplicateGroup{
+			Size:     files[0].obj.GetSize(),
+			HashType: ht.Name,
+			Hash:     h,
+			Files:    paths,
+		})
+	}
+	return groups, unconfirmed
+}
+
+func (t *DedupeTask) runApply() error {
+	t.mu.Lock()
+	t.Result.Groups = t.Groups
+	t.mu.Unlock()
+	failed, err := t.apply(t.Groups)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		t.Status = fmt.Sprintf("%s done with %d errors", t.Action, failed)
+		return errors.Errorf("failed to %s %d duplicates", t.Action, failed)
+	}
+	t.SetProgress(100)
+	t.Status = fmt.Sprintf("%s done", t.Action)
+	return nil
+}
+
+// apply deletes or links the duplicates and returns how many failed. The files are checked
+// again as they may have changed since the report, and the duplicates which turn out to be
+// the kept file reached through another storage are skipped.
+func (t *DedupeTask) apply(groups []DuplicateGroup) (int, error) {
+	total, done := 0, 0
+	for _, g := range groups {
+		total += len(g.Files) - 1
+	}
+	failed := 0
+	for _, g := range groups {
+		kept := g.Files[0]
+		t.Status = fmt.Sprintf("checking %s", kept)
+		keptID, err := t.verify(kept, g)
+		if err != nil {
+			failed += len(g.Files) - 1
+			done += len(g.Files) - 1
+			t.addError(kept, err)
+			continue
+		}
+		for _, path := range g.Files[1:] {
+			if utils.IsCanceled(t.Ctx()) {
+				return failed, t.Ctx().Err()
+			}
+			done++
+			t.SetProgress(float64(done) / float64(total) * 100)
+			t.Status = fmt.Sprintf("%s %s", t.Action, path)
+			id, err := t.verify(path, g)
+			if err == nil && id == keptID {
+				continue
+			}
+			if err == nil && t.Action == DedupeLink {
+				err = t.link(path, kept)
+			}
+			if err == nil {
+				err = t.remove(path)
+			}
+			if err != nil {
+				failed++
+				t.addError(path, err)
+			}
+		}
+	}
+	return failed, nil
+}
+
+// verify checks that the file still has the content of the group and returns its key, see op.Identify
+func (t *DedupeTask) verify(path string, g DuplicateGroup) (string, error) {
+	obj, err := Get(t.ctx(), path, &GetArgs{NoLog: true})
+	if err != nil {
+		return "", err
+	}
+	changed := errors.New("the file changed since the report")
+	if obj.IsDir() || obj.GetSize() != g.Size {
+		return "", changed
+	}
+	ht, ok := utils.GetHashByName(g.HashType)
+	if !ok {
+		return "", errors.Errorf("unknown hash type [%s]", g.HashType)
+	}
+	h := obj.GetHash().GetHash(ht)
+	if h == "" {
+		if !t.StreamHash {
+			return "", errors.Errorf("the %s hash of the file is not provided", ht.Name)
+		}
+		if h, err = hashFile(t.ctx(), path, ht); err != nil {
+			return "", err
+		}
+	}
+	if !strings.EqualFold(h, g.Hash) {
+		return "", changed
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed get storage")
+	}
+	return op.Identify(t.ctx(), storage, actualPath), nil
+}
+
+func (t *DedupeTask) remove(path string) error {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	return removeObj(t.ctx(), storage, actualPath, t.Creator)
+}
+
+// link puts an internet shortcut named after the duplicate next to it, pointing to the signed
+// download link of the kept file
+func (t *DedupeTask) link(path, kept string) error {
+	content := []byte(fmt.Sprintf("[InternetShortcut]\r\nURL=%s/d%s?sign=%s\r\n",
+		t.ApiUrl, utils.EncodePath(kept, true), sign.Sign(kept)))
+	file := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     stdpath.Base(path) + ".url",
+			Size:     int64(len(content)),
+			Modified: time.Now(),
+		},
+		Reader:   io.NopCloser(bytes.NewReader(content)),
+		Mimetype: "application/internet-shortcut",
+	}
+	storage, dirActualPath, err := op.GetStorageAndActualPath(stdpath.Dir(path))
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	return putKeepingVersion(t.ctx(), storage, dirActualPath, file, nil, t.Creator)
+}
+
+func findDuplicates(ctx context.Context, args DedupeArgs) (task.TaskExtensionInfo, error) {
+	if len(args.Paths) == 0 {
+		return nil, errors.New("no path to search")
+	}
+	for i, path := range args.Paths {
+		args.Paths[i] = utils.FixAndCleanPath(path)
+	}
+	t := &DedupeTask{DedupeArgs: args}
+	t.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
+	t.ApiUrl = common.GetApiUrl(ctx)
+	DedupeTaskManager.Add(t)
+	return t, nil
+}
+
+// applyDuplicates adds a task applying the action to the duplicates found by the report
+func applyDuplicates(ctx context.Context, report *DedupeTask, action string) (task.TaskExtensionInfo, error) {
+	switch action {
+	case DedupeDelete, DedupeLink:
+	default:
+		return nil, errors.Errorf("unknown action [%s]", action)
+	}
+	if report.Action != "" || report.GetState() != tache.StateSucceeded {
+		return nil, errors.New("the task is not a finished search of duplicates")
+	}
+	res := report.GetResult()
+	if len(res.Groups) == 0 {
+		return nil, errors.New("no duplicates to " + action)
+	}
+	t := &DedupeTask{DedupeArgs: report.DedupeArgs, Action: action, Groups: res.Groups}
+	t.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
+	t.ApiUrl = common.GetApiUrl(ctx)
+	DedupeTaskManager.Add(t)
+	return t, nil
+}
+
+var DedupeTaskManager *tache.Manager[*DedupeTask]
@@ -0,0 +1,149 @@
+package fs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/alias"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func runDedupe(t *testing.T, args fs.DedupeArgs) *fs.DedupeTask {
+	task := &fs.DedupeTask{DedupeArgs: args}
+	task.SetCtx(context.Background())
+	if err := task.Run(); err != nil {
+		t.Fatalf("failed to find duplicates: %+v", err)
+	}
+	return task
+}
+
+// applyDedupe applies the action to the groups of the report like the task added by fs.ApplyDuplicates
+func applyDedupe(t *testing.T, report *fs.DedupeTask, action string, groups []fs.DuplicateGroup) {
+	task := &fs.DedupeTask{DedupeArgs: report.DedupeArgs, Action: action, Groups: groups}
+	task.SetCtx(context.Background())
+	if err := task.Run(); err != nil {
+		t.Fatalf("failed to %s the duplicates: %+v", action, err)
+	}
+}
+
+func TestDedupeTask(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	writeFiles(t, a, map[string]string{
+		"one.txt":     "duplicate",
+		"sub/two.txt": "duplicate",
+		"other.txt":   "different",
+		"single.txt":  "single content",
+	})
+	writeFiles(t, b, map[string]string{
+		"three.txt": "duplicate",
+	})
+	createLocal(t, "/dedupe_a", a)
+	createLocal(t, "/dedupe_b", b)
+	paths := []string{"/dedupe_a", "/dedupe_b", "/dedupe_a/sub"}
+
+	res := runDedupe(t, fs.DedupeArgs{Paths: paths}).GetResult()
+	if len(res.Groups) != 0 || res.Unconfirmed != 4 || res.Scanned != 5 {
+		t.Errorf("expect the files of the same size to be unconfirmed without hash, got %+v", res)
+	}
+
+	report := runDedupe(t, fs.DedupeArgs{Paths: paths, StreamHash: true})
+	res = report.GetResult()
+	if len(res.Groups) != 1 {
+		t.Fatalf("expect 1 group, got %+v", res)
+	}
+	g := res.Groups[0]
+	expected := []string{"/dedupe_a/one.txt", "/dedupe_a/sub/two.txt", "/dedupe_b/three.txt"}
+	if len(g.Files) != len(expected) {
+		t.Fatalf("expect files %v, got %v", expected, g.Files)
+	}
+	for i := range expected {
+		if g.Files[i] != expected[i] {
+			t.Errorf("expect files %v, got %v", expected, g.Files)
+			break
+		}
+	}
+	if res.ReclaimableBytes != 2*int64(len("duplicate")) {
+		t.Errorf("unexpected reclaimable bytes: %d", res.ReclaimableBytes)
+	}
+
+	for _, p := range []string{filepath.Join(a, "sub", "two.txt"), filepath.Join(b, "three.txt")} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expect %s to be kept by the search, got %v", p, err)
+		}
+	}
+	applyDedupe(t, report, fs.DedupeDelete, res.Groups)
+	for _, p := range []string{filepath.Join(a, "sub", "two.txt"), filepath.Join(b, "three.txt")} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("expect %s to be deleted, got %v", p, err)
+		}
+	}
+	for _, p := range []string{filepath.Join(a, "one.txt"), filepath.Join(a, "other.txt")} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expect %s to be kept, got %v", p, err)
+		}
+	}
+}
+
+func TestDedupeLink(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.txt": "duplicate",
+		"b.txt": "duplicate",
+	})
+	createLocal(t, "/dedupe_link", root)
+
+	report := runDedupe(t, fs.DedupeArgs{Paths: []string{"/dedupe_link"}, StreamHash: true})
+	applyDedupe(t, report, fs.DedupeLink, report.GetResult().Groups)
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); !os.IsNotExist(err) {
+		t.Errorf("expect the duplicate to be replaced, got %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(root, "b.txt.url"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "/d/dedupe_link/a.txt?sign="+sign.Sign("/dedupe_link/a.txt")) {
+		t.Errorf("expect the shortcut to point to the signed link of the kept file, got %q", content)
+	}
+}
+
+func TestDedupeOverlappingStorages(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"only.txt": "the only copy",
+	})
+	createLocal(t, "/dedupe_twice_a", root)
+	createLocal(t, "/dedupe_twice_b", root)
+	if _, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Alias",
+		MountPath: "/dedupe_alias",
+		Addition:  `{"paths":"/dedupe_twice_a"}`,
+	}); err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+
+	report := runDedupe(t, fs.DedupeArgs{
+		Paths:      []string{"/dedupe_twice_a", "/dedupe_twice_b", "/dedupe_alias"},
+		StreamHash: true,
+	})
+	if res := report.GetResult(); len(res.Groups) != 0 || res.Scanned != 1 {
+		t.Errorf("expect the same file through each storage to be scanned once, got %+v", res)
+	}
+
+	// a report listing the same file twice must not remove it
+	applyDedupe(t, report, fs.DedupeDelete, []fs.DuplicateGroup{{
+		Size:     int64(len("the only copy")),
+		HashType: utils.MD5.Name,
+		Hash:     utils.HashData(utils.MD5, []byte("the only copy")),
+		Files:    []string{"/dedupe_twice_a/only.txt", "/dedupe_twice_b/only.txt", "/dedupe_alias/only.txt"},
+	}})
+	if _, err := os.Stat(filepath.Join(root, "only.txt")); err != nil {
+		t.Errorf("expect the only copy to be kept, got %v", err)
+	}
+}
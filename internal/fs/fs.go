@@ -3,6 +3,7 @@ package fs
 import (
 	"context"
 	"io"
+	stdpath "path"
 
 	log "github.com/sirupsen/logrus"
 
@@ -18,6 +19,18 @@ import (
 // So, the purpose of this package is to convert mount path to actual path
 // then pass the actual path to the op package
 
+// checkHidden returns errs.ObjectNotFound if one of the paths is in the recycle bin, the versions
+// folder or the folder of the staged uploads of its storage, they are only reachable through
+// the recycle and versions api
+func checkHidden(paths ...string) error {
+	for _, path := range paths {
+		if op.IsHiddenPath(path) {
+			return errors.WithStack(errs.ObjectNotFound)
+		}
+	}
+	return nil
+}
+
 type ListArgs struct {
 	Refresh            bool
 	NoLog              bool
@@ -25,6 +38,9 @@ type ListArgs struct {
 }
 
 func List(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error) {
+	if err := checkHidden(path); err != nil {
+		return nil, err
+	}
 	res, err := list(ctx, path, args)
 	if err != nil {
 		if !args.NoLog {
@@ -41,6 +57,9 @@ type GetArgs struct {
 }
 
 func Get(ctx context.Context, path string, args *GetArgs) (model.Obj, error) {
+	if err := checkHidden(path); err != nil {
+		return nil, err
+	}
 	res, err := get(ctx, path, args)
 	if err != nil {
 		if !args.NoLog {
@@ -52,6 +71,9 @@ func Get(ctx context.Context, path string, args *GetArgs) (model.Obj, error) {
 }
 
 func Link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, model.Obj, error) {
+	if err := checkHidden(path); err != nil {
+		return nil, nil, err
+	}
 	res, file, err := link(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed link %s: %+v", path, err)
@@ -61,6 +83,9 @@ func Link(ctx context.Context, path string, args model.LinkArgs) (*model.Link, m
 }
 
 func MakeDir(ctx context.Context, path string, lazyCache ...bool) error {
+	if err := checkHidden(path); err != nil {
+		return err
+	}
 	err := makeDir(ctx, path, lazyCache...)
 	if err != nil {
 		log.Errorf("failed make dir %s: %+v", path, err)
@@ -69,6 +94,9 @@ func MakeDir(ctx context.Context, path string, lazyCache ...bool) error {
 }
 
 func Move(ctx context.Context, srcPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+	if err := checkHidden(srcPath, dstDirPath); err != nil {
+		return nil, err
+	}
 	req, err := transfer(ctx, move, srcPath, dstDirPath, lazyCache...)
 	if err != nil {
 		log.Errorf("failed move %s to %s: %+v", srcPath, dstDirPath, err)
@@ -77,6 +105,9 @@ func Move(ctx context.Context, srcPath, dstDirPath string, lazyCache ...bool) (t
 }
 
 func Copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+	if err := checkHidden(srcObjPath, dstDirPath); err != nil {
+		return nil, err
+	}
 	res, err := transfer(ctx, copy, srcObjPath, dstDirPath, lazyCache...)
 	if err != nil {
 		log.Errorf("failed copy %s to %s: %+v", srcObjPath, dstDirPath, err)
@@ -85,6 +116,9 @@ func Copy(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool)
 }
 
 func Merge(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+	if err := checkHidden(srcObjPath, dstDirPath); err != nil {
+		return nil, err
+	}
 	res, err := transfer(ctx, merge, srcObjPath, dstDirPath, lazyCache...)
 	if err != nil {
 		log.Errorf("failed merge %s to %s: %+v", srcObjPath, dstDirPath, err)
@@ -92,7 +126,53 @@ func Merge(ctx context.Context, srcObjPath, dstDirPath string, lazyCache ...bool
 	return res, err
 }
 
+func Sync(ctx context.Context, srcDirPath, dstDirPath string, args SyncArgs) (task.TaskExtensionInfo, error) {
+	if err := checkHidden(srcDirPath, dstDirPath); err != nil {
+		return nil, err
+	}
+	res, err := syncDir(ctx, srcDirPath, dstDirPath, args)
+	if err != nil {
+		log.Errorf("failed sync %s to %s: %+v", srcDirPath, dstDirPath, err)
+	}
+	return res, err
+}
+
+func FindDuplicates(ctx context.Context, args DedupeArgs) (task.TaskExtensionInfo, error) {
+	if err := checkHidden(args.Paths...); err != nil {
+		return nil, err
+	}
+	res, err := findDuplicates(ctx, args)
+	if err != nil {
+		log.Errorf("failed find duplicates in %v: %+v", args.Paths, err)
+	}
+	return res, err
+}
+
+// ApplyDuplicates adds a task deleting or linking the duplicates found by a succeeded report,
+// kept apart from the search so that the report can be reviewed first
+func ApplyDuplicates(ctx context.Context, report *DedupeTask, action string) (task.TaskExtensionInfo, error) {
+	res, err := applyDuplicates(ctx, report, action)
+	if err != nil {
+		log.Errorf("failed %s duplicates in %v: %+v", action, report.Paths, err)
+	}
+	return res, err
+}
+
+func Checksum(ctx context.Context, args ChecksumArgs) (task.TaskExtensionInfo, error) {
+	if err := checkHidden(args.Path); err != nil {
+		return nil, err
+	}
+	res, err := checksum(ctx, args)
+	if err != nil {
+		log.Errorf("failed checksum %s: %+v", args.Path, err)
+	}
+	return res, err
+}
+
 func Rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) error {
+	if err := checkHidden(srcPath, stdpath.Join(stdpath.Dir(srcPath), dstName)); err != nil {
+		return err
+	}
 	err := rename(ctx, srcPath, dstName, lazyCache...)
 	if err != nil {
 		log.Errorf("failed rename %s to %s: %+v", srcPath, dstName, err)
@@ -101,6 +181,9 @@ func Rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) err
 }
 
 func Remove(ctx context.Context, path string) error {
+	if err := checkHidden(path); err != nil {
+		return err
+	}
 	err := remove(ctx, path)
 	if err != nil {
 		log.Errorf("failed remove %s: %+v", path, err)
@@ -109,6 +192,10 @@ func Remove(ctx context.Context, path string) error {
 }
 
 func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, lazyCache ...bool) error {
+	if err := checkHidden(stdpath.Join(dstDirPath, file.GetName())); err != nil {
+		_ = file.Close()
+		return err
+	}
 	err := putDirectly(ctx, dstDirPath, file, lazyCache...)
 	if err != nil {
 		log.Errorf("failed put %s: %+v", dstDirPath, err)
@@ -116,7 +203,24 @@ func PutDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer
 	return err
 }
 
+// PutConflict applies the conflict policy to the file about to be put into dstDirPath, it returns
+// the name to put the file under, or "" when the file should not be put
+func PutConflict(ctx context.Context, dstDirPath string, file model.Obj, policy string) (string, error) {
+	if err := checkHidden(stdpath.Join(dstDirPath, file.GetName())); err != nil {
+		return "", err
+	}
+	name, err := putConflict(ctx, dstDirPath, file, policy)
+	if err != nil && !errors.Is(err, errs.ObjectAlreadyExists) {
+		log.Errorf("failed resolve the conflict of %s in %s: %+v", file.GetName(), dstDirPath, err)
+	}
+	return name, err
+}
+
 func PutAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer) (task.TaskExtensionInfo, error) {
+	if err := checkHidden(stdpath.Join(dstDirPath, file.GetName())); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
 	t, err := putAsTask(ctx, dstDirPath, file)
 	if err != nil {
 		log.Errorf("failed put %s: %+v", dstDirPath, err)
@@ -125,6 +229,9 @@ func PutAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 }
 
 func ArchiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (*model.ArchiveMetaProvider, error) {
+	if err := checkHidden(path); err != nil {
+		return nil, err
+	}
 	meta, err := archiveMeta(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed get archive meta %s: %+v", path, err)
@@ -133,6 +240,9 @@ func ArchiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (
 }
 
 func ArchiveList(ctx context.Context, path string, args model.ArchiveListArgs) ([]model.Obj, error) {
+	if err := checkHidden(path); err != nil {
+		return nil, err
+	}
 	objs, err := archiveList(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed list archive [%s]%s: %+v", path, args.InnerPath, err)
@@ -141,6 +251,9 @@ func ArchiveList(ctx context.Context, path string, args model.ArchiveListArgs) (
 }
 
 func ArchiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args model.ArchiveDecompressArgs, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+	if err := checkHidden(srcObjPath, dstDirPath); err != nil {
+		return nil, err
+	}
 	t, err := archiveDecompress(ctx, srcObjPath, dstDirPath, args, lazyCache...)
 	if err != nil {
 		log.Errorf("failed decompress [%s]%s: %+v", srcObjPath, args.InnerPath, err)
@@ -149,6 +262,9 @@ func ArchiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 }
 
 func ArchiveDriverExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (*model.Link, model.Obj, error) {
+	if err := checkHidden(path); err != nil {
+		return nil, nil, err
+	}
 	l, obj, err := archiveDriverExtract(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed extract [%s]%s: %+v", path, args.InnerPath, err)
@@ -157,6 +273,9 @@ func ArchiveDriverExtract(ctx context.Context, path string, args model.ArchiveIn
 }
 
 func ArchiveInternalExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (io.ReadCloser, int64, error) {
+	if err := checkHidden(path); err != nil {
+		return nil, 0, err
+	}
 	l, obj, err := archiveInternalExtract(ctx, path, args)
 	if err != nil {
 		log.Errorf("failed extract [%s]%s: %+v", path, args.InnerPath, err)
@@ -184,6 +303,9 @@ func GetByActualPath(ctx context.Context, storage driver.Driver, actualPath stri
 }
 
 func Other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
+	if err := checkHidden(args.Path); err != nil {
+		return nil, err
+	}
 	res, err := other(ctx, args)
 	if err != nil {
 		log.Errorf("failed get other %s: %+v", args.Path, err)
@@ -192,6 +314,9 @@ func Other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
 }
 
 func PutURL(ctx context.Context, path, dstName, urlStr string) error {
+	if err := checkHidden(stdpath.Join(path, dstName)); err != nil {
+		return err
+	}
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
@@ -208,6 +333,9 @@ func PutURL(ctx context.Context, path, dstName, urlStr string) error {
 }
 
 func GetDirectUploadInfo(ctx context.Context, tool, path, dstName string, fileSize int64) (any, error) {
+	if err := checkHidden(stdpath.Join(path, dstName)); err != nil {
+		return nil, err
+	}
 	info, err := getDirectUploadInfo(ctx, tool, path, dstName, fileSize)
 	if err != nil {
 		log.Errorf("failed get %s direct upload info for %s(%d bytes): %+v", path, dstName, fileSize, err)
@@ -0,0 +1,59 @@
+package fs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestHiddenFolders(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"file.txt": "file",
+		op.RecycleBinDir + "/1700000000000_abcdef/secret.txt":  "trashed",
+		op.VersionsDir + "/20240101000000.000_abcdef/file.txt": "version",
+		op.PartsDir + "/0123456789abcdef":                      "part",
+	})
+	createLocal(t, "/hidden_local", root)
+	ctx := context.Background()
+
+	objs, err := fs.List(ctx, "/hidden_local", &fs.ListArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 1 || objs[0].GetName() != "file.txt" {
+		t.Errorf("expect the hidden folders not to be listed, got %d objects", len(objs))
+	}
+
+	for _, dir := range []string{op.RecycleBinDir, op.VersionsDir, op.PartsDir} {
+		path := "/hidden_local" + dir
+		if _, err = fs.List(ctx, path, &fs.ListArgs{NoLog: true}); !errors.Is(err, errs.ObjectNotFound) {
+			t.Errorf("expect %s not to be listed directly, got %v", path, err)
+		}
+		if _, err = fs.Get(ctx, path, &fs.GetArgs{NoLog: true}); !errors.Is(err, errs.ObjectNotFound) {
+			t.Errorf("expect %s not to be got directly, got %v", path, err)
+		}
+	}
+	for _, path := range []string{
+		"/hidden_local" + op.RecycleBinDir + "/1700000000000_abcdef/secret.txt",
+		"/hidden_local" + op.VersionsDir + "/20240101000000.000_abcdef/file.txt",
+	} {
+		if _, err = fs.Get(ctx, path, &fs.GetArgs{NoLog: true}); !errors.Is(err, errs.ObjectNotFound) {
+			t.Errorf("expect %s not to be got directly, got %v", path, err)
+		}
+		if _, _, err = fs.Link(ctx, path, model.LinkArgs{}); !errors.Is(err, errs.ObjectNotFound) {
+			t.Errorf("expect %s not to be linked directly, got %v", path, err)
+		}
+	}
+	if err = fs.MakeDir(ctx, "/hidden_local"+op.RecycleBinDir+"/new"); !errors.Is(err, errs.ObjectNotFound) {
+		t.Errorf("expect no folder to be made in the recycle bin, got %v", err)
+	}
+	if err = fs.Rename(ctx, "/hidden_local/file.txt", op.RecycleBinDir[1:]); !errors.Is(err, errs.ObjectNotFound) {
+		t.Errorf("expect no file to be renamed to a hidden folder, got %v", err)
+	}
+}
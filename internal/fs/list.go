@@ -2,6 +2,7 @@ package fs
 
 import (
 	"context"
+	stdpath "path"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -43,6 +44,12 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 		om.InitHideReg(meta.Hide)
 	}
 	objs := om.Merge(_objs, virtualFiles...)
+	if storage != nil && utils.PathEqual(actualPath, "/") {
+		// the recycle bin and the versions are only reachable through their own api
+		objs = utils.SliceFilter(objs, func(obj model.Obj) bool {
+			return !isHiddenFolder(stdpath.Join(actualPath, obj.GetName()))
+		})
+	}
 	return objs, nil
 }
 
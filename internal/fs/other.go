@@ -3,9 +3,11 @@ package fs
 import (
 	"context"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/pkg/errors"
 )
@@ -31,6 +33,15 @@ func remove(ctx context.Context, path string) error {
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
 	}
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	return removeObj(ctx, storage, actualPath, user)
+}
+
+// removeObj moves the object into the recycle bin when it is enabled, or removes it
+func removeObj(ctx context.Context, storage driver.Driver, actualPath string, user *model.User) error {
+	if setting.GetBool(conf.RecycleBinEnabled) && op.CanRecycle(storage, actualPath) {
+		return op.Recycle(ctx, storage, actualPath, user)
+	}
 	return op.Remove(ctx, storage, actualPath)
 }
 
@@ -0,0 +1,50 @@
+package fs
+
+import (
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/tache"
+)
+
+var (
+	transfersPaused   bool
+	transfersPausedMu sync.Mutex
+)
+
+// PauseTransfers pauses the upload, copy, move and decompress managers and the tasks they run,
+// the tasks added meanwhile wait in the queues. When pause is false, the managers are started
+// again and the tasks paused by the switch are resumed.
+func PauseTransfers(pause bool) {
+	transfersPausedMu.Lock()
+	defer transfersPausedMu.Unlock()
+	if pause == transfersPaused {
+		return
+	}
+	transfersPaused = pause
+	pauseManager(UploadTaskManager, pause)
+	pauseManager(CopyTaskManager, pause)
+	pauseManager(MoveTaskManager, pause)
+	pauseManager(ArchiveDownloadTaskManager, pause)
+	pauseManager(ArchiveContentUploadTaskManager.Manager, pause)
+}
+
+func pauseManager[T task.PausableTask](m *tache.Manager[T], pause bool) {
+	if m == nil {
+		return
+	}
+	if !pause {
+		m.Start()
+		for _, t := range m.GetByCondition(func(t T) bool { return t.IsAutoPaused() }) {
+			_ = task.ResumeTask[T](m, t)
+		}
+		return
+	}
+	m.Pause()
+	// the pending ones stay in the queue
+	for _, t := range m.GetByState(tache.StateRunning, tache.StateWaitingRetry, tache.StateBeforeRetry) {
+		if t.CanPause() {
+			t.Pause(true)
+		}
+	}
+}
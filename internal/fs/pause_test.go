@@ -0,0 +1,127 @@
+package fs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/tache"
+)
+
+func waitState(t *testing.T, tsk *fs.FileTransferTask, state tache.State) {
+	for i := 0; tsk.GetState() != state; i++ {
+		if i == 200 {
+			t.Fatalf("expect the task to be %d, got %d", state, tsk.GetState())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"dir/a.txt": "a",
+		"dir/b.txt": "b",
+	})
+	createLocal(t, "/pause_src", src)
+	createLocal(t, "/pause_dst", dst)
+	fs.CopyTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(2))
+
+	fs.PauseTransfers(true)
+	tsk, err := fs.Copy(context.Background(), "/pause_src/dir", "/pause_dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyTask := tsk.(*fs.FileTransferTask)
+	time.Sleep(50 * time.Millisecond)
+	if copyTask.GetState() != tache.StatePending {
+		t.Errorf("expect the task to wait while the transfers are paused, got %d", copyTask.GetState())
+	}
+	// paused by hand, it's not resumed with the transfers
+	copyTask.Pause(false)
+	fs.PauseTransfers(false)
+	waitState(t, copyTask, tache.StateFailed)
+	if !copyTask.IsPaused() || !errors.Is(copyTask.GetErr(), task.ErrPaused) {
+		t.Fatalf("expect the task to be paused, got %v", copyTask.GetErr())
+	}
+	if _, err = os.Stat(filepath.Join(dst, "dir")); !os.IsNotExist(err) {
+		t.Errorf("expect nothing to be copied: %v", err)
+	}
+
+	if err = task.ResumeTask(fs.CopyTaskManager, copyTask); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, copyTask, tache.StateSucceeded)
+	for i := 0; len(fs.CopyTaskManager.GetByState(tache.StateSucceeded)) != 3; i++ {
+		if i == 200 {
+			t.Fatal("the copy tasks are not over")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if readFile(t, filepath.Join(dst, "dir", "a.txt")) != "a" || readFile(t, filepath.Join(dst, "dir", "b.txt")) != "b" {
+		t.Errorf("expect dir to be copied")
+	}
+	if len(copyTask.Finished) != 2 {
+		t.Errorf("expect the children to be checkpointed, got %v", copyTask.Finished)
+	}
+	if err = task.ResumeTask(fs.CopyTaskManager, copyTask); err == nil {
+		t.Errorf("expect a task which is not paused not to be resumed")
+	}
+}
+
+func TestResumablePut(t *testing.T) {
+	dst := t.TempDir()
+	createLocal(t, "/resume_dst", dst)
+	storage, _, err := op.GetStorageAndActualPath("/resume_dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(resume bool) {
+		s := &stream.FileStream{
+			Obj:    &model.Object{Name: "c.txt", Size: 10, Modified: time.Now()},
+			Reader: bytes.NewReader([]byte("0123456789")),
+		}
+		if err := op.Put(op.WithResumablePut(context.Background(), resume), storage, "/", s, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := sha1.Sum([]byte(filepath.Join(dst, "c.txt")))
+	partRel := filepath.Join(".openlist_parts", hex.EncodeToString(h[:]))
+	part := filepath.Join(dst, partRel)
+
+	// the interrupted upload left 4 bytes, only the rest is read
+	writeFiles(t, dst, map[string]string{partRel: "XXXX"})
+	put(true)
+	if data := readFile(t, filepath.Join(dst, "c.txt")); data != "XXXX456789" {
+		t.Errorf("expect the upload to be carried on, got %s", data)
+	}
+	if _, err = os.Stat(part); !os.IsNotExist(err) {
+		t.Errorf("expect the staged part to be renamed: %v", err)
+	}
+
+	writeFiles(t, dst, map[string]string{partRel: "XXXX"})
+	put(false)
+	if data := readFile(t, filepath.Join(dst, "c.txt")); data != "0123456789" {
+		t.Errorf("expect the upload to start over, got %s", data)
+	}
+
+	// the upload given up is removed
+	writeFiles(t, dst, map[string]string{partRel: "XXXX"})
+	if err = op.DiscardResumablePut(context.Background(), storage, "/", "c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(part); !os.IsNotExist(err) {
+		t.Errorf("expect the staged part to be removed: %v", err)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
 	"github.com/OpenListTeam/tache"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type UploadTask struct {
@@ -34,11 +35,19 @@ func (t *UploadTask) GetStatus() string {
 	return "uploading"
 }
 
+// CanPause lets only the pending uploads be paused, the stream of a running one can't be read again
+func (t *UploadTask) CanPause() bool {
+	return t.TaskExtension.CanPause() && t.GetState() == tache.StatePending
+}
+
 func (t *UploadTask) Run() error {
+	if err := t.BeforeRun(); err != nil {
+		return err
+	}
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
-	return op.Put(t.Ctx(), t.storage, t.dstDirActualPath, t.file, t.SetProgress, true)
+	return putKeepingVersion(t.Ctx(), t.storage, t.dstDirActualPath, t.file, t.SetProgress, t.Creator, true)
 }
 
 func (t *UploadTask) OnSucceeded() {
@@ -46,13 +55,16 @@ func (t *UploadTask) OnSucceeded() {
 }
 
 func (t *UploadTask) OnFailed() {
+	if t.IsPaused() {
+		return
+	}
 	task_group.TransferCoordinator.Done(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), false)
 }
 
 func (t *UploadTask) SetRetry(retry int, maxRetry int) {
 	t.TaskExtension.SetRetry(retry, maxRetry)
 	if retry == 0 &&
-		(t.GetErr() == nil && t.GetState() != tache.StatePending) { // 手动重试
+		(!t.Resuming() && t.GetErr() == nil && t.GetState() != tache.StatePending) { // 手动重试
 		task_group.TransferCoordinator.AddTask(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), nil)
 	}
 }
@@ -103,7 +115,26 @@ func putDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer
 		_ = file.Close()
 		return errors.WithStack(errs.UploadNotSupported)
 	}
-	return op.Put(ctx, storage, dstDirActualPath, file, nil, lazyCache...)
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	return putKeepingVersion(ctx, storage, dstDirActualPath, file, nil, user, lazyCache...)
+}
+
+// putKeepingVersion puts the file after moving the one it overwrites into the versions
+// folder when the meta of the dir enables versioning, the old file is put back if the put fails
+func putKeepingVersion(ctx context.Context, storage driver.Driver, dstDirActualPath string, file model.FileStreamer,
+	up driver.UpdateProgress, user *model.User, lazyCache ...bool) error {
+	version, err := op.KeepVersion(ctx, storage, stdpath.Join(dstDirActualPath, file.GetName()), user)
+	if err != nil {
+		_ = file.Close()
+		return errors.WithMessage(err, "failed to keep version")
+	}
+	err = op.Put(ctx, storage, dstDirActualPath, file, up, lazyCache...)
+	if err != nil && version != nil {
+		if e := op.RestoreFileVersion(context.WithoutCancel(ctx), version, user); e != nil {
+			log.Errorf("failed to put back %s after failed put: %+v", version.Path, e)
+		}
+	}
+	return err
 }
 
 func getDirectUploadInfo(ctx context.Context, tool, dstDirPath, dstName string, fileSize int64) (any, error) {
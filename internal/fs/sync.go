@@ -0,0 +1,343 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+)
+
+// SyncArgs are the options of a one-way sync
+type SyncArgs struct {
+	// Delete removes the objects of the destination missing in the source
+	Delete bool `json:"delete"`
+	// CompareHash compares the hashes when both storages provide the same kind
+	CompareHash bool `json:"compare_hash"`
+	// DryRun only plans the operations
+	DryRun bool `json:"dry_run"`
+}
+
+const (
+	SyncMkdir    = "mkdir"
+	SyncCopy     = "copy"
+	SyncUpdate   = "update"
+	SyncDelete   = "delete"
+	SyncConflict = "conflict"
+)
+
+// SyncOperation is a planned operation, Path is relative to the synced folders
+type SyncOperation struct {
+	Action string `json:"action"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	IsDir  bool   `json:"is_dir"`
+}
+
+// FileError is an error met on a file by a task walking many files
+type FileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+type SyncResult struct {
+	Operations []SyncOperation `json:"operations"`
+	Errors     []FileError     `json:"errors"`
+}
+
+// the modified times of different storages don't have the same precision
+const syncModTimeWindow = 2 * time.Second
+
+type SyncTask struct {
+	task.TaskExtension
+	SyncArgs
+	Status  string     `json:"-"`
+	SrcPath string     `json:"src_path"`
+	DstPath string     `json:"dst_path"`
+	Result  SyncResult `json:"result"`
+	mu      sync.Mutex
+}
+
+func (t *SyncTask) GetName() string {
+	if t.DryRun {
+		return fmt.Sprintf("plan sync %s to %s", t.SrcPath, t.DstPath)
+	}
+	return fmt.Sprintf("sync %s to %s", t.SrcPath, t.DstPath)
+}
+
+func (t *SyncTask) GetStatus() string {
+	return t.Status
+}
+
+// GetResult returns a copy of the planned operations and of the errors met
+func (t *SyncTask) GetResult() SyncResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return SyncResult{
+		Operations: append([]SyncOperation(nil), t.Result.Operations...),
+		Errors:     append([]FileError(nil), t.Result.Errors...),
+	}
+}
+
+func (t *SyncTask) addError(path string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Result.Errors = append(t.Result.Errors, FileError{Path: path, Error: err.Error()})
+}
+
+func (t *SyncTask) Run() error {
+	if err := t.ReinitCtx(); err != nil {
+		return err
+	}
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	srcStorage, srcActualPath, err := op.GetStorageAndActualPath(t.SrcPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get src storage")
+	}
+	dstStorage, dstActualPath, err := op.GetStorageAndActualPath(t.DstPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get dst storage")
+	}
+
+	t.Status = "comparing"
+	t.mu.Lock()
+	t.Result = SyncResult{}
+	t.mu.Unlock()
+	p := &syncPlanner{ctx: t.Ctx(), src: srcStorage, dst: dstStorage, args: t.SyncArgs}
+	dstExists := true
+	if _, err = op.Get(t.Ctx(), dstStorage, dstActualPath); errs.IsObjectNotFound(err) {
+		dstExists = false
+		p.add(SyncMkdir, "/", 0, true)
+	} else if err != nil {
+		return errors.WithMessagef(err, "failed get dst [%s]", t.DstPath)
+	}
+	if err = p.plan(srcActualPath, dstActualPath, "/", dstExists); err != nil {
+		return err
+	}
+	var total int64
+	for _, o := range p.ops {
+		if o.Action == SyncCopy || o.Action == SyncUpdate {
+			total += o.Size
+		}
+	}
+	t.mu.Lock()
+	t.Result.Operations = p.ops
+	t.mu.Unlock()
+	t.SetTotalBytes(total)
+	if t.DryRun {
+		t.Status = fmt.Sprintf("planned %d operations", len(p.ops))
+		t.SetProgress(100)
+		return nil
+	}
+
+	var doneBytes int64
+	failed := 0
+	for i, o := range p.ops {
+		if utils.IsCanceled(t.Ctx()) {
+			return t.Ctx().Err()
+		}
+		t.Status = fmt.Sprintf("%s %s (%d/%d)", o.Action, o.Path, i+1, len(p.ops))
+		base := doneBytes
+		up := func(percentage float64) {
+			if total > 0 {
+				t.SetProgress(float64(base) + percentage/100*float64(o.Size)/float64(total)*100)
+			}
+		}
+		if err = t.apply(srcStorage, srcActualPath, dstStorage, dstActualPath, o, up); err != nil {
+			failed++
+			t.addError(o.Path, err)
+		}
+		if o.Action == SyncCopy || o.Action == SyncUpdate {
+			doneBytes += o.Size
+		}
+		if total > 0 {
+			t.SetProgress(float64(doneBytes) / float64(total) * 100)
+		}
+	}
+	t.SetProgress(100)
+	if failed > 0 {
+		t.Status = fmt.Sprintf("done with %d errors", failed)
+		return errors.Errorf("%d of %d operations failed", failed, len(p.ops))
+	}
+	t.Status = fmt.Sprintf("done %d operations", len(p.ops))
+	return nil
+}
+
+func (t *SyncTask) apply(src driver.Driver, srcRoot string, dst driver.Driver, dstRoot string, o SyncOperation, up driver.UpdateProgress) error {
+	dstPath := stdpath.Join(dstRoot, o.Path)
+	switch o.Action {
+	case SyncMkdir:
+		return op.MakeDir(t.Ctx(), dst, dstPath)
+	case SyncDelete:
+		return removeObj(t.Ctx(), dst, dstPath, t.Creator)
+	case SyncConflict:
+		return errors.New("the destination is not of the same type, enable delete to replace it")
+	}
+	srcPath := stdpath.Join(srcRoot, o.Path)
+	srcObj, err := op.Get(t.Ctx(), src, srcPath)
+	if err != nil {
+		return errors.WithMessagef(err, "failed get src [%s] file", srcPath)
+	}
+	link, _, err := op.Link(t.Ctx(), src, srcPath, model.LinkArgs{})
+	if err != nil {
+		return errors.WithMessagef(err, "failed get [%s] link", srcPath)
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{
+		Obj: srcObj,
+		Ctx: t.Ctx(),
+	}, link)
+	if err != nil {
+		_ = link.Close()
+		return errors.WithMessagef(err, "failed get [%s] stream", srcPath)
+	}
+	return putKeepingVersion(t.Ctx(), dst, stdpath.Dir(dstPath), ss, up, t.Creator)
+}
+
+type syncPlanner struct {
+	ctx  context.Context
+	src  driver.Driver
+	dst  driver.Driver
+	args SyncArgs
+	ops  []SyncOperation
+}
+
+func (p *syncPlanner) add(action, path string, size int64, isDir bool) {
+	p.ops = append(p.ops, SyncOperation{Action: action, Path: path, Size: size, IsDir: isDir})
+}
+
+// plan compares srcDir with dstDir, rel is their path relative to the synced folders
+func (p *syncPlanner) plan(srcDir, dstDir, rel string, dstExists bool) error {
+	if utils.IsCanceled(p.ctx) {
+		return p.ctx.Err()
+	}
+	srcObjs, err := op.List(p.ctx, p.src, srcDir, model.ListArgs{Refresh: true})
+	if err != nil {
+		return errors.WithMessagef(err, "failed list src [%s] objs", srcDir)
+	}
+	var dstList []model.Obj
+	if dstExists {
+		dstList, err = op.List(p.ctx, p.dst, dstDir, model.ListArgs{Refresh: true})
+		if err != nil {
+			return errors.WithMessagef(err, "failed list dst [%s] objs", dstDir)
+		}
+		dstList = utils.SliceFilter(dstList, func(obj model.Obj) bool {
+			return !isHiddenFolder(stdpath.Join(dstDir, obj.GetName()))
+		})
+	}
+	dstObjs := make(map[string]model.Obj, len(dstList))
+	for _, obj := range dstList {
+		dstObjs[obj.GetName()] = obj
+	}
+	srcNames := make(map[string]struct{}, len(srcObjs))
+	for _, s := range srcObjs {
+		name := s.GetName()
+		if isHiddenFolder(stdpath.Join(srcDir, name)) {
+			continue
+		}
+		srcNames[name] = struct{}{}
+		path := stdpath.Join(rel, name)
+		d, ok := dstObjs[name]
+		if ok && d.IsDir() != s.IsDir() {
+			if !p.args.Delete {
+				p.add(SyncConflict, path, s.GetSize(), s.IsDir())
+				continue
+			}
+			p.add(SyncDelete, path, d.GetSize(), d.IsDir())
+			ok = false
+		}
+		if s.IsDir() {
+			if !ok {
+				p.add(SyncMkdir, path, 0, true)
+			}
+			if err = p.plan(stdpath.Join(srcDir, name), stdpath.Join(dstDir, name), path, ok); err != nil {
+				return err
+			}
+			continue
+		}
+		if !ok {
+			p.add(SyncCopy, path, s.GetSize(), false)
+		} else if syncNeedsUpdate(s, d, p.args.CompareHash) {
+			p.add(SyncUpdate, path, s.GetSize(), false)
+		}
+	}
+	if p.args.Delete {
+		for _, d := range dstList {
+			if _, ok := srcNames[d.GetName()]; !ok {
+				p.add(SyncDelete, stdpath.Join(rel, d.GetName()), d.GetSize(), d.IsDir())
+			}
+		}
+	}
+	return nil
+}
+
+// isHiddenFolder reports whether the path is the recycle bin, the versions folder
+// or the folder of the staged uploads of a storage
+func isHiddenFolder(actualPath string) bool {
+	return utils.PathEqual(actualPath, op.RecycleBinDir) || utils.PathEqual(actualPath, op.VersionsDir) ||
+		utils.PathEqual(actualPath, op.PartsDir)
+}
+
+// syncNeedsUpdate reports whether dst differs from src. When hashes are compared and
+// a common kind is found, they decide alone, otherwise a newer src is a change.
+func syncNeedsUpdate(src, dst model.Obj, compareHash bool) bool {
+	if src.GetSize() != dst.GetSize() {
+		return true
+	}
+	if compareHash {
+		dstHash := dst.GetHash()
+		for ht, h := range src.GetHash().All() {
+			if dh := dstHash.GetHash(ht); h != "" && dh != "" {
+				return !strings.EqualFold(h, dh)
+			}
+		}
+	}
+	return src.ModTime().After(dst.ModTime().Add(syncModTimeWindow))
+}
+
+// syncDir adds a task syncing the content of srcDirPath into dstDirPath
+func syncDir(ctx context.Context, srcDirPath, dstDirPath string, args SyncArgs) (task.TaskExtensionInfo, error) {
+	srcDirPath, dstDirPath = utils.FixAndCleanPath(srcDirPath), utils.FixAndCleanPath(dstDirPath)
+	if utils.IsSubPath(srcDirPath, dstDirPath) || utils.IsSubPath(dstDirPath, srcDirPath) {
+		return nil, errors.New("the source and the destination can't contain each other")
+	}
+	srcStorage, srcActualPath, err := op.GetStorageAndActualPath(srcDirPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get src storage")
+	}
+	if _, _, err = op.GetStorageAndActualPath(dstDirPath); err != nil {
+		return nil, errors.WithMessage(err, "failed get dst storage")
+	}
+	srcObj, err := op.Get(ctx, srcStorage, srcActualPath)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed get src [%s]", srcDirPath)
+	}
+	if !srcObj.IsDir() {
+		return nil, errors.WithStack(errs.NotFolder)
+	}
+	t := &SyncTask{
+		SyncArgs: args,
+		SrcPath:  srcDirPath,
+		DstPath:  dstDirPath,
+	}
+	t.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
+	t.ApiUrl = common.GetApiUrl(ctx)
+	SyncTaskManager.Add(t)
+	return t, nil
+}
+
+var SyncTaskManager *tache.Manager[*SyncTask]
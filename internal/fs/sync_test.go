@@ -0,0 +1,117 @@
+package fs_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func createLocal(t *testing.T, mountPath, root string) {
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: mountPath,
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+}
+
+func runSync(t *testing.T, args fs.SyncArgs) *fs.SyncTask {
+	task := &fs.SyncTask{SyncArgs: args, SrcPath: "/sync_src", DstPath: "/sync_dst/backup"}
+	task.SetCtx(context.Background())
+	if err := task.Run(); err != nil {
+		t.Fatalf("failed to sync: %+v", err)
+	}
+	return task
+}
+
+func TestSyncTask(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"same.txt":      "same",
+		"changed.txt":   "new content",
+		"new/child.txt": "child",
+	})
+	writeFiles(t, dst, map[string]string{
+		"backup/same.txt":    "same",
+		"backup/changed.txt": "old",
+		"backup/extra.txt":   "extra",
+	})
+	// the copy in dst is newer, so only the size tells same.txt apart
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dst, "backup", "same.txt"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	createLocal(t, "/sync_src", src)
+	createLocal(t, "/sync_dst", dst)
+
+	planned := runSync(t, fs.SyncArgs{Delete: true, DryRun: true}).GetResult().Operations
+	expected := map[string]string{
+		"/changed.txt":   fs.SyncUpdate,
+		"/new":           fs.SyncMkdir,
+		"/new/child.txt": fs.SyncCopy,
+		"/extra.txt":     fs.SyncDelete,
+	}
+	if len(planned) != len(expected) {
+		t.Fatalf("expect %d operations, got %+v", len(expected), planned)
+	}
+	for _, o := range planned {
+		if expected[o.Path] != o.Action {
+			t.Errorf("unexpected operation %+v", o)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(dst, "backup", "changed.txt")); string(content) != "old" {
+		t.Fatalf("dry run must not change the destination")
+	}
+
+	result := runSync(t, fs.SyncArgs{Delete: true}).GetResult()
+	if len(result.Errors) != 0 {
+		t.Fatalf("unexpected errors: %+v", result.Errors)
+	}
+	for name, want := range map[string]string{"changed.txt": "new content", "new/child.txt": "child", "same.txt": "same"} {
+		if content, err := os.ReadFile(filepath.Join(dst, "backup", filepath.FromSlash(name))); err != nil || string(content) != want {
+			t.Errorf("expect %s to be %q, got %q %v", name, want, content, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, "backup", "extra.txt")); !os.IsNotExist(err) {
+		t.Errorf("expect extra.txt to be deleted, got %v", err)
+	}
+
+	if ops := runSync(t, fs.SyncArgs{Delete: true, DryRun: true}).GetResult().Operations; len(ops) != 0 {
+		t.Errorf("expect nothing to do after sync, got %+v", ops)
+	}
+}
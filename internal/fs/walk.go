@@ -44,3 +44,25 @@ func WalkFS(ctx context.Context, depth int, name string, info model.Obj, walkFn
 	}
 	return nil
 }
+
+// walkFiles calls fn for each file under dir, unlike WalkFS the folders are listed
+// with args and the listing errors are returned
+func walkFiles(ctx context.Context, dir string, args *ListArgs, fn func(reqPath string, info model.Obj) error) error {
+	meta, _ := op.GetNearestMeta(dir)
+	objs, err := List(context.WithValue(ctx, conf.MetaKey, meta), dir, args)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		reqPath := path.Join(dir, obj.GetName())
+		if obj.IsDir() {
+			err = walkFiles(ctx, reqPath, args, fn)
+		} else {
+			err = fn(reqPath, obj)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
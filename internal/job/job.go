@@ -0,0 +1,62 @@
+package job
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func GetJobs() ([]model.ScheduledJob, error) {
+	jobs, err := db.GetScheduledJobs()
+	for i := range jobs {
+		Fill(&jobs[i])
+	}
+	return jobs, err
+}
+
+func GetJobById(id uint) (*model.ScheduledJob, error) {
+	j, err := db.GetScheduledJobById(id)
+	if err != nil {
+		return nil, err
+	}
+	Fill(j)
+	return j, nil
+}
+
+func CreateJob(j *model.ScheduledJob) error {
+	if err := Validate(j); err != nil {
+		return err
+	}
+	if err := db.CreateScheduledJob(j); err != nil {
+		return err
+	}
+	Reschedule(j)
+	Fill(j)
+	return nil
+}
+
+// UpdateJob saves the settings of the job, its last run is kept
+func UpdateJob(j *model.ScheduledJob) (*model.ScheduledJob, error) {
+	if err := Validate(j); err != nil {
+		return nil, err
+	}
+	old, err := db.GetScheduledJobById(j.ID)
+	if err != nil {
+		return nil, err
+	}
+	old.Name, old.Kind, old.Cron, old.Args, old.Disabled = j.Name, j.Kind, j.Cron, j.Args, j.Disabled
+	if err = db.UpdateScheduledJob(old); err != nil {
+		return nil, err
+	}
+	Reschedule(old)
+	Fill(old)
+	return old, nil
+}
+
+func DeleteJobById(id uint) error {
+	Unschedule(id)
+	return db.DeleteScheduledJobById(id)
+}
+
+func GetJobRuns(jobId uint, pageIndex, pageSize int) ([]model.ScheduledJobRun, int64, error) {
+	return db.GetScheduledJobRuns(jobId, pageIndex, pageSize)
+}
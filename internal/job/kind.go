@@ -0,0 +1,313 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/search"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+)
+
+// TransferArgs are the arguments of the copy and merge jobs, SrcPath is copied into DstDir
+type TransferArgs struct {
+	SrcPath string `json:"src_path"`
+	DstDir  string `json:"dst_dir"`
+}
+
+type SyncArgs struct {
+	SrcDir string `json:"src_dir"`
+	DstDir string `json:"dst_dir"`
+	fs.SyncArgs
+}
+
+type ScanArgs struct {
+	Path  string  `json:"path"`
+	Limit float64 `json:"limit"` // listed folders per second, 0 means no limit
+	Depth int     `json:"depth"` // levels of folders to list, 0 means all
+}
+
+type IndexUpdateArgs struct {
+	Paths    []string `json:"paths"`
+	MaxDepth int      `json:"max_depth"`
+}
+
+type LogCleanupArgs struct {
+	Types      []string `json:"types"` // login, upload or system, all of them if empty
+	MaxAgeDays int      `json:"max_age_days"`
+}
+
+type OfflineDownloadArgs struct {
+	URLs         []string          `json:"urls"`
+	DstDir       string            `json:"dst_dir"`
+	Tool         string            `json:"tool"`
+	DeletePolicy tool.DeletePolicy `json:"delete_policy"`
+}
+
+var logTypes = []string{"login", "upload", "system"}
+
+func parseArgs[T any](args string) (*T, error) {
+	var v T
+	if strings.TrimSpace(args) == "" {
+		return &v, nil
+	}
+	if err := json.Unmarshal([]byte(args), &v); err != nil {
+		return nil, errors.Wrap(err, "invalid args")
+	}
+	return &v, nil
+}
+
+func required(name, value string) error {
+	if value == "" {
+		return errors.Errorf("%s is required", name)
+	}
+	return nil
+}
+
+// Validate checks the cron expression and the arguments of the job
+func Validate(j *model.ScheduledJob) error {
+	if _, err := cron.ParseSchedule(j.Cron); err != nil {
+		return errors.WithMessage(err, "invalid cron expression")
+	}
+	var err error
+	switch j.Kind {
+	case model.JobCopy, model.JobMerge:
+		var args *TransferArgs
+		if args, err = parseArgs[TransferArgs](j.Args); err == nil {
+			err = stderrors.Join(required("src_path", args.SrcPath), required("dst_dir", args.DstDir))
+		}
+	case model.JobSync:
+		var args *SyncArgs
+		if args, err = parseArgs[SyncArgs](j.Args); err == nil {
+			err = stderrors.Join(required("src_dir", args.SrcDir), required("dst_dir", args.DstDir))
+		}
+	case model.JobScan:
+		var args *ScanArgs
+		if args, err = parseArgs[ScanArgs](j.Args); err == nil {
+			err = required("path", args.Path)
+			if args.Depth < 0 {
+				err = stderrors.Join(err, errors.New("depth must not be negative"))
+			}
+		}
+	case model.JobIndexUpdate:
+		var args *IndexUpdateArgs
+		if args, err = parseArgs[IndexUpdateArgs](j.Args); err == nil && len(args.Paths) == 0 {
+			err = errors.New("paths is required")
+		}
+	case model.JobLogCleanup:
+		var args *LogCleanupArgs
+		if args, err = parseArgs[LogCleanupArgs](j.Args); err == nil {
+			if args.MaxAgeDays <= 0 {
+				err = errors.New("max_age_days must be positive")
+			}
+			for _, t := range args.Types {
+				if !utils.SliceContains(logTypes, t) {
+					err = errors.Errorf("unknown log type [%s]", t)
+				}
+			}
+		}
+	case model.JobChecksum:
+		var args *fs.ChecksumArgs
+		if args, err = parseArgs[fs.ChecksumArgs](j.Args); err == nil {
+			err = required("path", args.Path)
+		}
+	case model.JobOfflineDownload:
+		var args *OfflineDownloadArgs
+		if args, err = parseArgs[OfflineDownloadArgs](j.Args); err == nil {
+			err = stderrors.Join(required("dst_dir", args.DstDir), required("tool", args.Tool))
+			if len(args.URLs) == 0 {
+				err = stderrors.Join(err, errors.New("urls is required"))
+			}
+		}
+	default:
+		err = errors.Errorf("unknown job kind [%s]", j.Kind)
+	}
+	return err
+}
+
+// outcome is what a job did. When the job added tasks or started something
+// running in the background, pending reports whether it is over.
+type outcome struct {
+	message string
+	taskIDs []string
+	pending func() (bool, error)
+}
+
+func tasksOutcome(action string, tasks []task.TaskExtensionInfo) *outcome {
+	if len(tasks) == 0 {
+		return &outcome{message: action + " done without task"}
+	}
+	o := &outcome{message: fmt.Sprintf("added %d %s task(s)", len(tasks), action)}
+	for _, t := range tasks {
+		o.taskIDs = append(o.taskIDs, t.GetID())
+	}
+	o.pending = func() (bool, error) {
+		failed := 0
+		for _, t := range tasks {
+			switch t.GetState() {
+			case tache.StateSucceeded:
+			case tache.StateFailed, tache.StateCanceled:
+				failed++
+			default:
+				return false, nil
+			}
+		}
+		if failed > 0 {
+			return true, errors.Errorf("%d of %d tasks failed", failed, len(tasks))
+		}
+		return true, nil
+	}
+	return o
+}
+
+// do does the job as the admin
+func do(ctx context.Context, j *model.ScheduledJob) (*outcome, error) {
+	admin, err := op.GetAdmin()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get admin")
+	}
+	ctx = context.WithValue(ctx, conf.UserKey, admin)
+	switch j.Kind {
+	case model.JobCopy, model.JobMerge:
+		args, err := parseArgs[TransferArgs](j.Args)
+		if err != nil {
+			return nil, err
+		}
+		transfer := fs.Copy
+		if j.Kind == model.JobMerge {
+			transfer = fs.Merge
+		}
+		t, err := transfer(ctx, args.SrcPath, args.DstDir)
+		if err != nil {
+			return nil, err
+		}
+		var tasks []task.TaskExtensionInfo
+		if t != nil {
+			tasks = append(tasks, t)
+		}
+		return tasksOutcome(j.Kind, tasks), nil
+	case model.JobSync:
+		args, err := parseArgs[SyncArgs](j.Args)
+		if err != nil {
+			return nil, err
+		}
+		t, err := fs.Sync(ctx, args.SrcDir, args.DstDir, args.SyncArgs)
+		if err != nil {
+			return nil, err
+		}
+		return tasksOutcome(j.Kind, []task.TaskExtensionInfo{t}), nil
+	case model.JobScan:
+		args, err := parseArgs[ScanArgs](j.Args)
+		if err != nil {
+			return nil, err
+		}
+		if err = op.BeginManualScan(args.Path, args.Limit, args.Depth); err != nil {
+			return nil, err
+		}
+		return &outcome{
+			message: "scan started",
+			pending: func() (bool, error) {
+				return !op.ManualScanRunning(), nil
+			},
+		}, nil
+	case model.JobIndexUpdate:
+		args, err := parseArgs[IndexUpdateArgs](j.Args)
+		if err != nil {
+			return nil, err
+		}
+		return updateIndex(ctx, args)
+	case model.JobLogCleanup:
+		args, err := parseArgs[LogCleanupArgs](j.Args)
+		if err != nil {
+			return nil, err
+		}
+		types := args.Types
+		if len(types) == 0 {
+			types = logTypes
+		}
+		before := time.Now().AddDate(0, 0, -args.MaxAgeDays)
+		var deleted int64
+		for _, t := range types {
+			n, err := op.DeleteLogsBefore(t, before)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed delete %s logs", t)
+			}
+			deleted += n
+		}
+		return &outcome{message: fmt.Sprintf("deleted %d logs", deleted)}, nil
+	case model.JobChecksum:
+		args, err := parseArgs[fs.ChecksumArgs](j.Args)
+		if err != nil {
+			return nil, err
+		}
+		t, err := fs.Checksum(ctx, *args)
+		if err != nil {
+			return nil, err
+		}
+		return tasksOutcome(j.Kind, []task.TaskExtensionInfo{t}), nil
+	case model.JobOfflineDownload:
+		args, err := parseArgs[OfflineDownloadArgs](j.Args)
+		if err != nil {
+			return nil, err
+		}
+		policy := args.DeletePolicy
+		if policy == "" {
+			policy = tool.DeleteOnUploadSucceed
+		}
+		var tasks []task.TaskExtensionInfo
+		var errs []error
+		for _, url := range args.URLs {
+			t, err := tool.AddURL(ctx, &tool.AddURLArgs{
+				URL:          url,
+				DstDirPath:   args.DstDir,
+				Tool:         args.Tool,
+				DeletePolicy: policy,
+			})
+			if err != nil {
+				errs = append(errs, errors.WithMessagef(err, "failed add [%s]", url))
+			} else if t != nil {
+				tasks = append(tasks, t)
+			}
+		}
+		o := tasksOutcome(j.Kind, tasks)
+		if len(errs) > 0 {
+			if len(tasks) == 0 {
+				return nil, stderrors.Join(errs...)
+			}
+			o.message = fmt.Sprintf("%s, %d url(s) failed: %v", o.message, len(errs), stderrors.Join(errs...))
+		}
+		return o, nil
+	}
+	return nil, errors.Errorf("unknown job kind [%s]", j.Kind)
+}
+
+func updateIndex(ctx context.Context, args *IndexUpdateArgs) (*outcome, error) {
+	if search.Running() {
+		return nil, errors.New("index is running")
+	}
+	if !search.Config(ctx).AutoUpdate {
+		return nil, errors.New("update is not supported for current index")
+	}
+	for _, path := range args.Paths {
+		if err := search.Del(ctx, path); err != nil {
+			return nil, errors.WithMessagef(err, "failed delete index on %s", path)
+		}
+	}
+	err := search.BuildIndex(ctx, args.Paths, conf.SlicesMap[conf.IgnorePaths], args.MaxDepth, false)
+	if err != nil {
+		return nil, err
+	}
+	return &outcome{message: fmt.Sprintf("updated index of %d path(s)", len(args.Paths))}, nil
+}
@@ -0,0 +1,244 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the runs kept for each job
+const keepRuns = 50
+
+// the schedules are checked at this interval, so a job may start up to this late
+const tickInterval = 20 * time.Second
+
+// activeRun is a run whose job or tasks are not over yet
+type activeRun struct {
+	job     *model.ScheduledJob
+	run     *model.ScheduledJobRun
+	pending func() (bool, error)
+}
+
+var (
+	mu     sync.Mutex
+	next   = make(map[uint]time.Time)
+	active = make(map[uint]*activeRun)
+	ticker *cron.Cron
+)
+
+// Start schedules the saved jobs, the runs interrupted by the last stop are marked as failed
+func Start() {
+	if ticker != nil {
+		return
+	}
+	runs, err := db.GetUnfinishedScheduledJobRuns()
+	if err != nil {
+		log.Errorf("failed get unfinished scheduled job runs: %+v", err)
+	}
+	for i := range runs {
+		now := time.Now()
+		runs[i].Status = model.JobFailed
+		runs[i].Error = "interrupted by a restart"
+		runs[i].FinishedAt = &now
+		if err = db.UpdateScheduledJobRun(&runs[i]); err != nil {
+			log.Errorf("failed update scheduled job run: %+v", err)
+		}
+	}
+	jobs, err := db.GetScheduledJobs()
+	if err != nil {
+		log.Errorf("failed get scheduled jobs: %+v", err)
+	}
+	for i := range jobs {
+		Reschedule(&jobs[i])
+	}
+	ticker = cron.NewCron(tickInterval)
+	ticker.Do(func() {
+		tick(time.Now())
+	})
+}
+
+// Reschedule computes the next run of the job, it must be called when the job is saved
+func Reschedule(j *model.ScheduledJob) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(next, j.ID)
+	if j.Disabled {
+		return
+	}
+	s, err := cron.ParseSchedule(j.Cron)
+	if err != nil {
+		log.Warnf("invalid cron expression of scheduled job %d: %v", j.ID, err)
+		return
+	}
+	if t := s.Next(time.Now()); !t.IsZero() {
+		next[j.ID] = t
+	}
+}
+
+// Unschedule stops the future runs of a deleted job
+func Unschedule(id uint) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(next, id)
+}
+
+// Fill sets the fields of the job which are not saved
+func Fill(j *model.ScheduledJob) {
+	mu.Lock()
+	defer mu.Unlock()
+	if t, ok := next[j.ID]; ok {
+		j.NextRunAt = &t
+	}
+	_, j.Running = active[j.ID]
+}
+
+func tick(now time.Time) {
+	settle()
+	jobs, err := db.GetScheduledJobs()
+	if err != nil {
+		log.Errorf("failed get scheduled jobs: %+v", err)
+		return
+	}
+	for i := range jobs {
+		j := &jobs[i]
+		mu.Lock()
+		t, ok := next[j.ID]
+		due := ok && !now.Before(t)
+		if due {
+			delete(next, j.ID)
+		}
+		mu.Unlock()
+		if !due {
+			continue
+		}
+		Reschedule(j)
+		if _, err := Trigger(j, false); err != nil && !errors.Is(err, ErrRunning) {
+			log.Errorf("failed run scheduled job %d: %+v", j.ID, err)
+		}
+	}
+}
+
+// settle finishes the runs whose tasks are over
+func settle() {
+	mu.Lock()
+	var done []*activeRun
+	var errs []error
+	for _, a := range active {
+		if a.pending == nil {
+			continue
+		}
+		if over, err := a.pending(); over {
+			done = append(done, a)
+			errs = append(errs, err)
+		}
+	}
+	mu.Unlock()
+	for i, a := range done {
+		finish(a, errs[i])
+	}
+}
+
+var ErrRunning = errors.New("the previous run of the job is not over")
+
+// Trigger runs the job now, unless its previous run is not over, in which
+// case a skipped run is recorded and ErrRunning is returned
+func Trigger(j *model.ScheduledJob, manual bool) (*model.ScheduledJobRun, error) {
+	run := &model.ScheduledJobRun{
+		JobID:     j.ID,
+		Manual:    manual,
+		Status:    model.JobRunning,
+		StartedAt: time.Now(),
+	}
+	mu.Lock()
+	_, running := active[j.ID]
+	if !running {
+		active[j.ID] = &activeRun{job: j, run: run}
+	}
+	mu.Unlock()
+	if running {
+		run.Status = model.JobSkipped
+		run.Message = ErrRunning.Error()
+		run.FinishedAt = &run.StartedAt
+		if err := db.CreateScheduledJobRun(run); err != nil {
+			return nil, err
+		}
+		return run, ErrRunning
+	}
+	if err := db.CreateScheduledJobRun(run); err != nil {
+		mu.Lock()
+		delete(active, j.ID)
+		mu.Unlock()
+		return nil, err
+	}
+	j.LastRunAt = &run.StartedAt
+	j.LastStatus = model.JobRunning
+	j.LastError = ""
+	if err := db.UpdateScheduledJobStatus(j); err != nil {
+		log.Errorf("failed update scheduled job %d: %+v", j.ID, err)
+	}
+	go execute(j, run)
+	return run, nil
+}
+
+func execute(j *model.ScheduledJob, run *model.ScheduledJobRun) {
+	mu.Lock()
+	a := active[j.ID]
+	mu.Unlock()
+	o, err := func() (o *outcome, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return do(context.Background(), j)
+	}()
+	if err != nil || o.pending == nil {
+		if o != nil {
+			run.Message = o.message
+		}
+		finish(a, err)
+		return
+	}
+	mu.Lock()
+	run.Message = o.message
+	run.TaskIDs = strings.Join(o.taskIDs, ",")
+	a.pending = o.pending
+	mu.Unlock()
+	if err = db.UpdateScheduledJobRun(run); err != nil {
+		log.Errorf("failed update scheduled job run: %+v", err)
+	}
+}
+
+func finish(a *activeRun, err error) {
+	now := time.Now()
+	mu.Lock()
+	run, j := a.run, a.job
+	run.FinishedAt = &now
+	run.Status = model.JobSucceeded
+	if err != nil {
+		run.Status = model.JobFailed
+		run.Error = err.Error()
+	}
+	if active[j.ID] == a {
+		delete(active, j.ID)
+	}
+	mu.Unlock()
+	if e := db.UpdateScheduledJobRun(run); e != nil {
+		log.Errorf("failed update scheduled job run: %+v", e)
+	}
+	j.LastStatus, j.LastError = run.Status, run.Error
+	if e := db.UpdateScheduledJobStatus(j); e != nil {
+		log.Errorf("failed update scheduled job %d: %+v", j.ID, e)
+	}
+	if e := db.DeleteOldScheduledJobRuns(j.ID, keepRuns); e != nil {
+		log.Errorf("failed delete old runs of scheduled job %d: %+v", j.ID, e)
+	}
+}
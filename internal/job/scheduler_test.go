@@ -0,0 +1,88 @@
+package job
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func waitRun(t *testing.T, jobId uint) model.ScheduledJobRun {
+	for i := 0; i < 100; i++ {
+		runs, _, err := GetJobRuns(jobId, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) > 0 && runs[0].FinishedAt != nil {
+			return runs[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the run of job %d is not over", jobId)
+	return model.ScheduledJobRun{}
+}
+
+func TestLogCleanupJob(t *testing.T) {
+	if err := db.CreateUser(&model.User{Username: "admin", Role: model.ADMIN}); err != nil {
+		t.Fatal(err)
+	}
+	old, recent := time.Now().AddDate(0, 0, -10), time.Now()
+	for _, at := range []time.Time{old, recent} {
+		if err := db.GetDb().Create(&model.LoginLog{Username: "admin", CreatedAt: at}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := CreateJob(&model.ScheduledJob{Name: "bad", Kind: model.JobLogCleanup, Cron: "@daily"}); err == nil {
+		t.Errorf("expect max_age_days to be required")
+	}
+	if err := CreateJob(&model.ScheduledJob{Name: "bad", Kind: model.JobScan, Cron: "61 * * * *", Args: `{"path":"/"}`}); err == nil {
+		t.Errorf("expect invalid cron to be rejected")
+	}
+	j := &model.ScheduledJob{Name: "cleanup", Kind: model.JobLogCleanup, Cron: "@daily", Args: `{"types":["login"],"max_age_days":7}`}
+	if err := CreateJob(j); err != nil {
+		t.Fatal(err)
+	}
+	if j.NextRunAt == nil || j.NextRunAt.Before(time.Now()) {
+		t.Errorf("expect the next run to be planned, got %v", j.NextRunAt)
+	}
+	if _, err := Trigger(j, true); err != nil {
+		t.Fatal(err)
+	}
+	if run := waitRun(t, j.ID); run.Status != model.JobSucceeded || run.Message != "deleted 1 logs" {
+		t.Errorf("unexpected run: %+v", run)
+	}
+	var count int64
+	db.GetDb().Model(&model.LoginLog{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expect 1 log left, got %d", count)
+	}
+	saved, err := GetJobById(j.ID)
+	if err != nil || saved.LastStatus != model.JobSucceeded || saved.LastRunAt == nil {
+		t.Errorf("expect last status to be saved, got %+v %v", saved, err)
+	}
+
+	// a run whose tasks are not over blocks the next one
+	mu.Lock()
+	active[j.ID] = &activeRun{job: j, run: &model.ScheduledJobRun{}, pending: func() (bool, error) { return false, nil }}
+	mu.Unlock()
+	if run, err := Trigger(j, false); !errors.Is(err, ErrRunning) || run.Status != model.JobSkipped {
+		t.Errorf("expect the run to be skipped, got %+v %v", run, err)
+	}
+	mu.Lock()
+	delete(active, j.ID)
+	mu.Unlock()
+}
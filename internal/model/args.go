@@ -78,6 +78,8 @@ type ArchiveDecompressArgs struct {
 	CacheFull     bool
 	PutIntoNewDir bool
 	Overwrite     bool
+	// one of the conflict policies of fs, Overwrite chooses between overwrite and fail when it's empty
+	ConflictPolicy string
 }
 
 type SharingListArgs struct {
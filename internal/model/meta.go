@@ -13,4 +13,9 @@ type Meta struct {
 	RSub      bool   `json:"r_sub"`
 	Header    string `json:"header"`
 	HeaderSub bool   `json:"header_sub"`
+	// keep the previous content of overwritten files, 0 means no limit
+	Versioning     bool `json:"versioning"`
+	VSub           bool `json:"v_sub"`
+	MaxVersions    int  `json:"max_versions"`
+	VersionMaxDays int  `json:"version_max_days"`
 }
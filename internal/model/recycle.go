@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RecycleItem is an object moved into the recycle bin of its storage instead of being deleted
+type RecycleItem struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	StorageID  uint      `json:"storage_id" gorm:"index"`
+	Path       string    `json:"path" gorm:"type:text"` // original path, mount path included
+	ActualPath string    `json:"-" gorm:"type:text"`    // original path inside the storage
+	TrashDir   string    `json:"-" gorm:"type:text"`    // the folder holding the object, relative to the storage root
+	Name       string    `json:"name" gorm:"size:512"`  // name of the object inside TrashDir
+	Size       int64     `json:"size"`
+	IsDir      bool      `json:"is_dir"`
+	UserID     uint      `json:"user_id" gorm:"index"` // who deleted it
+	Username   string    `json:"username" gorm:"size:128"`
+	DeletedAt  time.Time `json:"deleted_at" gorm:"index"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// kinds of scheduled jobs, the arguments of each kind are described in package job
+const (
+	JobCopy            = "copy"
+	JobMerge           = "merge"
+	JobSync            = "sync"
+	JobScan            = "scan"
+	JobIndexUpdate     = "index_update"
+	JobLogCleanup      = "log_cleanup"
+	JobOfflineDownload = "offline_download"
+	JobChecksum        = "checksum"
+)
+
+// statuses of a run of a scheduled job
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobSkipped   = "skipped"
+)
+
+type ScheduledJob struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Name       string     `json:"name" gorm:"size:255" binding:"required"`
+	Kind       string     `json:"kind" gorm:"size:32" binding:"required"`
+	Cron       string     `json:"cron" gorm:"size:128" binding:"required"`
+	Args       string     `json:"args" gorm:"type:text"` // json arguments of the kind
+	Disabled   bool       `json:"disabled"`
+	LastRunAt  *time.Time `json:"last_run_at"`
+	LastStatus string     `json:"last_status" gorm:"size:32"`
+	LastError  string     `json:"last_error" gorm:"type:text"`
+	NextRunAt  *time.Time `json:"next_run_at" gorm:"-"`
+	Running    bool       `json:"running" gorm:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ScheduledJobRun is a run of a scheduled job. Jobs adding tasks finish when all
+// their tasks are done, the ids of the tasks are kept in TaskIDs.
+type ScheduledJobRun struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	JobID      uint       `json:"job_id" gorm:"index"`
+	Manual     bool       `json:"manual"`
+	Status     string     `json:"status" gorm:"size:32"`
+	Message    string     `json:"message" gorm:"type:text"`
+	Error      string     `json:"error" gorm:"type:text"`
+	TaskIDs    string     `json:"task_ids" gorm:"type:text"` // comma separated
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
@@ -1,6 +1,16 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+const (
+	SharingTypeRead   = iota // visitors can list and download the shared files
+	SharingTypeUpload        // file request, visitors can only upload into the shared folder
+)
 
 type SharingDB struct {
 	ID          string     `json:"id" gorm:"type:char(12);primaryKey"`
@@ -14,6 +24,22 @@ type SharingDB struct {
 	Remark      string     `json:"remark"`
 	Readme      string     `json:"readme" gorm:"type:text"`
 	Header      string     `json:"header" gorm:"type:text"`
+	Type        int        `json:"type"`
+	// the upload limits only apply to file requests, 0 means unlimited
+	UploadMaxFileSize  int64  `json:"upload_max_file_size"`
+	UploadMaxTotalSize int64  `json:"upload_max_total_size"`
+	UploadAllowedExts  string `json:"upload_allowed_exts"` // comma separated, empty allows any
+	UploadNamePrefix   bool   `json:"upload_name_prefix"`  // prefix file names with the uploader and date
+	Uploaded           int64  `json:"uploaded"`            // bytes received so far
+	// the traffic limits of downloads, 0 means unlimited
+	MaxTraffic      int64  `json:"max_traffic"`       // total bytes served
+	MaxDailyTraffic int64  `json:"max_daily_traffic"` // bytes served per day
+	SpeedLimit      int64  `json:"speed_limit"`       // bytes per second shared by all visitors
+	MaxConcurrent   int    `json:"max_concurrent"`    // downloads at the same time
+	ForceProxy      bool   `json:"force_proxy"`
+	Traffic         int64  `json:"traffic"`
+	DailyTraffic    int64  `json:"daily_traffic"`
+	TrafficDate     string `json:"traffic_date" gorm:"size:10"` // the day DailyTraffic is counted for
 	Sort
 }
 
@@ -45,3 +71,30 @@ func (s *Sharing) Valid() bool {
 func (s *Sharing) Verify(pwd string) bool {
 	return s.Pwd == "" || s.Pwd == pwd
 }
+
+func (s *Sharing) IsUpload() bool {
+	return s.Type == SharingTypeUpload
+}
+
+func (s *Sharing) AllowUploadExt(name string) bool {
+	if strings.TrimSpace(s.UploadAllowedExts) == "" {
+		return true
+	}
+	ext := utils.Ext(name)
+	for _, e := range strings.Split(s.UploadAllowedExts, ",") {
+		if strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(e), "."), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sharing) HasDownloadLimit() bool {
+	return s.MaxTraffic > 0 || s.MaxDailyTraffic > 0 || s.SpeedLimit > 0 || s.MaxConcurrent > 0
+}
+
+// MustProxy reports whether downloads must go through the server,
+// redirected downloads can't be limited
+func (s *Sharing) MustProxy() bool {
+	return s.ForceProxy || s.HasDownloadLimit()
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+const (
+	SharingLogAccess   = "access"   // listing or getting info of the share
+	SharingLogDownload = "download" // file served through the server
+	SharingLogRedirect = "redirect" // visitor redirected to the storage, bytes are unknown
+	SharingLogUpload   = "upload"   // file received by a file request
+)
+
+// SharingAccessLog records a single visit of a share.
+type SharingAccessLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	SharingID string    `json:"sharing_id" gorm:"size:12;index"`
+	Type      string    `json:"type" gorm:"size:16;index"`
+	Path      string    `json:"path" gorm:"size:1024"` // path inside the share
+	IP        string    `json:"ip" gorm:"size:64"`
+	UserAgent string    `json:"user_agent" gorm:"size:512"`
+	Bytes     int64     `json:"bytes"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+type SharingTimelineItem struct {
+	Date      string `json:"date"`
+	Accesses  int64  `json:"accesses"`
+	Downloads int64  `json:"downloads"`
+	Uploads   int64  `json:"uploads"`
+	Bytes     int64  `json:"bytes"`
+}
+
+type SharingTopFile struct {
+	Path      string `json:"path"`
+	Downloads int64  `json:"downloads"`
+	Bytes     int64  `json:"bytes"`
+}
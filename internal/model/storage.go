@@ -5,20 +5,23 @@ import (
 )
 
 type Storage struct {
-	ID              uint      `json:"id" gorm:"primaryKey"`                        // unique key
-	MountPath       string    `json:"mount_path" gorm:"unique" binding:"required"` // must be standardized
-	Order           int       `json:"order"`                                       // use to sort
-	Driver          string    `json:"driver"`                                      // driver used
-	CacheExpiration int       `json:"cache_expiration"`                            // cache expire time
-	Status          string    `json:"status"`
-	Addition        string    `json:"addition" gorm:"type:text"` // Additional information, defined in the corresponding driver
-	Remark          string    `json:"remark"`
-	Modified        time.Time `json:"modified"`
-	Disabled        bool      `json:"disabled"` // if disabled
-	DisableIndex    bool      `json:"disable_index"`
-	EnableSign      bool      `json:"enable_sign"`
+	ID                uint      `json:"id" gorm:"primaryKey"`                        // unique key
+	MountPath         string    `json:"mount_path" gorm:"unique" binding:"required"` // must be standardized
+	Order             int       `json:"order"`                                       // use to sort
+	Driver            string    `json:"driver"`                                      // driver used
+	CacheExpiration   int       `json:"cache_expiration"`                            // cache expire time
+	RefreshAhead      bool      `json:"refresh_ahead"`                               // refresh the hot directories before they expire
+	Status            string    `json:"status"`
+	Addition          string    `json:"addition" gorm:"type:text"` // Additional information, defined in the corresponding driver
+	Remark            string    `json:"remark"`
+	Modified          time.Time `json:"modified"`
+	Disabled          bool      `json:"disabled"` // if disabled
+	DisableIndex      bool      `json:"disable_index"`
+	EnableSign        bool      `json:"enable_sign"`
+	DisableRecycleBin bool      `json:"disable_recycle_bin"` // delete for good instead of moving into the recycle bin
 	Sort
 	Proxy
+	APILimit
 }
 
 type Sort struct {
@@ -31,11 +34,21 @@ type Proxy struct {
 	WebProxy     bool   `json:"web_proxy"`
 	WebdavPolicy string `json:"webdav_policy"`
 	ProxyRange   bool   `json:"proxy_range"`
+	// BlockCache keeps the proxied reads on the local disk
+	BlockCache   bool   `json:"block_cache"`
 	DownProxyURL string `json:"down_proxy_url"`
 	// Disable sign for DownProxyURL
 	DisableProxySign bool `json:"disable_proxy_sign"`
 }
 
+// APILimit limits the calls to the driver of a storage, 0 means unlimited
+type APILimit struct {
+	// MaxRPS is the calls per second
+	MaxRPS float64 `json:"max_rps"`
+	// MaxConcurrent is the calls at the same time, including the running uploads
+	MaxConcurrent int `json:"max_concurrent"`
+}
+
 func (s *Storage) GetStorage() *Storage {
 	return s
 }
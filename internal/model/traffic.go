@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// UserTraffic is the traffic of a user in a day
+type UserTraffic struct {
+	UserID   uint   `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Day      string `json:"day" gorm:"primaryKey;size:10"` // 2006-01-02 in the local time
+	Download int64  `json:"download"`                      // bytes sent to the user
+	Upload   int64  `json:"upload"`                        // bytes received from the user
+}
+
+// TrafficStat is the traffic of a user through a protocol to a storage in an hour
+type TrafficStat struct {
+	Hour      time.Time `json:"hour" gorm:"primaryKey;autoIncrement:false"` // the start of the hour in UTC
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	StorageID uint      `json:"storage_id" gorm:"primaryKey;autoIncrement:false"` // 0 when the storage is unknown
+	Protocol  string    `json:"protocol" gorm:"primaryKey;size:16"`
+	SharingID string    `json:"sharing_id" gorm:"primaryKey;size:64"` // empty out of the shares
+	Download  int64     `json:"download"`
+	Upload    int64     `json:"upload"`
+}
@@ -1,9 +1,11 @@
 package model
 
 import (
+	"crypto/subtle"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -29,6 +31,10 @@ var (
 	DefaultMaxAuthRetries = 5
 )
 
+const (
+	UserSourceLdap = "ldap"
+)
+
 type User struct {
 	ID           uint       `json:"id" gorm:"primaryKey"`                      // unique key
 	Username     string     `json:"username" gorm:"unique" binding:"required"` // username
@@ -45,6 +51,14 @@ type User struct {
 	// WebdavMaxSessions limits concurrent WebDAV sessions for the user.
 	// 0 means unlimited.
 	WebdavMaxSessions int `json:"webdav_max_sessions" gorm:"default:0"`
+	// MaxDownloadSpeed and MaxUploadSpeed cap the speed of the user in KB/s,
+	// 0 uses the limit of the role and -1 means unlimited.
+	MaxDownloadSpeed int `json:"max_download_speed" gorm:"default:0"`
+	MaxUploadSpeed   int `json:"max_upload_speed" gorm:"default:0"`
+	// TrafficQuota is the traffic in MB the user can download and upload in a TrafficQuotaPeriod,
+	// "day" or "month". 0 and empty use the quota of the role and -1 means unlimited.
+	TrafficQuota       int64  `json:"traffic_quota" gorm:"default:0"`
+	TrafficQuotaPeriod string `json:"traffic_quota_period" gorm:"size:16"`
 	// Determine permissions by bit
 	//   0:  can see hidden files
 	//   1:  can access without password
@@ -63,7 +77,11 @@ type User struct {
 	//   14: can share
 	Permission int32  `json:"permission"`
 	OtpSecret  string `json:"-"`
+	// RecoveryCodes holds the hashes of the unused 2FA recovery codes as a JSON array
+	RecoveryCodes string `gorm:"type:text" json:"-"`
 	SsoID      string `json:"sso_id"` // unique by sso platform
+	// Source is the provider which created the user, e.g. UserSourceLdap, empty for the local users
+	Source string `json:"-" gorm:"size:16"`
 	Authn      string `gorm:"type:text" json:"-"`
 }
 
@@ -156,6 +174,57 @@ func (u *User) CanShare() bool {
 	return (u.Permission>>14)&1 == 1
 }
 
+// HasTwoFactor reports whether the user has enrolled TOTP or at least one WebAuthn credential
+func (u *User) HasTwoFactor() bool {
+	if u.OtpSecret != "" {
+		return true
+	}
+	return u.Authn != "" && len(u.WebAuthnCredentials()) > 0
+}
+
+func (u *User) recoveryCodeHashes() []string {
+	var hashes []string
+	if u.RecoveryCodes != "" {
+		_ = json.Unmarshal([]byte(u.RecoveryCodes), &hashes)
+	}
+	return hashes
+}
+
+// SetRecoveryCodes replaces the stored recovery codes with the hashes of codes
+func (u *User) SetRecoveryCodes(codes []string) {
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	res, _ := json.Marshal(hashes)
+	u.RecoveryCodes = string(res)
+}
+
+// RecoveryCodesLeft returns the number of unused recovery codes
+func (u *User) RecoveryCodesLeft() int {
+	return len(u.recoveryCodeHashes())
+}
+
+// UseRecoveryCode consumes code if it matches one of the unused recovery codes.
+// The caller is responsible for persisting the user afterwards.
+func (u *User) UseRecoveryCode(code string) bool {
+	code = NormalizeRecoveryCode(code)
+	if code == "" {
+		return false
+	}
+	hash := HashRecoveryCode(code)
+	hashes := u.recoveryCodeHashes()
+	for i := range hashes {
+		if subtle.ConstantTimeCompare([]byte(hashes[i]), []byte(hash)) == 1 {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			res, _ := json.Marshal(hashes)
+			u.RecoveryCodes = string(res)
+			return true
+		}
+	}
+	return false
+}
+
 func (u *User) JoinPath(reqPath string) (string, error) {
 	return utils.JoinBasePath(u.BasePath, reqPath)
 }
@@ -164,6 +233,15 @@ func StaticHash(password string) string {
 	return utils.HashData(utils.SHA256, []byte(fmt.Sprintf("%s-%s", password, StaticHashSalt)))
 }
 
+// NormalizeRecoveryCode strips separators and case so that "ABCDE-12345" and "abcde12345" match
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
+
+func HashRecoveryCode(code string) string {
+	return StaticHash(NormalizeRecoveryCode(code))
+}
+
 func HashPwd(static string, salt string) string {
 	return utils.HashData(utils.SHA256, []byte(fmt.Sprintf("%s-%s", static, salt)))
 }
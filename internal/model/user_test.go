@@ -0,0 +1,23 @@
+package model
+
+import "testing"
+
+func TestUseRecoveryCode(t *testing.T) {
+	u := &User{}
+	u.SetRecoveryCodes([]string{"abcde-12345", "fghij-67890"})
+	if u.RecoveryCodesLeft() != 2 {
+		t.Fatalf("expect 2 recovery codes, got %d", u.RecoveryCodesLeft())
+	}
+	if u.UseRecoveryCode("wrong-codes") {
+		t.Errorf("unexpected match of a wrong code")
+	}
+	if !u.UseRecoveryCode(" ABCDE12345 ") {
+		t.Errorf("expect normalized code to match")
+	}
+	if u.UseRecoveryCode("abcde-12345") {
+		t.Errorf("expect recovery code to be single-use")
+	}
+	if u.RecoveryCodesLeft() != 1 {
+		t.Errorf("expect 1 recovery code left, got %d", u.RecoveryCodesLeft())
+	}
+}
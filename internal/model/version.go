@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// FileVersion is the previous content of an overwritten file, kept in the versions folder of its storage
+type FileVersion struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	StorageID   uint      `json:"storage_id" gorm:"index"`
+	ActualPath  string    `json:"-" gorm:"type:text"`    // path of the file inside the storage
+	Path        string    `json:"path" gorm:"type:text"` // path of the file, mount path included
+	VersionPath string    `json:"-" gorm:"type:text"`    // where the content is kept inside the storage
+	Size        int64     `json:"size"`
+	Modified    time.Time `json:"modified"` // modification time of the content
+	UserID      uint      `json:"user_id"`  // who overwrote it
+	Username    string    `json:"username" gorm:"size:128"`
+	CreatedAt   time.Time `json:"created_at"`
+}
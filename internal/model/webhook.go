@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// events sent to webhooks
+const (
+	// WebhookUpload is sent when a file is created or overwritten
+	WebhookUpload = "upload"
+	WebhookDelete = "delete"
+	// WebhookRename is sent for renames and moves
+	WebhookRename        = "rename"
+	WebhookTaskSucceeded = "task_succeeded"
+	WebhookTaskFailed    = "task_failed"
+	WebhookStorageStatus = "storage_status"
+	WebhookLoginFailed   = "login_failed"
+	// WebhookPing is only sent by the test of a webhook
+	WebhookPing = "ping"
+)
+
+var WebhookEvents = []string{WebhookUpload, WebhookDelete, WebhookRename, WebhookTaskSucceeded,
+	WebhookTaskFailed, WebhookStorageStatus, WebhookLoginFailed}
+
+type Webhook struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Name   string `json:"name" gorm:"size:255" binding:"required"`
+	URL    string `json:"url" gorm:"type:text" binding:"required"`
+	Secret string `json:"secret" gorm:"size:255"`  // the key of the signature, not signed if empty
+	Events string `json:"events" gorm:"type:text"` // comma separated, all of them if empty
+	// Paths are the prefixes of the paths of the events, one per line. The events without
+	// path, like the task and login events, are not filtered.
+	Paths     string    `json:"paths" gorm:"type:text"`
+	MaxRetry  int       `json:"max_retry"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is a request sent to a webhook, with its retries
+type WebhookDelivery struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	WebhookID  uint       `json:"webhook_id" gorm:"index"`
+	Event      string     `json:"event" gorm:"size:32"`
+	Payload    string     `json:"payload" gorm:"type:text"`
+	Attempts   int        `json:"attempts"`
+	StatusCode int        `json:"status_code"`
+	Response   string     `json:"response" gorm:"type:text"`
+	Error      string     `json:"error" gorm:"type:text"`
+	Succeeded  bool       `json:"succeeded"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
@@ -159,7 +159,9 @@ func getArchiveMeta(ctx context.Context, storage driver.Driver, path string, arg
 		if obj.IsDir() {
 			return nil, nil, errors.WithStack(errs.NotFile)
 		}
-		meta, err := storageAr.GetArchiveMeta(ctx, obj, args.ArchiveArgs)
+		meta, err := limitCallResult(ctx, storage, "archive_meta", func(ctx context.Context) (model.ArchiveMeta, error) {
+			return storageAr.GetArchiveMeta(ctx, obj, args.ArchiveArgs)
+		})
 		if !errors.Is(err, errs.NotImplement) {
 			archiveMetaProvider := &model.ArchiveMetaProvider{ArchiveMeta: meta, DriverProviding: true}
 			if meta != nil && meta.GetTree() != nil {
@@ -264,7 +266,9 @@ func _listArchive(ctx context.Context, storage driver.Driver, path string, args
 		if obj.IsDir() {
 			return nil, nil, errors.WithStack(errs.NotFile)
 		}
-		files, err := storageAr.ListArchive(ctx, obj, args.ArchiveInnerArgs)
+		files, err := limitCallResult(ctx, storage, "list_archive", func(ctx context.Context) ([]model.Obj, error) {
+			return storageAr.ListArchive(ctx, obj, args.ArchiveInnerArgs)
+		})
 		if !errors.Is(err, errs.NotImplement) {
 			return obj, files, err
 		}
@@ -364,7 +368,9 @@ func ArchiveGet(ctx context.Context, storage driver.Driver, path string, args mo
 		return nil, nil, errors.WithStack(errs.NotFile)
 	}
 	if g, ok := storage.(driver.ArchiveGetter); ok {
-		obj, err := g.ArchiveGet(ctx, af, args.ArchiveInnerArgs)
+		obj, err := limitCallResult(ctx, storage, "archive_get", func(ctx context.Context) (model.Obj, error) {
+			return g.ArchiveGet(ctx, af, args.ArchiveInnerArgs)
+		})
 		if err == nil {
 			return af, model.WrapObjName(obj), nil
 		}
@@ -458,7 +464,9 @@ func driverExtract(ctx context.Context, storage driver.Driver, path string, args
 	if extracted.IsDir() {
 		return nil, errors.WithStack(errs.NotFile)
 	}
-	link, err := storageAr.Extract(ctx, archiveFile, args)
+	link, err := limitCallResult(ctx, storage, "extract", func(ctx context.Context) (*model.Link, error) {
+		return storageAr.Extract(ctx, archiveFile, args)
+	})
 	return &objWithLink{link: link, obj: extracted}, err
 }
 
@@ -517,7 +525,9 @@ func ArchiveDecompress(ctx context.Context, storage driver.Driver, srcPath, dstD
 	var newObjs []model.Obj
 	switch s := storage.(type) {
 	case driver.ArchiveDecompressResult:
-		newObjs, err = s.ArchiveDecompress(ctx, srcObj, dstDir, args)
+		newObjs, err = limitCallResult(ctx, storage, "decompress", func(ctx context.Context) ([]model.Obj, error) {
+			return s.ArchiveDecompress(ctx, srcObj, dstDir, args)
+		})
 		if err == nil {
 			if len(newObjs) > 0 {
 				for _, newObj := range newObjs {
@@ -528,7 +538,9 @@ func ArchiveDecompress(ctx context.Context, storage driver.Driver, srcPath, dstD
 			}
 		}
 	case driver.ArchiveDecompress:
-		err = s.ArchiveDecompress(ctx, srcObj, dstDir, args)
+		err = limitCall(ctx, storage, "decompress", func(ctx context.Context) error {
+			return s.ArchiveDecompress(ctx, srcObj, dstDir, args)
+		})
 		if err == nil && !utils.IsBool(lazyCache...) {
 			Cache.DeleteDirectory(storage, dstDirPath)
 		}
@@ -3,6 +3,7 @@ package op
 import (
 	stdpath "path"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/cache"
@@ -48,11 +49,12 @@ func (cm *CacheManager) updateDirectoryObject(storage driver.Driver, dirPath str
 		return
 	}
 
-	if cache, exist := cm.dirCache.Get(key); exist {
+	if cache, exist := cm.getDirectory(storage, key); exist {
 		if oldObj.IsDir() {
 			cm.deleteDirectoryTree(stdpath.Join(key, oldObj.GetName()))
 		}
 		cache.UpdateObject(oldObj.GetName(), newObj)
+		persistDirectory(storage, key, cache)
 	}
 }
 
@@ -61,9 +63,11 @@ func (cm *CacheManager) addDirectoryObject(storage driver.Driver, dirPath string
 	if storage.Config().NoCache {
 		return
 	}
-	cache, exist := cm.dirCache.Get(Key(storage, dirPath))
+	key := Key(storage, dirPath)
+	cache, exist := cm.getDirectory(storage, key)
 	if exist {
 		cache.UpdateObject(newObj.GetName(), newObj)
+		persistDirectory(storage, key, cache)
 	}
 }
 
@@ -75,10 +79,16 @@ func (cm *CacheManager) DeleteDirectoryTree(storage driver.Driver, dirPath strin
 	cm.deleteDirectoryTree(Key(storage, dirPath))
 }
 func (cm *CacheManager) deleteDirectoryTree(key string) {
+	if s := getDirStore(); s != nil {
+		s.deleteTree(key)
+	}
+	cm.takeDirectoryTree(key)
+}
+func (cm *CacheManager) takeDirectoryTree(key string) {
 	if dirCache, exists := cm.dirCache.Take(key); exists {
 		for _, obj := range dirCache.objs {
 			if obj.IsDir() {
-				cm.deleteDirectoryTree(stdpath.Join(key, obj.GetName()))
+				cm.takeDirectoryTree(stdpath.Join(key, obj.GetName()))
 			}
 		}
 	}
@@ -89,7 +99,11 @@ func (cm *CacheManager) DeleteDirectory(storage driver.Driver, dirPath string) {
 	if storage.Config().NoCache {
 		return
 	}
-	cm.dirCache.Delete(Key(storage, dirPath))
+	key := Key(storage, dirPath)
+	cm.dirCache.Delete(key)
+	if s := getDirStore(); s != nil {
+		s.delete(key)
+	}
 }
 
 // remove object from dirCache.
@@ -104,14 +118,66 @@ func (cm *CacheManager) removeDirectoryObject(storage driver.Driver, dirPath str
 	if storage.Config().NoCache {
 		return
 	}
-	if cache, exist := cm.dirCache.Get(key); exist {
+	if cache, exist := cm.getDirectory(storage, key); exist {
 		if obj.IsDir() {
 			cm.deleteDirectoryTree(stdpath.Join(key, obj.GetName()))
 		}
 		cache.RemoveObject(obj.GetName())
+		persistDirectory(storage, key, cache)
 	}
 }
 
+// cache the listing of a directory until the cache of the storage expires
+func (cm *CacheManager) setDirectory(storage driver.Driver, key string, objs []model.Obj) {
+	ttl := time.Minute * time.Duration(storage.GetStorage().CacheExpiration)
+	dirCache := newDirectoryCache(objs)
+	dirCache.expires = time.Now().Add(ttl)
+	cm.dirCache.SetWithExpirable(key, dirCache, cache.ExpirationTime(dirCache.expires))
+	persistDirectory(storage, key, dirCache)
+}
+
+// cached listing of a directory, it's loaded from the persistent cache if it's not in memory
+func (cm *CacheManager) getDirectory(storage driver.Driver, key string) (*directoryCache, bool) {
+	if dirCache, exist := cm.dirCache.Get(key); exist {
+		return dirCache, true
+	}
+	s := getDirStore()
+	if s == nil || storage.Config().NoCache {
+		return nil, false
+	}
+	stored, exist := s.get(key)
+	if !exist || stored.StorageID != storage.GetStorage().ID {
+		return nil, false
+	}
+	if !time.Now().Before(stored.Expires) {
+		s.delete(key)
+		return nil, false
+	}
+	dirCache := newDirectoryCache(fromStoredObjs(stored.Objs))
+	dirCache.expires = stored.Expires
+	// the listing may have been changed after it was sorted
+	dirCache.dirtyFlags = dirtyUpdate
+	cm.dirCache.SetWithExpirable(key, dirCache, cache.ExpirationTime(stored.Expires))
+	return dirCache, true
+}
+
+// write the listing of a directory to the persistent cache if it's enabled
+func persistDirectory(storage driver.Driver, key string, dirCache *directoryCache) {
+	s := getDirStore()
+	if s == nil {
+		return
+	}
+	dirCache.mu.RLock()
+	objs, ok := toStoredObjs(dirCache.objs)
+	expires := dirCache.expires
+	dirCache.mu.RUnlock()
+	if !ok {
+		s.delete(key)
+		return
+	}
+	s.put(key, &storedDir{StorageID: storage.GetStorage().ID, Expires: expires, Objs: objs})
+}
+
 // cache user data
 func (cm *CacheManager) SetUser(username string, user *model.User) {
 	cm.userCache.Set(username, user)
@@ -173,8 +239,11 @@ func (cm *CacheManager) InvalidateStorageDetails(storage driver.Driver) {
 	cm.detailCache.Delete(storage.GetStorage().MountPath)
 }
 
-// clears all caches
+// clears all caches, including the persisted listings
 func (cm *CacheManager) ClearAll() {
+	if s := getDirStore(); s != nil {
+		s.clear()
+	}
 	cm.dirCache.Clear()
 	cm.linkCache.Clear()
 	cm.userCache.Clear()
@@ -183,9 +252,12 @@ func (cm *CacheManager) ClearAll() {
 }
 
 type directoryCache struct {
-	objs   []model.Obj
-	sorted []model.Obj
-	mu     sync.RWMutex
+	objs    []model.Obj
+	sorted  []model.Obj
+	mu      sync.RWMutex
+	expires time.Time
+	// hits counts the listings from the cache in the refresh ahead window
+	hits atomic.Int32
 
 	dirtyFlags uint8
 }
@@ -204,6 +276,13 @@ func newDirectoryCache(objs []model.Obj) *directoryCache {
 	}
 }
 
+// expiry returns when the listing expires
+func (dc *directoryCache) expiry() time.Time {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+	return dc.expires
+}
+
 func (dc *directoryCache) RemoveObject(name string) {
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
@@ -0,0 +1,360 @@
+package op
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// dirCacheBucket holds the persisted listings keyed by Key(storage, path)
+var dirCacheBucket = []byte("dirs")
+
+// the changes are written to the file in batches
+const dirCacheFlushDelay = time.Second
+
+// storedObj is a model.Object with the thumbnail and the url of model.ObjThumbURL,
+// the listings with other objects are only cached in memory as their drivers need them as they are
+type storedObj struct {
+	ID        string    `json:"id,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Modified  time.Time `json:"modified"`
+	Ctime     time.Time `json:"ctime"`
+	IsFolder  bool      `json:"is_folder"`
+	HashInfo  string    `json:"hash_info,omitempty"`
+	Thumbnail string    `json:"thumbnail,omitempty"`
+	Url       string    `json:"url,omitempty"`
+}
+
+type storedDir struct {
+	StorageID uint        `json:"storage_id"`
+	Expires   time.Time   `json:"expires"`
+	Objs      []storedObj `json:"objs"`
+}
+
+func newStoredObj(o model.Object) storedObj {
+	s := storedObj{
+		ID:       o.ID,
+		Path:     o.Path,
+		Name:     o.Name,
+		Size:     o.Size,
+		Modified: o.Modified,
+		Ctime:    o.Ctime,
+		IsFolder: o.IsFolder,
+	}
+	if hash := o.HashInfo.String(); hash != "{}" && hash != "null" {
+		s.HashInfo = hash
+	}
+	return s
+}
+
+// toStoredObjs returns false if any of the objs can't be restored as it is
+func toStoredObjs(objs []model.Obj) ([]storedObj, bool) {
+	stored := make([]storedObj, 0, len(objs))
+	for _, obj := range objs {
+		var s storedObj
+		switch o := model.UnwrapObj(obj).(type) {
+		case *model.Object:
+			s = newStoredObj(*o)
+		case *model.ObjThumb:
+			s = newStoredObj(o.Object)
+			s.Thumbnail = o.Thumbnail.Thumbnail
+		case *model.ObjectURL:
+			s = newStoredObj(o.Object)
+			s.Url = o.Url.Url
+		case *model.ObjThumbURL:
+			s = newStoredObj(o.Object)
+			s.Thumbnail, s.Url = o.Thumbnail.Thumbnail, o.Url.Url
+		default:
+			return nil, false
+		}
+		stored = append(stored, s)
+	}
+	return stored, true
+}
+
+func fromStoredObjs(stored []storedObj) []model.Obj {
+	objs := make([]model.Obj, len(stored))
+	for i, s := range stored {
+		o := model.Object{
+			ID:       s.ID,
+			Path:     s.Path,
+			Name:     s.Name,
+			Size:     s.Size,
+			Modified: s.Modified,
+			Ctime:    s.Ctime,
+			IsFolder: s.IsFolder,
+		}
+		if s.HashInfo != "" {
+			o.HashInfo = utils.FromString(s.HashInfo)
+		}
+		switch {
+		case s.Thumbnail != "" && s.Url != "":
+			objs[i] = &model.ObjThumbURL{Object: o, Thumbnail: model.Thumbnail{Thumbnail: s.Thumbnail}, Url: model.Url{Url: s.Url}}
+		case s.Thumbnail != "":
+			objs[i] = &model.ObjThumb{Object: o, Thumbnail: model.Thumbnail{Thumbnail: s.Thumbnail}}
+		case s.Url != "":
+			objs[i] = &model.ObjectURL{Object: o, Url: model.Url{Url: s.Url}}
+		default:
+			objs[i] = &o
+		}
+	}
+	model.WrapObjsName(objs)
+	return objs
+}
+
+// dirCacheStore keeps the directory listings in a bbolt file so that they survive restarts
+type dirCacheStore struct {
+	mu sync.Mutex
+	db *bolt.DB
+	// pending are the listings not written yet, nil deletes the listing
+	pending map[string]*storedDir
+	// trees are the directories whose listings and those of their children are deleted
+	// before the pending listings are written
+	trees []string
+	timer *time.Timer
+}
+
+func openDirCacheStore(file string) (*dirCacheStore, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0o777); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	db, err := bolt.Open(file, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed open %s", file)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(dirCacheBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.WithStack(err)
+	}
+	s := &dirCacheStore{db: db, pending: make(map[string]*storedDir)}
+	go s.purgeExpired()
+	return s, nil
+}
+
+// get returns the persisted listing of the key, which may have expired
+func (s *dirCacheStore) get(key string) (*storedDir, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.pending[key]; ok {
+		return d, d != nil
+	}
+	for _, tree := range s.trees {
+		if utils.IsSubPath(tree, key) {
+			return nil, false
+		}
+	}
+	if s.db == nil {
+		return nil, false
+	}
+	var d *storedDir
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(dirCacheBucket).Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+		d = &storedDir{}
+		return utils.Json.Unmarshal(v, d)
+	})
+	if err != nil {
+		log.Warnf("failed read the persisted listing of %s: %+v", key, err)
+		return nil, false
+	}
+	return d, d != nil
+}
+
+func (s *dirCacheStore) put(key string, d *storedDir) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[key] = d
+	s.schedule()
+}
+
+func (s *dirCacheStore) delete(key string) {
+	s.put(key, nil)
+}
+
+// deleteTree deletes the listing of the key and those of its children
+func (s *dirCacheStore) deleteTree(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range s.pending {
+		if utils.IsSubPath(key, k) {
+			delete(s.pending, k)
+		}
+	}
+	s.trees = append(s.trees, key)
+	s.schedule()
+}
+
+// clear deletes all the persisted listings, the keys all start with /
+func (s *dirCacheStore) clear() {
+	s.deleteTree("/")
+}
+
+// schedule must be called with s.mu held
+func (s *dirCacheStore) schedule() {
+	if s.timer != nil {
+		return
+	}
+	s.timer = time.AfterFunc(dirCacheFlushDelay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.timer = nil
+		s.flush()
+	})
+}
+
+// flush writes the pending changes, it must be called with s.mu held.
+// The changes are kept to be written again if it fails.
+func (s *dirCacheStore) flush() {
+	if s.db == nil || (len(s.pending) == 0 && len(s.trees) == 0) {
+		return
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(dirCacheBucket)
+		for _, tree := range s.trees {
+			// the keys with the prefix aren't all in the tree, such as /a-b for /a
+			var keys [][]byte
+			c := b.Cursor()
+			for k, _ := c.Seek([]byte(tree)); k != nil && strings.HasPrefix(string(k), tree); k, _ = c.Next() {
+				if utils.IsSubPath(tree, string(k)) {
+					keys = append(keys, append([]byte(nil), k...))
+				}
+			}
+			for _, k := range keys {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		for k, d := range s.pending {
+			if d == nil {
+				if err := b.Delete([]byte(k)); err != nil {
+					return err
+				}
+				continue
+			}
+			v, err := utils.Json.Marshal(d)
+			if err != nil {
+				return err
+			}
+			if err = b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("failed write the persisted listings: %+v", err)
+		s.schedule()
+		return
+	}
+	s.pending = make(map[string]*storedDir)
+	s.trees = nil
+}
+
+// purgeExpired deletes the expired listings left in the file
+func (s *dirCacheStore) purgeExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return
+	}
+	now := time.Now()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(dirCacheBucket)
+		var keys [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var d storedDir
+			if err := utils.Json.Unmarshal(v, &d); err != nil || !now.Before(d.Expires) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err = b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Warnf("failed purge the expired persisted listings: %+v", err)
+	}
+}
+
+func (s *dirCacheStore) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.flush()
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return errors.WithStack(err)
+}
+
+var (
+	dirStoreMu     sync.Mutex
+	dirStore       *dirCacheStore
+	dirStoreLoaded bool
+)
+
+// getDirStore opens the persistent directory cache on the first use,
+// it returns nil if the listings are not persisted
+func getDirStore() *dirCacheStore {
+	dirStoreMu.Lock()
+	defer dirStoreMu.Unlock()
+	if dirStoreLoaded {
+		return dirStore
+	}
+	dirStoreLoaded = true
+	if conf.Conf == nil || !conf.Conf.DirCache.Persist {
+		return nil
+	}
+	s, err := openDirCacheStore(conf.Conf.DirCache.File)
+	if err != nil {
+		log.Errorf("failed open the persistent directory cache, the listings are only cached in memory: %+v", err)
+		return nil
+	}
+	dirStore = s
+	return s
+}
+
+// CloseDirCache writes the pending listings and closes the persistent directory cache,
+// it's opened again on the next use
+func CloseDirCache() {
+	dirStoreMu.Lock()
+	s := dirStore
+	dirStore, dirStoreLoaded = nil, false
+	dirStoreMu.Unlock()
+	if s == nil {
+		return
+	}
+	if err := s.close(); err != nil {
+		log.Errorf("failed close the persistent directory cache: %+v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package op_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func objNames(objs []model.Obj) []string {
+	names := make([]string, len(objs))
+	for i, obj := range objs {
+		names[i] = obj.GetName()
+	}
+	return names
+}
+
+func TestPersistentDirCache(t *testing.T) {
+	op.CloseDirCache()
+	conf.Conf.DirCache = conf.DirCache{Persist: true, File: filepath.Join(t.TempDir(), "dir_cache.db")}
+	defer func() {
+		op.CloseDirCache()
+		conf.Conf.DirCache.Persist = false
+	}()
+	// the listings in memory are lost, those in the file are loaded again.
+	// The caches are cleared while the file is closed, so that it's kept.
+	restart := func() {
+		op.CloseDirCache()
+		conf.Conf.DirCache.Persist = false
+		op.Cache.ClearAll()
+		op.CloseDirCache()
+		conf.Conf.DirCache.Persist = true
+	}
+
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:          "Virtual",
+		MountPath:       "/dir_cache",
+		CacheExpiration: 10,
+		Addition:        `{"num_file":3,"num_folder":2,"max_file_size":10,"min_file_size":1}`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/dir_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := func() []string {
+		objs, err := op.List(ctx, storage, "/", model.ListArgs{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return objNames(objs)
+	}
+
+	// the virtual files are named randomly on each listing
+	names := list()
+	restart()
+	if got := list(); !utils.SliceEqual(got, names) {
+		t.Fatalf("expect the listing to be loaded from the file, got %v, want %v", got, names)
+	}
+
+	if err = op.MakeDir(ctx, storage, "/new"); err != nil {
+		t.Fatal(err)
+	}
+	file := names[len(names)-1]
+	if err = op.Remove(ctx, storage, "/"+file); err != nil {
+		t.Fatal(err)
+	}
+	restart()
+	got := list()
+	if !utils.SliceContains(got, "new") || utils.SliceContains(got, file) {
+		t.Errorf("expect the changes to be persisted, got %v", got)
+	}
+
+	op.Cache.DeleteDirectoryTree(storage, "/")
+	restart()
+	if got = list(); utils.SliceContains(got, names[0]) {
+		t.Errorf("expect the deleted listing not to be loaded, got %v", got)
+	}
+
+	// clearing all the caches deletes the persisted listings too
+	names = list()
+	op.Cache.ClearAll()
+	restart()
+	if got = list(); utils.SliceContains(got, names[0]) {
+		t.Errorf("expect the cleared listing not to be loaded, got %v", got)
+	}
+}
@@ -79,6 +79,10 @@ func getMainItems(config driver.Config) []driver.Item {
 			Default:  "30",
 			Required: true,
 			Help:     "The cache expiration time for this storage",
+		}, driver.Item{
+			Name: "refresh_ahead",
+			Type: conf.TypeBool,
+			Help: "Refresh the often accessed directories in the background before their cache expires",
 		})
 	}
 	if config.MustProxy() {
@@ -123,6 +127,12 @@ func getMainItems(config driver.Config) []driver.Item {
 		Default: "false",
 		Help:    "Disable sign for Download proxy URL",
 	})
+	items = append(items, driver.Item{
+		Name:    "block_cache",
+		Type:    conf.TypeBool,
+		Default: "false",
+		Help:    "Cache the proxied reads on the local disk",
+	})
 	if config.LocalSort {
 		items = append(items, []driver.Item{{
 			Name:    "order_by",
@@ -0,0 +1,145 @@
+package op
+
+import (
+	"context"
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// types of fs events
+const (
+	FsCreated = "created"
+	FsUpdated = "updated"
+	FsDeleted = "deleted"
+	// FsRenamed is sent for renames and moves, OldPath is the path before
+	FsRenamed = "renamed"
+)
+
+// FsEvent is a change of an object made by a write or noticed by a refresh of the cache,
+// the paths are full paths starting with the mount path of the storage
+type FsEvent struct {
+	Type    string    `json:"type"`
+	Path    string    `json:"path"`
+	OldPath string    `json:"old_path,omitempty"`
+	IsDir   bool      `json:"is_dir"`
+	Size    int64     `json:"size"`
+	Time    time.Time `json:"time"`
+}
+
+// FsEventListener is called for each event, it must not block
+type FsEventListener func(e FsEvent)
+
+var (
+	fsEventMu        sync.RWMutex
+	fsEventListeners = make(map[*FsEventListener]struct{})
+)
+
+// SubscribeFsEvents adds a listener of the fs events and returns the function removing it
+func SubscribeFsEvents(listener FsEventListener) func() {
+	key := &listener
+	fsEventMu.Lock()
+	fsEventListeners[key] = struct{}{}
+	fsEventMu.Unlock()
+	return func() {
+		fsEventMu.Lock()
+		delete(fsEventListeners, key)
+		fsEventMu.Unlock()
+	}
+}
+
+type noFsEventsKey struct{}
+
+// withoutFsEvents is used for the temporary changes made in the middle of an operation
+func withoutFsEvents(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noFsEventsKey{}, true)
+}
+
+// inHiddenFolder reports whether the actual path is in the recycle bin, the versions folder
+// or the folder of the staged uploads
+func inHiddenFolder(actualPath string) bool {
+	return utils.IsSubPath(RecycleBinDir, actualPath) || utils.IsSubPath(VersionsDir, actualPath) ||
+		utils.IsSubPath(PartsDir, actualPath)
+}
+
+// IsHiddenPath reports whether the path is in the recycle bin, the versions folder or the folder
+// of the staged uploads of its storage
+func IsHiddenPath(path string) bool {
+	_, actualPath, err := GetStorageAndActualPath(path)
+	return err == nil && inHiddenFolder(actualPath)
+}
+
+// publishFsEvent sends the event of the objects at the actual paths of the storage, oldPath
+// is only set for renames. The objects moved into or out of the recycle bin and the versions
+// folder are seen as deleted or created, and the changes inside them are not sent.
+func publishFsEvent(ctx context.Context, storage driver.Driver, typ, path, oldPath string, obj model.Obj) {
+	if v, _ := ctx.Value(noFsEventsKey{}).(bool); v {
+		return
+	}
+	fsEventMu.RLock()
+	defer fsEventMu.RUnlock()
+	if len(fsEventListeners) == 0 {
+		return
+	}
+	if typ == FsRenamed {
+		switch hidden, oldHidden := inHiddenFolder(path), inHiddenFolder(oldPath); {
+		case hidden && oldHidden:
+			return
+		case hidden:
+			typ, path, oldPath = FsDeleted, oldPath, ""
+		case oldHidden:
+			typ, oldPath = FsCreated, ""
+		}
+	} else if inHiddenFolder(path) {
+		return
+	}
+	mountPath := storage.GetStorage().MountPath
+	e := FsEvent{
+		Type: typ,
+		Path: utils.GetFullPath(mountPath, path),
+		Time: time.Now(),
+	}
+	if oldPath != "" {
+		e.OldPath = utils.GetFullPath(mountPath, oldPath)
+	}
+	if obj != nil {
+		e.IsDir = obj.IsDir()
+		e.Size = obj.GetSize()
+	}
+	for l := range fsEventListeners {
+		(*l)(e)
+	}
+}
+
+// publishListChanges sends the differences between the cached and the refreshed objects of a folder
+func publishListChanges(ctx context.Context, storage driver.Driver, dirPath string, old, objs []model.Obj) {
+	fsEventMu.RLock()
+	n := len(fsEventListeners)
+	fsEventMu.RUnlock()
+	if n == 0 {
+		return
+	}
+	oldObjs := make(map[string]model.Obj, len(old))
+	for _, obj := range old {
+		oldObjs[obj.GetName()] = obj
+	}
+	for _, obj := range objs {
+		path := stdpath.Join(dirPath, obj.GetName())
+		o, ok := oldObjs[obj.GetName()]
+		if !ok {
+			publishFsEvent(ctx, storage, FsCreated, path, "", obj)
+			continue
+		}
+		delete(oldObjs, obj.GetName())
+		if !obj.IsDir() && (o.GetSize() != obj.GetSize() || !o.ModTime().Equal(obj.ModTime())) {
+			publishFsEvent(ctx, storage, FsUpdated, path, "", obj)
+		}
+	}
+	for name, obj := range oldObjs {
+		publishFsEvent(ctx, storage, FsDeleted, stdpath.Join(dirPath, name), "", obj)
+	}
+}
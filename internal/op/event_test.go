@@ -0,0 +1,72 @@
+package op_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+)
+
+func TestFsEvents(t *testing.T) {
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/events",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, t.TempDir()),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var events []string
+	unsubscribe := op.SubscribeFsEvents(func(e op.FsEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf("%s %s %s %v", e.Type, e.Path, e.OldPath, e.IsDir))
+	})
+	defer unsubscribe()
+
+	put := func(content string) {
+		file := &stream.FileStream{
+			Obj:    &model.Object{Name: "a.txt", Size: int64(len(content))},
+			Reader: io.NopCloser(bytes.NewReader([]byte(content))),
+		}
+		if err := op.Put(ctx, storage, "/dir", file, nil); err != nil {
+			t.Fatalf("failed to put: %+v", err)
+		}
+	}
+	if err = op.MakeDir(ctx, storage, "/dir"); err != nil {
+		t.Fatal(err)
+	}
+	put("a")
+	put("aa")
+	if err = op.Rename(ctx, storage, "/dir/a.txt", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err = op.Remove(ctx, storage, "/dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"created /events/dir  true",
+		"created /events/dir/a.txt  false",
+		"updated /events/dir/a.txt  false",
+		"renamed /events/dir/b.txt /events/dir/a.txt false",
+		"deleted /events/dir/b.txt  false",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("expect events %q, got %q", expected, events)
+	}
+}
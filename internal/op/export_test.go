@@ -0,0 +1,4 @@
+package op
+
+// LimitCall exposes limitCall to the tests of the package op_test
+var LimitCall = limitCall
@@ -29,12 +29,21 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 	log.Debugf("op.List %s", path)
 	key := Key(storage, path)
 	if !args.Refresh {
-		if dirCache, exists := Cache.dirCache.Get(key); exists {
+		if dirCache, exists := Cache.getDirectory(storage, key); exists {
 			log.Debugf("use cache when list %s", path)
+			refreshAhead(storage, path, key, dirCache)
 			return dirCache.GetSortedObjects(storage), nil
 		}
 	}
 
+	// the cached objects of a refreshed folder are compared with the new ones to send the changes
+	var oldObjs []model.Obj
+	if args.Refresh {
+		if dirCache, exists := Cache.getDirectory(storage, key); exists {
+			oldObjs = dirCache.GetSortedObjects(storage)
+		}
+	}
+
 	dir, err := GetUnwrap(ctx, storage, path)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get dir")
@@ -45,7 +54,9 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 	}
 
 	objs, err, _ := listG.Do(key, func() ([]model.Obj, error) {
-		files, err := storage.List(ctx, dir, args)
+		files, err := limitCallResult(ctx, storage, "list", func(ctx context.Context) ([]model.Obj, error) {
+			return storage.List(ctx, dir, args)
+		})
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to list objs")
 		}
@@ -71,13 +82,15 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 		if !storage.Config().NoCache {
 			if len(files) > 0 {
 				log.Debugf("set cache: %s => %+v", key, files)
-				ttl := time.Minute * time.Duration(storage.GetStorage().CacheExpiration)
-				Cache.dirCache.SetWithTTL(key, newDirectoryCache(files), ttl)
+				Cache.setDirectory(storage, key, files)
 			} else {
 				log.Debugf("del cache: %s", key)
 				Cache.deleteDirectoryTree(key)
 			}
 		}
+		if oldObjs != nil {
+			publishListChanges(ctx, storage, path, oldObjs, files)
+		}
 		return files, nil
 	})
 	return objs, err
@@ -90,7 +103,9 @@ func Get(ctx context.Context, storage driver.Driver, path string) (model.Obj, er
 
 	// get the obj directly without list so that we can reduce the io
 	if g, ok := storage.(driver.Getter); ok {
-		obj, err := g.Get(ctx, path)
+		obj, err := limitCallResult(ctx, storage, "get", func(ctx context.Context) (model.Obj, error) {
+			return g.Get(ctx, path)
+		})
 		if err == nil {
 			return model.WrapObjName(obj), nil
 		}
@@ -103,7 +118,9 @@ func Get(ctx context.Context, storage driver.Driver, path string) (model.Obj, er
 	if utils.PathEqual(path, "/") {
 		var rootObj model.Obj
 		if getRooter, ok := storage.(driver.GetRooter); ok {
-			obj, err := getRooter.GetRoot(ctx)
+			obj, err := limitCallResult(ctx, storage, "get_root", func(ctx context.Context) (model.Obj, error) {
+				return getRooter.GetRoot(ctx)
+			})
 			if err != nil {
 				return nil, errors.WithMessage(err, "failed get root obj")
 			}
@@ -198,7 +215,9 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 			return nil, errors.WithStack(errs.NotFile)
 		}
 
-		link, err := storage.Link(ctx, file, args)
+		link, err := limitCallResult(ctx, storage, "link", func(ctx context.Context) (*model.Link, error) {
+			return storage.Link(ctx, file, args)
+		})
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed get link")
 		}
@@ -233,10 +252,12 @@ func Other(ctx context.Context, storage driver.Driver, args model.FsOtherArgs) (
 		return nil, errors.WithMessagef(err, "failed to get obj")
 	}
 	if o, ok := storage.(driver.Other); ok {
-		return o.Other(ctx, model.OtherArgs{
-			Obj:    obj,
-			Method: args.Method,
-			Data:   args.Data,
+		return limitCallResult(ctx, storage, "other", func(ctx context.Context) (interface{}, error) {
+			return o.Other(ctx, model.OtherArgs{
+				Obj:    obj,
+				Method: args.Method,
+				Data:   args.Data,
+			})
 		})
 	} else {
 		return nil, errs.NotImplement
@@ -270,26 +291,29 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string, lazyCache
 				switch s := storage.(type) {
 				case driver.MkdirResult:
 					var newObj model.Obj
-					newObj, err = s.MakeDir(ctx, parentDir, dirName)
+					newObj, err = limitCallResult(ctx, storage, "make_dir", func(ctx context.Context) (model.Obj, error) {
+						return s.MakeDir(ctx, parentDir, dirName)
+					})
 					if err == nil {
 						if newObj != nil {
-							if !storage.Config().NoCache {
-								if dirCache, exist := Cache.dirCache.Get(Key(storage, parentPath)); exist {
-									dirCache.UpdateObject("", newObj)
-								}
-							}
+							Cache.addDirectoryObject(storage, parentPath, newObj)
 						} else if !utils.IsBool(lazyCache...) {
 							Cache.DeleteDirectory(storage, parentPath)
 						}
 					}
 				case driver.Mkdir:
-					err = s.MakeDir(ctx, parentDir, dirName)
+					err = limitCall(ctx, storage, "make_dir", func(ctx context.Context) error {
+						return s.MakeDir(ctx, parentDir, dirName)
+					})
 					if err == nil && !utils.IsBool(lazyCache...) {
 						Cache.DeleteDirectory(storage, parentPath)
 					}
 				default:
 					return nil, errs.NotImplement
 				}
+				if err == nil {
+					publishFsEvent(ctx, storage, FsCreated, path, "", &model.Object{Name: dirName, IsFolder: true, Modified: time.Now()})
+				}
 				return nil, errors.WithStack(err)
 			}
 			return nil, errors.WithMessage(err, "failed to check if dir exists")
@@ -327,7 +351,9 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	switch s := storage.(type) {
 	case driver.MoveResult:
 		var newObj model.Obj
-		newObj, err = s.Move(ctx, srcObj, dstDir)
+		newObj, err = limitCallResult(ctx, storage, "move", func(ctx context.Context) (model.Obj, error) {
+			return s.Move(ctx, srcObj, dstDir)
+		})
 		if err == nil {
 			Cache.removeDirectoryObject(storage, srcDirPath, srcRawObj)
 			if newObj != nil {
@@ -337,7 +363,9 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 			}
 		}
 	case driver.Move:
-		err = s.Move(ctx, srcObj, dstDir)
+		err = limitCall(ctx, storage, "move", func(ctx context.Context) error {
+			return s.Move(ctx, srcObj, dstDir)
+		})
 		if err == nil {
 			Cache.removeDirectoryObject(storage, srcDirPath, srcRawObj)
 			if !utils.IsBool(lazyCache...) {
@@ -347,6 +375,9 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	default:
 		err = errs.NotImplement
 	}
+	if err == nil {
+		publishFsEvent(ctx, storage, FsRenamed, stdpath.Join(dstDirPath, srcObj.GetName()), srcPath, srcObj)
+	}
 
 	if !utils.IsBool(lazyCache...) && err == nil && needHandleObjsUpdateHook() {
 		if !srcObj.IsDir() {
@@ -380,7 +411,9 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 	switch s := storage.(type) {
 	case driver.RenameResult:
 		var newObj model.Obj
-		newObj, err = s.Rename(ctx, srcObj, dstName)
+		newObj, err = limitCallResult(ctx, storage, "rename", func(ctx context.Context) (model.Obj, error) {
+			return s.Rename(ctx, srcObj, dstName)
+		})
 		if err == nil {
 			srcDirPath := stdpath.Dir(srcPath)
 			if newObj != nil {
@@ -393,7 +426,9 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 			}
 		}
 	case driver.Rename:
-		err = s.Rename(ctx, srcObj, dstName)
+		err = limitCall(ctx, storage, "rename", func(ctx context.Context) error {
+			return s.Rename(ctx, srcObj, dstName)
+		})
 		if err == nil {
 			srcDirPath := stdpath.Dir(srcPath)
 			Cache.removeDirectoryObject(storage, srcDirPath, srcRawObj)
@@ -404,6 +439,9 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		publishFsEvent(ctx, storage, FsRenamed, stdpath.Join(stdpath.Dir(srcPath), dstName), srcPath, srcObj)
+	}
 	return errors.WithStack(err)
 }
 
@@ -430,7 +468,9 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	switch s := storage.(type) {
 	case driver.CopyResult:
 		var newObj model.Obj
-		newObj, err = s.Copy(ctx, srcObj, dstDir)
+		newObj, err = limitCallResult(ctx, storage, "copy", func(ctx context.Context) (model.Obj, error) {
+			return s.Copy(ctx, srcObj, dstDir)
+		})
 		if err == nil {
 			if newObj != nil {
 				Cache.addDirectoryObject(storage, dstDirPath, model.WrapObjName(newObj))
@@ -439,7 +479,9 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 			}
 		}
 	case driver.Copy:
-		err = s.Copy(ctx, srcObj, dstDir)
+		err = limitCall(ctx, storage, "copy", func(ctx context.Context) error {
+			return s.Copy(ctx, srcObj, dstDir)
+		})
 		if err == nil {
 			if !utils.IsBool(lazyCache...) {
 				Cache.DeleteDirectory(storage, dstDirPath)
@@ -448,6 +490,9 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	default:
 		err = errs.NotImplement
 	}
+	if err == nil {
+		publishFsEvent(ctx, storage, FsCreated, stdpath.Join(dstDirPath, srcObj.GetName()), "", srcObj)
+	}
 
 	if !utils.IsBool(lazyCache...) && err == nil && needHandleObjsUpdateHook() {
 		if !srcObj.IsDir() {
@@ -488,9 +533,12 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 
 	switch s := storage.(type) {
 	case driver.Remove:
-		err = s.Remove(ctx, model.UnwrapObj(rawObj))
+		err = limitCall(ctx, storage, "remove", func(ctx context.Context) error {
+			return s.Remove(ctx, model.UnwrapObj(rawObj))
+		})
 		if err == nil {
 			Cache.removeDirectoryObject(storage, dirPath, rawObj)
+			publishFsEvent(ctx, storage, FsDeleted, path, "", rawObj)
 		}
 	default:
 		return errs.NotImplement
@@ -498,6 +546,34 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 	return errors.WithStack(err)
 }
 
+type resumablePutKey struct{}
+
+// PartsDir is the hidden folder at the root of each storage where PutResume may stage the uploads
+const PartsDir = "/.openlist_parts"
+
+// WithResumablePut makes Put use PutResume on the storages which have it, resume tells
+// to carry on the interrupted upload of the same file rather than start over
+func WithResumablePut(ctx context.Context, resume bool) context.Context {
+	return context.WithValue(ctx, resumablePutKey{}, resume)
+}
+
+// DiscardResumablePut removes what the interrupted resumable upload of the file kept,
+// it does nothing on the storages which can't carry on uploads
+func DiscardResumablePut(ctx context.Context, storage driver.Driver, dstDirPath, name string) error {
+	s, ok := storage.(driver.PutResume)
+	if !ok {
+		return nil
+	}
+	dstDir, err := GetUnwrap(ctx, storage, dstDirPath)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			return nil
+		}
+		return errors.WithMessagef(err, "failed to get dir [%s]", dstDirPath)
+	}
+	return errors.WithStack(s.DiscardResume(ctx, dstDir, name))
+}
+
 func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress, lazyCache ...bool) error {
 	close := file.Close
 	defer func() {
@@ -522,13 +598,13 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	fi, err := GetUnwrap(ctx, storage, dstPath)
 	if err == nil {
 		if fi.GetSize() == 0 {
-			err = Remove(ctx, storage, dstPath)
+			err = Remove(withoutFsEvents(ctx), storage, dstPath)
 			if err != nil {
 				return errors.WithMessagef(err, "while uploading, failed remove existing file which size = 0")
 			}
 		} else if storage.Config().NoOverwriteUpload {
 			// try to rename old obj
-			err = Rename(ctx, storage, dstPath, tempName)
+			err = Rename(withoutFsEvents(ctx), storage, dstPath, tempName)
 			if err != nil {
 				return err
 			}
@@ -555,42 +631,66 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		log.Warnf("file size < 0, try to get full size from cache")
 		file.CacheFullAndWriter(nil, nil)
 	}
-	switch s := storage.(type) {
-	case driver.PutResult:
-		var newObj model.Obj
-		newObj, err = s.Put(ctx, parentDir, file, up)
+	resume, resumable := ctx.Value(resumablePutKey{}).(bool)
+	if s, ok := storage.(driver.PutResume); ok && resumable {
+		err = limitCall(ctx, storage, "put", func(ctx context.Context) error {
+			return s.PutResume(ctx, parentDir, file, resume, up)
+		})
 		if err == nil {
 			Cache.linkCache.DeleteKey(Key(storage, dstPath))
-			if newObj != nil {
-				Cache.addDirectoryObject(storage, dstDirPath, model.WrapObjName(newObj))
-			} else if !utils.IsBool(lazyCache...) {
+			if !utils.IsBool(lazyCache...) {
 				Cache.DeleteDirectory(storage, dstDirPath)
 			}
 		}
-	case driver.Put:
-		err = s.Put(ctx, parentDir, file, up)
-		if err == nil {
-			Cache.linkCache.DeleteKey(Key(storage, dstPath))
-			if !utils.IsBool(lazyCache...) {
-				Cache.DeleteDirectory(storage, dstDirPath)
+	} else {
+		switch s := storage.(type) {
+		case driver.PutResult:
+			var newObj model.Obj
+			newObj, err = limitCallResult(ctx, storage, "put", func(ctx context.Context) (model.Obj, error) {
+				return s.Put(ctx, parentDir, file, up)
+			})
+			if err == nil {
+				Cache.linkCache.DeleteKey(Key(storage, dstPath))
+				if newObj != nil {
+					Cache.addDirectoryObject(storage, dstDirPath, model.WrapObjName(newObj))
+				} else if !utils.IsBool(lazyCache...) {
+					Cache.DeleteDirectory(storage, dstDirPath)
+				}
+			}
+		case driver.Put:
+			err = limitCall(ctx, storage, "put", func(ctx context.Context) error {
+				return s.Put(ctx, parentDir, file, up)
+			})
+			if err == nil {
+				Cache.linkCache.DeleteKey(Key(storage, dstPath))
+				if !utils.IsBool(lazyCache...) {
+					Cache.DeleteDirectory(storage, dstDirPath)
+				}
 			}
+		default:
+			return errs.NotImplement
 		}
-	default:
-		return errs.NotImplement
 	}
 	log.Debugf("put file [%s] done", file.GetName())
 	if storage.Config().NoOverwriteUpload && fi != nil && fi.GetSize() > 0 {
 		if err != nil {
 			// upload failed, recover old obj
-			err := Rename(ctx, storage, tempPath, file.GetName())
+			err := Rename(withoutFsEvents(ctx), storage, tempPath, file.GetName())
 			if err != nil {
 				log.Errorf("failed recover old obj: %+v", err)
 			}
 		} else {
 			// upload success, remove old obj
-			err = Remove(ctx, storage, tempPath)
+			err = Remove(withoutFsEvents(ctx), storage, tempPath)
 		}
 	}
+	if err == nil {
+		typ := FsCreated
+		if fi != nil {
+			typ = FsUpdated
+		}
+		publishFsEvent(ctx, storage, typ, dstPath, "", file)
+	}
 	if !utils.IsBool(lazyCache...) && err == nil && needHandleObjsUpdateHook() {
 		go List(context.Background(), storage, dstDirPath, model.ListArgs{Refresh: true})
 	}
@@ -618,7 +718,9 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	switch s := storage.(type) {
 	case driver.PutURLResult:
 		var newObj model.Obj
-		newObj, err = s.PutURL(ctx, dstDir, dstName, url)
+		newObj, err = limitCallResult(ctx, storage, "put_url", func(ctx context.Context) (model.Obj, error) {
+			return s.PutURL(ctx, dstDir, dstName, url)
+		})
 		if err == nil {
 			Cache.linkCache.DeleteKey(Key(storage, dstPath))
 			if newObj != nil {
@@ -628,7 +730,9 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 			}
 		}
 	case driver.PutURL:
-		err = s.PutURL(ctx, dstDir, dstName, url)
+		err = limitCall(ctx, storage, "put_url", func(ctx context.Context) error {
+			return s.PutURL(ctx, dstDir, dstName, url)
+		})
 		if err == nil {
 			Cache.linkCache.DeleteKey(Key(storage, dstPath))
 			if !utils.IsBool(lazyCache...) {
@@ -638,6 +742,9 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	default:
 		return errors.WithStack(errs.NotImplement)
 	}
+	if err == nil {
+		publishFsEvent(ctx, storage, FsCreated, dstPath, "", &model.Object{Name: dstName, Modified: time.Now()})
+	}
 	if !utils.IsBool(lazyCache...) && err == nil && needHandleObjsUpdateHook() {
 		go List(context.Background(), storage, dstDirPath, model.ListArgs{Refresh: true})
 	}
@@ -678,7 +785,9 @@ func GetDirectUploadInfo(ctx context.Context, tool string, storage driver.Driver
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to get dir [%s]", dstDirPath)
 	}
-	info, err := du.GetDirectUploadInfo(ctx, tool, dstDir, dstName, fileSize)
+	info, err := limitCallResult(ctx, storage, "direct_upload_info", func(ctx context.Context) (any, error) {
+		return du.GetDirectUploadInfo(ctx, tool, dstDir, dstName, fileSize)
+	})
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
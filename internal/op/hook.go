@@ -4,6 +4,7 @@ import (
 	"context"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
@@ -110,3 +111,45 @@ func callStorageHooks(typ string, storage driver.Driver) {
 func RegisterStorageHook(hook StorageHook) {
 	storageHooks = append(storageHooks, hook)
 }
+
+// StorageStatusHook is called when the status of a storage changes, oldStatus
+// is empty the first time the status is saved
+type StorageStatusHook func(storage driver.Driver, oldStatus string)
+
+var (
+	storageStatusHooks = make([]StorageStatusHook, 0)
+	storageStatuses    sync.Map // the last saved status by storage id
+)
+
+// callStorageStatusHooks is called when the storage is saved. A storage which starts
+// working for the first time is not seen as a change.
+func callStorageStatusHooks(storage driver.Driver) {
+	s := storage.GetStorage()
+	old, loaded := storageStatuses.Swap(s.ID, s.Status)
+	oldStatus, _ := old.(string)
+	if oldStatus == s.Status || !loaded && s.Status == WORK {
+		return
+	}
+	for _, hook := range storageStatusHooks {
+		hook(storage, oldStatus)
+	}
+}
+
+func RegisterStorageStatusHook(hook StorageStatusHook) {
+	storageStatusHooks = append(storageStatusHooks, hook)
+}
+
+// SharingDeleteHook is called after a sharing is deleted
+type SharingDeleteHook func(sid string)
+
+var sharingDeleteHooks = make([]SharingDeleteHook, 0)
+
+func callSharingDeleteHooks(sid string) {
+	for _, hook := range sharingDeleteHooks {
+		hook(sid)
+	}
+}
+
+func RegisterSharingDeleteHook(hook SharingDeleteHook) {
+	sharingDeleteHooks = append(sharingDeleteHooks, hook)
+}
@@ -148,15 +148,23 @@ func listSystemLogs(filter LogFilter) ([]model.SystemLog, int64, error) {
 	return logs, total, nil
 }
 
-func DeleteLogs(kind string, ids []uint) error {
-	var modelType interface{}
+func logModel(kind string) interface{} {
 	switch kind {
 	case "upload":
-		modelType = &model.UploadLog{}
+		return &model.UploadLog{}
 	case "system":
-		modelType = &model.SystemLog{}
+		return &model.SystemLog{}
 	default:
-		modelType = &model.LoginLog{}
+		return &model.LoginLog{}
 	}
-	return db.GetDb().Where("id IN ?", ids).Delete(modelType).Error
+}
+
+func DeleteLogs(kind string, ids []uint) error {
+	return db.GetDb().Where("id IN ?", ids).Delete(logModel(kind)).Error
+}
+
+// DeleteLogsBefore deletes the logs of the kind created before the time and returns how many were deleted
+func DeleteLogsBefore(kind string, before time.Time) (int64, error) {
+	res := db.GetDb().Where("created_at < ?", before).Delete(logModel(kind))
+	return res.RowsAffected, res.Error
 }
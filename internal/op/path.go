@@ -1,6 +1,7 @@
 package op
 
 import (
+	"context"
 	stdpath "path"
 	"strings"
 
@@ -30,6 +31,17 @@ func GetStorageAndActualPath(rawPath string) (storage driver.Driver, actualPath
 	return
 }
 
+// Identify returns a key of the object at the actual path of the storage, which is the same for
+// the object reached through the storages overlapping each other, see driver.Identifier
+func Identify(ctx context.Context, storage driver.Driver, actualPath string) string {
+	if i, ok := storage.(driver.Identifier); ok {
+		if id, err := i.Identify(ctx, actualPath); err == nil {
+			return id
+		}
+	}
+	return stdpath.Join(utils.GetActualMountPath(storage.GetStorage().MountPath), actualPath)
+}
+
 // urlTreeSplitLineFormPath 分割path中分割真实路径和UrlTree定义字符串
 func urlTreeSplitLineFormPath(path string) (pp string, file string) {
 	// url.PathUnescape 会移除 // ，手动加回去
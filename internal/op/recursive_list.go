@@ -24,7 +24,9 @@ func ManualScanRunning() bool {
 	return ManualScanCancel.Load() != nil
 }
 
-func BeginManualScan(rawPath string, limit float64) error {
+// BeginManualScan refreshes the listings under the path in the background, which warms the cache
+// of the directories to the depth, 0 for all of them
+func BeginManualScan(rawPath string, limit float64, depth int) error {
 	rawPath = utils.FixAndCleanPath(rawPath)
 	ctx, cancel := context.WithCancel(context.Background())
 	if !ManualScanCancel.CompareAndSwap(nil, &cancel) {
@@ -34,7 +36,7 @@ func BeginManualScan(rawPath string, limit float64) error {
 	ScannedCount.Store(0)
 	go func() {
 		defer func() { (*ManualScanCancel.Swap(nil))() }()
-		err := RecursivelyList(ctx, rawPath, rate.Limit(limit), &ScannedCount)
+		err := RecursivelyList(ctx, rawPath, depth, rate.Limit(limit), &ScannedCount)
 		if err != nil {
 			log.Errorf("failed recursively list: %v", err)
 		}
@@ -49,7 +51,9 @@ func StopManualScan() {
 	}
 }
 
-func RecursivelyList(ctx context.Context, rawPath string, limit rate.Limit, counter *atomic.Uint64) error {
+// RecursivelyList lists the directories under the path to the depth, the path itself is at depth 1
+// and 0 lists all of them
+func RecursivelyList(ctx context.Context, rawPath string, depth int, limit rate.Limit, counter *atomic.Uint64) error {
 	storage, actualPath, err := GetStorageAndActualPath(rawPath)
 	if err != nil && !errors.Is(err, errs.StorageNotFound) {
 		return err
@@ -58,20 +62,24 @@ func RecursivelyList(ctx context.Context, rawPath string, limit rate.Limit, coun
 		if limit > .0 {
 			limiter = rate.NewLimiter(limit, 1)
 		}
-		RecursivelyListStorage(ctx, storage, actualPath, limiter, counter)
+		recursivelyListStorage(ctx, storage, actualPath, depth, limiter, counter)
 	} else {
 		var wg sync.WaitGroup
-		recursivelyListVirtual(ctx, rawPath, limit, counter, &wg)
+		recursivelyListVirtual(ctx, rawPath, depth, limit, counter, &wg)
 		wg.Wait()
 	}
 	return nil
 }
 
-func recursivelyListVirtual(ctx context.Context, rawPath string, limit rate.Limit, counter *atomic.Uint64, wg *sync.WaitGroup) {
+func recursivelyListVirtual(ctx context.Context, rawPath string, depth int, limit rate.Limit, counter *atomic.Uint64, wg *sync.WaitGroup) {
 	objs := GetStorageVirtualFilesByPath(rawPath)
 	if counter != nil {
 		counter.Add(uint64(len(objs)))
 	}
+	if depth == 1 {
+		return
+	}
+	next := nextDepth(depth)
 	for _, obj := range objs {
 		if utils.IsCanceled(ctx) {
 			return
@@ -88,15 +96,26 @@ func recursivelyListVirtual(ctx context.Context, rawPath string, limit rate.Limi
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				RecursivelyListStorage(ctx, storage, actualPath, limiter, counter)
+				recursivelyListStorage(ctx, storage, actualPath, next, limiter, counter)
 			}()
 		} else {
-			recursivelyListVirtual(ctx, nextPath, limit, counter, wg)
+			recursivelyListVirtual(ctx, nextPath, next, limit, counter, wg)
 		}
 	}
 }
 
 func RecursivelyListStorage(ctx context.Context, storage driver.Driver, actualPath string, limiter *rate.Limiter, counter *atomic.Uint64) {
+	recursivelyListStorage(ctx, storage, actualPath, 0, limiter, counter)
+}
+
+func nextDepth(depth int) int {
+	if depth > 0 {
+		return depth - 1
+	}
+	return depth
+}
+
+func recursivelyListStorage(ctx context.Context, storage driver.Driver, actualPath string, depth int, limiter *rate.Limiter, counter *atomic.Uint64) {
 	objs, err := List(ctx, storage, actualPath, model.ListArgs{Refresh: true})
 	if err != nil {
 		if !errors.Is(err, context.Canceled) {
@@ -107,6 +126,9 @@ func RecursivelyListStorage(ctx context.Context, storage driver.Driver, actualPa
 	if counter != nil {
 		counter.Add(uint64(len(objs)))
 	}
+	if depth == 1 {
+		return
+	}
 	for _, obj := range objs {
 		if utils.IsCanceled(ctx) {
 			return
@@ -120,6 +142,6 @@ func RecursivelyListStorage(ctx context.Context, storage driver.Driver, actualPa
 			}
 		}
 		nextPath := stdpath.Join(actualPath, obj.GetName())
-		RecursivelyListStorage(ctx, storage, nextPath, limiter, counter)
+		recursivelyListStorage(ctx, storage, nextPath, nextDepth(depth), limiter, counter)
 	}
 }
@@ -0,0 +1,32 @@
+package op_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestRecursivelyListDepth(t *testing.T) {
+	_, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:          "Virtual",
+		MountPath:       "/warm/virtual",
+		CacheExpiration: 10,
+		Addition:        `{"num_file":1,"num_folder":2,"max_file_size":10,"min_file_size":1}`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	// /warm holds the storage, and the virtual folders go on forever with 2 folders and a file in each
+	for depth, want := range map[int]uint64{1: 1, 2: 1 + 3, 3: 1 + 3 + 2*3} {
+		var counter atomic.Uint64
+		if err = op.RecursivelyList(context.Background(), "/warm", depth, 0, &counter); err != nil {
+			t.Fatal(err)
+		}
+		if counter.Load() != want {
+			t.Errorf("expect %d objects listed to depth %d, got %d", want, depth, counter.Load())
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// RecycleBinDir is the hidden folder at the root of each storage holding the recycled objects
+const RecycleBinDir = "/.openlist_trash"
+
+// CanRecycle reports whether the object at path can be moved into the recycle bin
+// of the storage, which needs both Move and MakeDir
+func CanRecycle(storage driver.Driver, path string) bool {
+	if storage.GetStorage().DisableRecycleBin || storage.Config().OnlyIndices {
+		return false
+	}
+	if utils.IsSubPath(RecycleBinDir, path) {
+		return false
+	}
+	return canMoveAside(storage)
+}
+
+// canMoveAside reports whether objects can be moved into a hidden folder of the storage
+func canMoveAside(storage driver.Driver) bool {
+	switch storage.(type) {
+	case driver.Move, driver.MoveResult:
+	default:
+		return false
+	}
+	switch storage.(type) {
+	case driver.Mkdir, driver.MkdirResult:
+	default:
+		return false
+	}
+	return true
+}
+
+// Recycle moves the object into a folder of its own in the recycle bin and records it
+func Recycle(ctx context.Context, storage driver.Driver, path string, user *model.User) error {
+	path = utils.FixAndCleanPath(path)
+	if utils.PathEqual(path, "/") {
+		return errors.New("delete root folder is not allowed, please goto the manage page to delete the storage instead")
+	}
+	obj, err := Get(ctx, storage, path)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			log.Debugf("%s have been removed", path)
+			return nil
+		}
+		return errors.WithMessage(err, "failed to get object")
+	}
+	trashDir := stdpath.Join(RecycleBinDir, fmt.Sprintf("%d_%s", time.Now().UnixMilli(), random.String(6)))
+	if err = MakeDir(ctx, storage, trashDir); err != nil {
+		return errors.WithMessage(err, "failed to make recycle bin dir")
+	}
+	if err = Move(ctx, storage, path, trashDir); err != nil {
+		_ = Remove(ctx, storage, trashDir)
+		return errors.WithMessage(err, "failed to move object into recycle bin")
+	}
+	item := &model.RecycleItem{
+		StorageID:  storage.GetStorage().ID,
+		Path:       stdpath.Join(storage.GetStorage().MountPath, path),
+		ActualPath: path,
+		TrashDir:   trashDir,
+		Name:       obj.GetName(),
+		Size:       obj.GetSize(),
+		IsDir:      obj.IsDir(),
+		DeletedAt:  time.Now(),
+	}
+	if user != nil {
+		item.UserID = user.ID
+		item.Username = user.Username
+	}
+	return db.CreateRecycleItem(item)
+}
+
+func GetRecycleItems(userId uint, pageIndex, pageSize int) ([]model.RecycleItem, int64, error) {
+	return db.GetRecycleItems(userId, pageIndex, pageSize)
+}
+
+func GetRecycleItemById(id uint) (*model.RecycleItem, error) {
+	return db.GetRecycleItemById(id)
+}
+
+// getStorageById returns the mounted storage of a record kept in a hidden folder
+func getStorageById(id uint) (driver.Driver, error) {
+	s, err := db.GetStorageById(id)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get storage")
+	}
+	return GetStorageByMountPath(s.MountPath)
+}
+
+// RestoreRecycleItem moves the object back to its original path, which must be free
+func RestoreRecycleItem(ctx context.Context, item *model.RecycleItem) error {
+	storage, err := getStorageById(item.StorageID)
+	if err != nil {
+		return err
+	}
+	actualPath := item.ActualPath
+	if stdpath.Base(actualPath) != item.Name {
+		return errors.New("the recycled object doesn't match its original path")
+	}
+	if _, err = Get(ctx, storage, actualPath); err == nil {
+		return errors.Errorf("[%s] already exists", item.Path)
+	} else if !errs.IsObjectNotFound(err) {
+		return errors.WithMessage(err, "failed to check original path")
+	}
+	dstDir := stdpath.Dir(actualPath)
+	if err = MakeDir(ctx, storage, dstDir); err != nil {
+		return errors.WithMessage(err, "failed to make original dir")
+	}
+	if err = Move(ctx, storage, stdpath.Join(item.TrashDir, item.Name), dstDir); err != nil {
+		return errors.WithMessage(err, "failed to move object out of recycle bin")
+	}
+	if err = Remove(ctx, storage, item.TrashDir); err != nil {
+		log.Warnf("failed to remove recycle bin dir %s: %+v", item.TrashDir, err)
+	}
+	return db.DeleteRecycleItemById(item.ID)
+}
+
+// PurgeRecycleItem deletes the object for good. The record is dropped as well
+// when the storage is gone, as there is nothing left to restore.
+func PurgeRecycleItem(ctx context.Context, item *model.RecycleItem) error {
+	storage, err := getStorageById(item.StorageID)
+	if err == nil {
+		if err = Remove(ctx, storage, item.TrashDir); err != nil {
+			return errors.WithMessage(err, "failed to purge recycled object")
+		}
+	} else {
+		log.Warnf("purge recycle item %d without storage: %v", item.ID, err)
+	}
+	return db.DeleteRecycleItemById(item.ID)
+}
+
+// PurgeExpiredRecycleItems deletes the objects kept longer than the retention days
+func PurgeExpiredRecycleItems(ctx context.Context) {
+	days := getSettingInt(conf.RecycleBinRetentionDays)
+	if days <= 0 {
+		return
+	}
+	items, err := db.GetRecycleItemsBefore(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Errorf("failed to get expired recycle items: %+v", err)
+		return
+	}
+	for i := range items {
+		if err = PurgeRecycleItem(ctx, &items[i]); err != nil {
+			log.Warnf("failed to purge expired recycle item %s: %+v", items[i].Path, err)
+		}
+	}
+	if len(items) > 0 {
+		log.Infof("purged %d expired recycle items", len(items))
+	}
+}
+
+var recycleCron *cron.Cron
+
+func StartRecycleBinPurgeScheduler() {
+	if recycleCron != nil {
+		return
+	}
+	recycleCron = cron.NewCron(time.Hour)
+	recycleCron.Do(func() {
+		PurgeExpiredRecycleItems(context.Background())
+	})
+}
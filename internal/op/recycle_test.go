@@ -0,0 +1,60 @@
+package op_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestRecycleAndRestore(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "dir", "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/recycle",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/recycle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !op.CanRecycle(storage, "/dir/a.txt") || op.CanRecycle(storage, op.RecycleBinDir+"/x") {
+		t.Fatalf("unexpected CanRecycle result")
+	}
+	if err = op.Recycle(ctx, storage, "/dir/a.txt", &model.User{ID: 7, Username: "alice"}); err != nil {
+		t.Fatalf("failed to recycle: %+v", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "dir", "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expect file to be moved away, got %v", err)
+	}
+	items, total, err := op.GetRecycleItems(7, 1, 10)
+	if err != nil || total != 1 {
+		t.Fatalf("expect 1 recycle item, got %d %v", total, err)
+	}
+	if items[0].Path != "/recycle/dir/a.txt" || items[0].Size != 5 {
+		t.Errorf("unexpected recycle item: %+v", items[0])
+	}
+	if err = op.RestoreRecycleItem(ctx, &items[0]); err != nil {
+		t.Fatalf("failed to restore: %+v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(root, "dir", "a.txt")); err != nil || string(content) != "hello" {
+		t.Errorf("expect file to be restored, got %q %v", content, err)
+	}
+	if _, total, _ = op.GetRecycleItems(7, 1, 10); total != 0 {
+		t.Errorf("expect recycle item to be removed after restore")
+	}
+}
@@ -0,0 +1,81 @@
+package op
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// the directories listed from the cache this many times in the refresh window are often accessed
+	refreshAheadHits = 3
+	// the directories are refreshed in the last fifth of their cache lifetime
+	refreshAheadWindow = 5
+)
+
+var (
+	refreshingMu sync.Mutex
+	refreshing   = make(map[string]struct{})
+
+	refreshAheadLimiter = rate.NewLimiter(rate.Inf, 1)
+)
+
+// allowRefreshAhead reports whether the rate limit allows a refresh now
+func allowRefreshAhead() bool {
+	limit := rate.Inf
+	if item, _ := GetSettingItemByKey(conf.RefreshAheadRateLimit); item != nil {
+		if f, err := strconv.ParseFloat(item.Value, 64); err == nil && f > .0 {
+			limit = rate.Limit(f)
+		}
+	}
+	if refreshAheadLimiter.Limit() != limit {
+		refreshAheadLimiter.SetLimit(limit)
+	}
+	return refreshAheadLimiter.Allow()
+}
+
+// refreshAhead counts a listing from the cache, and refreshes the directory in the background
+// if the storage enables it, its cache is about to expire and it's often accessed in the meantime.
+// Only the listings in the refresh window count, the refreshed listing counts from zero again.
+// The refreshes over the rate limit are skipped, the next access tries again.
+func refreshAhead(storage driver.Driver, path, key string, dirCache *directoryCache) {
+	s := storage.GetStorage()
+	if !s.RefreshAhead || storage.Config().NoCache {
+		return
+	}
+	ttl := time.Minute * time.Duration(s.CacheExpiration)
+	if ttl <= 0 || time.Until(dirCache.expiry()) > ttl/refreshAheadWindow {
+		return
+	}
+	if dirCache.hits.Add(1) < refreshAheadHits {
+		return
+	}
+	refreshingMu.Lock()
+	if _, ok := refreshing[key]; ok {
+		refreshingMu.Unlock()
+		return
+	}
+	refreshing[key] = struct{}{}
+	refreshingMu.Unlock()
+	go func() {
+		defer func() {
+			refreshingMu.Lock()
+			delete(refreshing, key)
+			refreshingMu.Unlock()
+		}()
+		if !allowRefreshAhead() {
+			return
+		}
+		log.Debugf("refresh ahead %s", key)
+		if _, err := List(context.Background(), storage, path, model.ListArgs{Refresh: true}); err != nil {
+			log.Warnf("failed refresh ahead %s: %+v", key, err)
+		}
+	}()
+}
@@ -0,0 +1,61 @@
+package op
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestRefreshAhead(t *testing.T) {
+	ctx := context.Background()
+	_, err := CreateStorage(ctx, model.Storage{
+		Driver:          "Virtual",
+		MountPath:       "/refresh_ahead",
+		CacheExpiration: 1,
+		RefreshAhead:    true,
+		Addition:        `{"num_file":1,"num_folder":1,"max_file_size":10,"min_file_size":1}`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := GetStorageByMountPath("/refresh_ahead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = List(ctx, storage, "/", model.ListArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	key := Key(storage, "/")
+	cached, _ := Cache.dirCache.Get(key)
+
+	// far from the expiry
+	for i := 0; i < refreshAheadHits; i++ {
+		_, _ = List(ctx, storage, "/", model.ListArgs{})
+	}
+	time.Sleep(20 * time.Millisecond)
+	if cur, _ := Cache.dirCache.Get(key); cur != cached {
+		t.Fatalf("expect the directory not to be refreshed long before it expires")
+	}
+
+	// the listings before the refresh window don't count
+	cached.mu.Lock()
+	cached.expires = time.Now().Add(5 * time.Second)
+	cached.mu.Unlock()
+	for i := 0; i < refreshAheadHits-1; i++ {
+		_, _ = List(ctx, storage, "/", model.ListArgs{})
+	}
+	time.Sleep(20 * time.Millisecond)
+	if cur, _ := Cache.dirCache.Get(key); cur != cached {
+		t.Fatalf("expect the directory not to be refreshed before it's often accessed in the window")
+	}
+
+	_, _ = List(ctx, storage, "/", model.ListArgs{})
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		if cur, ok := Cache.dirCache.Get(key); ok && cur != cached {
+			return
+		}
+	}
+	t.Errorf("expect the often accessed directory about to expire to be refreshed")
+}
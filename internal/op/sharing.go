@@ -4,8 +4,10 @@ import (
 	"fmt"
 	stdpath "path"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -87,7 +89,17 @@ func GetSharingsByCreatorId(userId uint, pageIndex, pageSize int) ([]model.Shari
 	return makeJoined(s), cnt, nil
 }
 
+// GetSharingUnwrapPath returns the full path of the path in the sharing, the hidden folders
+// of the storages are not found in the sharings
 func GetSharingUnwrapPath(sharing *model.Sharing, path string) (unwrapPath string, err error) {
+	unwrapPath, err = getSharingUnwrapPath(sharing, path)
+	if err == nil && IsHiddenPath(unwrapPath) {
+		return "", errors.WithStack(errs.ObjectNotFound)
+	}
+	return unwrapPath, err
+}
+
+func getSharingUnwrapPath(sharing *model.Sharing, path string) (unwrapPath string, err error) {
 	if len(sharing.Files) == 0 {
 		return "", errors.New("cannot get actual path of an invalid sharing")
 	}
@@ -133,11 +145,91 @@ func UpdateSharing(sharing *model.Sharing, skipMarshal ...bool) (err error) {
 	return db.UpdateSharing(sharing.SharingDB)
 }
 
+func UpdateSharingTraffic(sharing *model.Sharing) error {
+	sharingCache.Del(sharing.ID)
+	return db.UpdateSharingTraffic(sharing.ID, sharing.Traffic, sharing.DailyTraffic, sharing.TrafficDate)
+}
+
+// ReserveSharingUpload returns false if the sharing has not enough space left for size
+func ReserveSharingUpload(sharing *model.Sharing, size int64) (bool, error) {
+	sharingCache.Del(sharing.ID)
+	ok, err := db.ReserveSharingUpload(sharing.ID, size)
+	if ok {
+		sharing.Uploaded += size
+	}
+	return ok, err
+}
+
+func ReleaseSharingUpload(sharing *model.Sharing, size int64) error {
+	sharingCache.Del(sharing.ID)
+	sharing.Uploaded -= size
+	return db.ReleaseSharingUpload(sharing.ID, size)
+}
+
 func DeleteSharing(sid string) error {
 	sharingCache.Del(sid)
-	return db.DeleteSharingById(sid)
+	if err := db.DeleteSharingAccessLogs(sid); err != nil {
+		log.Warnf("failed delete access logs of sharing [%s]: %+v", sid, err)
+	}
+	if err := db.DeleteSharingById(sid); err != nil {
+		return err
+	}
+	callSharingDeleteHooks(sid)
+	return nil
 }
 
 func DeleteSharingsByCreatorId(creatorId uint) error {
-	return db.DeleteSharingsByCreatorId(creatorId)
+	ids, err := db.GetSharingIdsByCreatorId(creatorId)
+	if err != nil {
+		return err
+	}
+	if err = db.DeleteSharingAccessLogsByCreatorId(creatorId); err != nil {
+		log.Warnf("failed delete sharing access logs of user [%d]: %+v", creatorId, err)
+	}
+	if err = db.DeleteSharingsByCreatorId(creatorId); err != nil {
+		return err
+	}
+	for _, sid := range ids {
+		sharingCache.Del(sid)
+		callSharingDeleteHooks(sid)
+	}
+	return nil
+}
+
+func CreateSharingAccessLog(l *model.SharingAccessLog) error {
+	return db.CreateSharingAccessLog(l)
+}
+
+func GetSharingAccessLogs(sid string, pageIndex, pageSize int) ([]model.SharingAccessLog, int64, error) {
+	return db.GetSharingAccessLogs(sid, pageIndex, pageSize)
+}
+
+// GetSharingTimeline aggregates the access logs of a sharing per day, in server local time
+func GetSharingTimeline(sid string, since time.Time) ([]model.SharingTimelineItem, error) {
+	logs, err := db.GetSharingAccessLogsSince(sid, since)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]model.SharingTimelineItem, 0)
+	for _, l := range logs {
+		date := l.CreatedAt.Local().Format(time.DateOnly)
+		if len(items) == 0 || items[len(items)-1].Date != date {
+			items = append(items, model.SharingTimelineItem{Date: date})
+		}
+		item := &items[len(items)-1]
+		switch l.Type {
+		case model.SharingLogAccess:
+			item.Accesses++
+		case model.SharingLogDownload, model.SharingLogRedirect:
+			item.Downloads++
+		case model.SharingLogUpload:
+			item.Uploads++
+		}
+		item.Bytes += l.Bytes
+	}
+	return items, nil
+}
+
+func GetSharingTopFiles(sid string, since time.Time, limit int) ([]model.SharingTopFile, error) {
+	return db.GetSharingTopFiles(sid, since, limit)
 }
@@ -0,0 +1,76 @@
+package op_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestSharingStats(t *testing.T) {
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	logs := []model.SharingAccessLog{
+		{Type: model.SharingLogAccess, Path: "/", CreatedAt: yesterday},
+		{Type: model.SharingLogDownload, Path: "/a.txt", Bytes: 10, CreatedAt: yesterday},
+		{Type: model.SharingLogDownload, Path: "/a.txt", Bytes: 5, CreatedAt: now},
+		{Type: model.SharingLogRedirect, Path: "/b.txt", CreatedAt: now},
+		{Type: model.SharingLogAccess, Path: "/", CreatedAt: now.AddDate(0, 0, -40)},
+	}
+	for i := range logs {
+		logs[i].SharingID = "stats"
+		if err := op.CreateSharingAccessLog(&logs[i]); err != nil {
+			t.Fatalf("failed to create access log: %+v", err)
+		}
+	}
+	since := now.AddDate(0, 0, -30)
+	timeline, err := op.GetSharingTimeline("stats", since)
+	if err != nil {
+		t.Fatalf("failed to get timeline: %+v", err)
+	}
+	if len(timeline) != 2 {
+		t.Fatalf("expect 2 days, got %+v", timeline)
+	}
+	if d := timeline[0]; d.Accesses != 1 || d.Downloads != 1 || d.Bytes != 10 {
+		t.Errorf("unexpected first day: %+v", d)
+	}
+	if d := timeline[1]; d.Accesses != 0 || d.Downloads != 2 || d.Bytes != 5 {
+		t.Errorf("unexpected second day: %+v", d)
+	}
+	top, err := op.GetSharingTopFiles("stats", since, 10)
+	if err != nil {
+		t.Fatalf("failed to get top files: %+v", err)
+	}
+	if len(top) != 2 || top[0].Path != "/a.txt" || top[0].Downloads != 2 || top[0].Bytes != 15 {
+		t.Errorf("unexpected top files: %+v", top)
+	}
+}
+
+func TestUpdateSharingKeepsTraffic(t *testing.T) {
+	s := &model.Sharing{
+		SharingDB: &model.SharingDB{ID: "traffic"},
+		Files:     []string{"/a"},
+		Creator:   &model.User{ID: 1},
+	}
+	if _, err := op.CreateSharing(s); err != nil {
+		t.Fatalf("failed to create sharing: %+v", err)
+	}
+	stale := *s.SharingDB
+	s.Traffic, s.DailyTraffic, s.TrafficDate = 100, 10, "2024-01-01"
+	if err := op.UpdateSharingTraffic(s); err != nil {
+		t.Fatalf("failed to update traffic: %+v", err)
+	}
+	stale.Accessed = 3
+	if err := op.UpdateSharing(&model.Sharing{SharingDB: &stale, Files: s.Files, Creator: s.Creator}); err != nil {
+		t.Fatalf("failed to update sharing: %+v", err)
+	}
+	got, err := db.GetSharingById("traffic")
+	if err != nil {
+		t.Fatalf("failed to get sharing: %+v", err)
+	}
+	if got.Accessed != 3 || got.Traffic != 100 || got.DailyTraffic != 10 {
+		t.Errorf("unexpected sharing after update: %+v", got)
+	}
+}
@@ -305,6 +305,7 @@ func saveDriverStorage(driver driver.Driver) error {
 	if err != nil {
 		return errors.WithMessage(err, "failed update storage in database")
 	}
+	callStorageStatusHooks(driver)
 	return nil
 }
 
@@ -466,7 +467,9 @@ func GetStorageDetails(ctx context.Context, storage driver.Driver, refresh ...bo
 		}
 	}
 	details, err, _ := detailsG.Do(storage.GetStorage().MountPath, func() (*model.StorageDetails, error) {
-		ret, err := wd.GetDetails(ctx)
+		ret, err := limitCallResult(ctx, storage, "details", func(ctx context.Context) (*model.StorageDetails, error) {
+			return wd.GetDetails(ctx)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,214 @@
+package op
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// storageLimiter enforces the APILimit of a storage and counts the calls to its driver
+type storageLimiter struct {
+	mu       sync.Mutex
+	limit    model.APILimit
+	rate     *rate.Limiter
+	slots    chan struct{}
+	calls    map[string]int64
+	queued   int64
+	canceled int64
+	waiting  int64
+	running  int64
+	waitTime time.Duration
+}
+
+var (
+	storageLimitersMu sync.Mutex
+	storageLimiters   = make(map[uint]*storageLimiter)
+)
+
+func getStorageLimiter(storage *model.Storage) *storageLimiter {
+	storageLimitersMu.Lock()
+	l, ok := storageLimiters[storage.ID]
+	if !ok {
+		l = &storageLimiter{calls: make(map[string]int64)}
+		storageLimiters[storage.ID] = l
+	}
+	storageLimitersMu.Unlock()
+	l.mu.Lock()
+	if !ok || l.limit != storage.APILimit {
+		l.setLimit(storage.APILimit)
+	}
+	l.mu.Unlock()
+	return l
+}
+
+// setLimit must be called with l.mu held, the calls running hold the slots they took
+func (l *storageLimiter) setLimit(limit model.APILimit) {
+	if limit.MaxRPS > 0 {
+		if l.rate == nil {
+			l.rate = rate.NewLimiter(rate.Limit(limit.MaxRPS), 1)
+		} else {
+			l.rate.SetLimit(rate.Limit(limit.MaxRPS))
+		}
+	} else {
+		l.rate = nil
+	}
+	if limit.MaxConcurrent > 0 {
+		if l.slots == nil || cap(l.slots) != limit.MaxConcurrent {
+			l.slots = make(chan struct{}, limit.MaxConcurrent)
+		}
+	} else {
+		l.slots = nil
+	}
+	l.limit = limit
+}
+
+// wait queues the call until a slot is free and the rate allows it
+func (l *storageLimiter) wait(ctx context.Context, slots chan struct{}, limiter *rate.Limiter) (queued bool, err error) {
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		default:
+			queued = true
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return queued, ctx.Err()
+			}
+		}
+	}
+	if limiter != nil && !limiter.Allow() {
+		queued = true
+		if err = limiter.Wait(ctx); err != nil {
+			if slots != nil {
+				<-slots
+			}
+			return queued, err
+		}
+	}
+	return queued, nil
+}
+
+// slotKey marks the context of a call holding a slot of the storage
+type slotKey struct {
+	storageID uint
+}
+
+// limitStorage waits for the API limit of the storage before a call to its driver,
+// the returned func must be called once the call is over. The driver is called with the
+// returned context, the calls the driver makes to its own storage with it don't wait for
+// another slot, which may never be free while the outer call holds one.
+func limitStorage(ctx context.Context, storage driver.Driver, call string) (context.Context, func(), error) {
+	l := getStorageLimiter(storage.GetStorage())
+	key := slotKey{storageID: storage.GetStorage().ID}
+	l.mu.Lock()
+	l.calls[call]++
+	l.waiting++
+	slots, limiter := l.slots, l.rate
+	l.mu.Unlock()
+	if ctx.Value(key) != nil {
+		slots = nil
+	}
+
+	start := time.Now()
+	queued, err := l.wait(ctx, slots, limiter)
+	l.mu.Lock()
+	l.waiting--
+	if queued {
+		l.queued++
+		l.waitTime += time.Since(start)
+	}
+	if err != nil {
+		l.canceled++
+	} else {
+		l.running++
+	}
+	l.mu.Unlock()
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed wait for the api limit of storage [%s]", storage.GetStorage().MountPath)
+	}
+	if slots != nil {
+		ctx = context.WithValue(ctx, key, struct{}{})
+	}
+	return ctx, func() {
+		if slots != nil {
+			<-slots
+		}
+		l.mu.Lock()
+		l.running--
+		l.mu.Unlock()
+	}, nil
+}
+
+type StorageAPIStats struct {
+	ID        uint   `json:"id"`
+	MountPath string `json:"mount_path"`
+	model.APILimit
+	// Calls counts the calls to the driver by method since the start
+	Calls map[string]int64 `json:"calls"`
+	// Queued counts the calls which waited for the limit, WaitSeconds is the time they waited
+	Queued      int64   `json:"queued"`
+	WaitSeconds float64 `json:"wait_seconds"`
+	// Canceled counts the calls given up while waiting
+	Canceled int64 `json:"canceled"`
+	Waiting  int64 `json:"waiting"`
+	Running  int64 `json:"running"`
+}
+
+// GetStorageAPIStats returns the API limits and the driver calls of the loaded storages
+func GetStorageAPIStats() []StorageAPIStats {
+	storages := GetAllStorages()
+	stats := make([]StorageAPIStats, 0, len(storages))
+	for _, s := range storages {
+		storage := s.GetStorage()
+		stat := StorageAPIStats{
+			ID:        storage.ID,
+			MountPath: storage.MountPath,
+			APILimit:  storage.APILimit,
+			Calls:     make(map[string]int64),
+		}
+		storageLimitersMu.Lock()
+		l, ok := storageLimiters[storage.ID]
+		storageLimitersMu.Unlock()
+		if ok {
+			l.mu.Lock()
+			for call, n := range l.calls {
+				stat.Calls[call] = n
+			}
+			stat.Queued = l.queued
+			stat.WaitSeconds = l.waitTime.Seconds()
+			stat.Canceled = l.canceled
+			stat.Waiting = l.waiting
+			stat.Running = l.running
+			l.mu.Unlock()
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// limitCall calls f, a call to the driver of the storage, within the API limit of the storage,
+// f must call the driver with the context it's given
+func limitCall(ctx context.Context, storage driver.Driver, call string, f func(ctx context.Context) error) error {
+	ctx, release, err := limitStorage(ctx, storage, call)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return f(ctx)
+}
+
+// limitCallResult is limitCall for the calls with a result
+func limitCallResult[T any](ctx context.Context, storage driver.Driver, call string, f func(ctx context.Context) (T, error)) (T, error) {
+	ctx, release, err := limitStorage(ctx, storage, call)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer release()
+	return f(ctx)
+}
@@ -0,0 +1,141 @@
+package op_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+)
+
+func apiStats(t *testing.T, mountPath string) op.StorageAPIStats {
+	for _, s := range op.GetStorageAPIStats() {
+		if s.MountPath == mountPath {
+			return s
+		}
+	}
+	t.Fatalf("no api stats of %s", mountPath)
+	return op.StorageAPIStats{}
+}
+
+func TestStorageAPILimit(t *testing.T) {
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/api_limit",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, t.TempDir()),
+		APILimit:  model.APILimit{MaxRPS: 50, MaxConcurrent: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/api_limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err = op.List(ctx, storage, "/", model.ListArgs{Refresh: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first call goes at once, each of the others waits for 20ms at least
+	if elapsed := time.Since(start); elapsed < 4*20*time.Millisecond {
+		t.Errorf("expect the calls to be throttled, took %s", elapsed)
+	}
+	stats := apiStats(t, "/api_limit")
+	if stats.Calls["list"] != 5 || stats.Queued == 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// the upload holds the only slot, the listing can't wait for it
+	pr, pw := io.Pipe()
+	done := make(chan error)
+	go func() {
+		done <- op.Put(ctx, storage, "/", &stream.FileStream{
+			Obj:    &model.Object{Name: "a.txt", Size: 1, Modified: time.Now()},
+			Reader: pr,
+		}, nil)
+	}()
+	for apiStats(t, "/api_limit").Running == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err = op.List(timeout, storage, "/", model.ListArgs{Refresh: true}); err == nil {
+		t.Errorf("expect the listing to time out in the queue")
+	}
+	_, _ = pw.Write([]byte("a"))
+	_ = pw.Close()
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	stats = apiStats(t, "/api_limit")
+	if stats.Canceled != 1 || stats.Running != 0 || stats.Waiting != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+// TestStorageAPILimitReentrant lists the storage inside a call holding its only slot,
+// as the drivers calling op on their own storage do
+func TestStorageAPILimitReentrant(t *testing.T) {
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/api_limit_reentrant",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, t.TempDir()),
+		APILimit:  model.APILimit{MaxConcurrent: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/api_limit_reentrant")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- op.LimitCall(ctx, storage, "remove", func(ctx context.Context) error {
+			_, err := op.List(ctx, storage, "/", model.ListArgs{Refresh: true})
+			return err
+		})
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect the inner call not to wait for the slot held by the outer call")
+	}
+	if stats := apiStats(t, "/api_limit_reentrant"); stats.Running != 0 || stats.Calls["list"] != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// the other calls still wait for the slot
+	release := make(chan struct{})
+	go func() {
+		done <- op.LimitCall(ctx, storage, "remove", func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+	}()
+	for apiStats(t, "/api_limit_reentrant").Running == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err = op.List(timeout, storage, "/", model.ListArgs{Refresh: true}); err == nil {
+		t.Errorf("expect the listing of another call to wait for the slot")
+	}
+	close(release)
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+}
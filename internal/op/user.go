@@ -1,13 +1,17 @@
 package op
 
 import (
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
 	"github.com/pkg/errors"
-	"time"
 )
 
 var userG singleflight.Group[*model.User]
@@ -84,6 +88,10 @@ func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err err
 	return db.GetUsers(pageIndex, pageSize)
 }
 
+func FindUsers(role int, username, ssoID string, offset, limit int) (users []model.User, count int64, err error) {
+	return db.FindUsers(role, username, ssoID, offset, limit)
+}
+
 func CreateUser(u *model.User) error {
 	u.BasePath = utils.FixAndCleanPath(u.BasePath)
 	if u.ExpiresAt != nil {
@@ -146,9 +154,47 @@ func UpdateUser(u *model.User) error {
 
 func Cancel2FAByUser(u *model.User) error {
 	u.OtpSecret = ""
+	u.RecoveryCodes = ""
 	return UpdateUser(u)
 }
 
+const recoveryCodeCount = 10
+
+var recoveryCodeMu sync.Mutex
+
+// GenerateRecoveryCodes replaces the user's recovery codes with a fresh set and
+// returns the plain codes, which are never stored and can only be shown once.
+func GenerateRecoveryCodes(u *model.User) ([]string, error) {
+	recoveryCodeMu.Lock()
+	defer recoveryCodeMu.Unlock()
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code := strings.ToLower(random.String(10))
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	u.SetRecoveryCodes(codes)
+	if err := UpdateUser(u); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode consumes one of the user's recovery codes.
+// The user is reloaded from the database so that a code can never be used twice.
+func UseRecoveryCode(u *model.User, code string) (bool, error) {
+	recoveryCodeMu.Lock()
+	defer recoveryCodeMu.Unlock()
+	user, err := db.GetUserById(u.ID)
+	if err != nil {
+		return false, err
+	}
+	if !user.UseRecoveryCode(code) {
+		return false, nil
+	}
+	u.RecoveryCodes = user.RecoveryCodes
+	return true, UpdateUser(user)
+}
+
 func Cancel2FAById(id uint) error {
 	user, err := db.GetUserById(id)
 	if err != nil {
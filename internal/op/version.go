@@ -0,0 +1,205 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	stdpath "path"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// VersionsDir is the hidden folder at the root of each storage holding the previous versions of files
+const VersionsDir = "/.openlist_versions"
+
+// GetVersioningMeta returns the meta enabling versioning for the file at path, or nil
+func GetVersioningMeta(path string) *model.Meta {
+	dir := stdpath.Dir(utils.FixAndCleanPath(path))
+	meta, err := GetNearestMeta(dir)
+	if err != nil || !meta.Versioning {
+		return nil
+	}
+	if !meta.VSub && !utils.PathEqual(meta.Path, dir) {
+		return nil
+	}
+	return meta
+}
+
+// KeepVersion moves the file about to be overwritten into the versions folder if
+// versioning applies to it. The returned version is nil if nothing was kept.
+func KeepVersion(ctx context.Context, storage driver.Driver, path string, user *model.User) (*model.FileVersion, error) {
+	path = utils.FixAndCleanPath(path)
+	if storage.Config().OnlyIndices || utils.IsSubPath(VersionsDir, path) || utils.IsSubPath(RecycleBinDir, path) {
+		return nil, nil
+	}
+	meta := GetVersioningMeta(stdpath.Join(storage.GetStorage().MountPath, path))
+	if meta == nil {
+		return nil, nil
+	}
+	if !canMoveAside(storage) {
+		log.Warnf("storage [%s] can't keep versions of %s", storage.GetStorage().MountPath, path)
+		return nil, nil
+	}
+	v, err := moveAside(ctx, storage, path, user)
+	if v != nil {
+		pruneFileVersions(ctx, storage, path, meta)
+	}
+	return v, err
+}
+
+// moveAside moves an existing non-empty file into a timestamped folder of the versions folder
+func moveAside(ctx context.Context, storage driver.Driver, path string, user *model.User) (*model.FileVersion, error) {
+	obj, err := GetUnwrap(ctx, storage, path)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.WithMessage(err, "failed to get object")
+	}
+	if obj.IsDir() || obj.GetSize() == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	versionDir := stdpath.Join(VersionsDir, fmt.Sprintf("%s_%s", now.Format("20060102150405.000"), random.String(6)))
+	if err = MakeDir(ctx, storage, versionDir); err != nil {
+		return nil, errors.WithMessage(err, "failed to make version dir")
+	}
+	if err = Move(ctx, storage, path, versionDir); err != nil {
+		_ = Remove(ctx, storage, versionDir)
+		return nil, errors.WithMessage(err, "failed to move file into versions")
+	}
+	v := &model.FileVersion{
+		StorageID:   storage.GetStorage().ID,
+		ActualPath:  path,
+		Path:        stdpath.Join(storage.GetStorage().MountPath, path),
+		VersionPath: stdpath.Join(versionDir, obj.GetName()),
+		Size:        obj.GetSize(),
+		Modified:    obj.ModTime(),
+		CreatedAt:   now,
+	}
+	if user != nil {
+		v.UserID = user.ID
+		v.Username = user.Username
+	}
+	return v, db.CreateFileVersion(v)
+}
+
+func pruneFileVersions(ctx context.Context, storage driver.Driver, path string, meta *model.Meta) {
+	if meta.MaxVersions <= 0 && meta.VersionMaxDays <= 0 {
+		return
+	}
+	versions, err := db.GetFileVersions(storage.GetStorage().ID, path)
+	if err != nil {
+		log.Errorf("failed to get versions of %s: %+v", path, err)
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -meta.VersionMaxDays)
+	for i := range versions {
+		if (meta.MaxVersions > 0 && i >= meta.MaxVersions) ||
+			(meta.VersionMaxDays > 0 && versions[i].CreatedAt.Before(cutoff)) {
+			if err = deleteFileVersion(ctx, storage, &versions[i]); err != nil {
+				log.Warnf("failed to prune version %d of %s: %+v", versions[i].ID, path, err)
+			}
+		}
+	}
+}
+
+func deleteFileVersion(ctx context.Context, storage driver.Driver, v *model.FileVersion) error {
+	if err := Remove(ctx, storage, stdpath.Dir(v.VersionPath)); err != nil {
+		return err
+	}
+	return db.DeleteFileVersionById(v.ID)
+}
+
+func GetFileVersions(storage driver.Driver, path string) ([]model.FileVersion, error) {
+	return db.GetFileVersions(storage.GetStorage().ID, utils.FixAndCleanPath(path))
+}
+
+func GetFileVersionById(id uint) (*model.FileVersion, error) {
+	return db.GetFileVersionById(id)
+}
+
+// GetFileVersionStorage returns the storage holding the version and the path of its content
+func GetFileVersionStorage(v *model.FileVersion) (driver.Driver, error) {
+	return getStorageById(v.StorageID)
+}
+
+// RestoreFileVersion puts the version back in place,
+// the current content of the file is kept as a new version
+func RestoreFileVersion(ctx context.Context, v *model.FileVersion, user *model.User) error {
+	storage, err := getStorageById(v.StorageID)
+	if err != nil {
+		return err
+	}
+	if stdpath.Base(v.ActualPath) != stdpath.Base(v.VersionPath) {
+		return errors.New("the version doesn't match its file")
+	}
+	if _, err = moveAside(ctx, storage, v.ActualPath, user); err != nil {
+		return errors.WithMessage(err, "failed to keep current content")
+	}
+	// an empty file may still be in the way
+	if err = Remove(ctx, storage, v.ActualPath); err != nil {
+		return errors.WithMessage(err, "failed to remove current file")
+	}
+	dstDir := stdpath.Dir(v.ActualPath)
+	if err = MakeDir(ctx, storage, dstDir); err != nil {
+		return errors.WithMessage(err, "failed to make dir of file")
+	}
+	if err = Move(ctx, storage, v.VersionPath, dstDir); err != nil {
+		return errors.WithMessage(err, "failed to move version back")
+	}
+	if err = Remove(ctx, storage, stdpath.Dir(v.VersionPath)); err != nil {
+		log.Warnf("failed to remove version dir %s: %+v", v.VersionPath, err)
+	}
+	return db.DeleteFileVersionById(v.ID)
+}
+
+func DeleteFileVersion(ctx context.Context, v *model.FileVersion) error {
+	storage, err := getStorageById(v.StorageID)
+	if err != nil {
+		log.Warnf("delete file version %d without storage: %v", v.ID, err)
+		return db.DeleteFileVersionById(v.ID)
+	}
+	return deleteFileVersion(ctx, storage, v)
+}
+
+// PurgeExpiredFileVersions applies the versioning policies to all the files
+// with versions, so that versions expire even if the file isn't written again
+func PurgeExpiredFileVersions(ctx context.Context) {
+	var versions []model.FileVersion
+	if err := db.GetDb().Select("storage_id", "actual_path").Distinct().Find(&versions).Error; err != nil {
+		log.Errorf("failed to get versioned files: %+v", err)
+		return
+	}
+	for _, v := range versions {
+		storage, err := getStorageById(v.StorageID)
+		if err != nil {
+			continue
+		}
+		meta := GetVersioningMeta(stdpath.Join(storage.GetStorage().MountPath, v.ActualPath))
+		if meta == nil {
+			continue
+		}
+		pruneFileVersions(ctx, storage, v.ActualPath, meta)
+	}
+}
+
+var versionCron *cron.Cron
+
+func StartFileVersionPurgeScheduler() {
+	if versionCron != nil {
+		return
+	}
+	versionCron = cron.NewCron(time.Hour)
+	versionCron.Do(func() {
+		PurgeExpiredFileVersions(context.Background())
+	})
+}
@@ -0,0 +1,76 @@
+package op_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestKeepAndRestoreVersion(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/versions",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(root, "dir", "a.txt")
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1")
+	if v, err := op.KeepVersion(ctx, storage, "/dir/a.txt", nil); err != nil || v != nil {
+		t.Fatalf("expect no version without meta, got %+v %v", v, err)
+	}
+	if err = op.CreateMeta(&model.Meta{Path: "/versions/dir", Versioning: true, MaxVersions: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"v1", "v2", "v3"} {
+		write(content)
+		if _, err = op.KeepVersion(ctx, storage, "/dir/a.txt", &model.User{ID: 7}); err != nil {
+			t.Fatalf("failed to keep version: %+v", err)
+		}
+		if _, err = os.Stat(file); !os.IsNotExist(err) {
+			t.Fatalf("expect file to be moved away, got %v", err)
+		}
+	}
+	versions, err := op.GetFileVersions(storage, "/dir/a.txt")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("expect 2 versions, got %d %v", len(versions), err)
+	}
+	if versions[0].Path != "/versions/dir/a.txt" || versions[0].Size != 2 {
+		t.Errorf("unexpected version: %+v", versions[0])
+	}
+	write("v4")
+	if err = op.RestoreFileVersion(ctx, &versions[0], nil); err != nil {
+		t.Fatalf("failed to restore: %+v", err)
+	}
+	if content, err := os.ReadFile(file); err != nil || string(content) != "v3" {
+		t.Errorf("expect v3 to be restored, got %q %v", content, err)
+	}
+	versions, _ = op.GetFileVersions(storage, "/dir/a.txt")
+	if len(versions) != 2 {
+		t.Fatalf("expect the overwritten content to be kept, got %d versions", len(versions))
+	}
+	content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(versions[0].VersionPath)))
+	if err != nil || string(content) != "v4" {
+		t.Errorf("expect newest version to hold v4, got %q %v", content, err)
+	}
+}
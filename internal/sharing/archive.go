@@ -21,6 +21,9 @@ func archiveMeta(ctx context.Context, sid, path string, args model.SharingArchiv
 	if !sharing.Verify(args.Pwd) {
 		return sharing, nil, errors.WithStack(errs.WrongShareCode)
 	}
+	if sharing.IsUpload() {
+		return sharing, nil, errors.WithStack(errs.SharingUploadOnly)
+	}
 	path = utils.FixAndCleanPath(path)
 	if len(sharing.Files) == 1 || path != "/" {
 		unwrapPath, err := op.GetSharingUnwrapPath(sharing, path)
@@ -48,6 +51,9 @@ func archiveList(ctx context.Context, sid, path string, args model.SharingArchiv
 	if !sharing.Verify(args.Pwd) {
 		return sharing, nil, errors.WithStack(errs.WrongShareCode)
 	}
+	if sharing.IsUpload() {
+		return sharing, nil, errors.WithStack(errs.SharingUploadOnly)
+	}
 	path = utils.FixAndCleanPath(path)
 	if len(sharing.Files) == 1 || path != "/" {
 		unwrapPath, err := op.GetSharingUnwrapPath(sharing, path)
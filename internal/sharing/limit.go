@@ -0,0 +1,177 @@
+package sharing
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// traffic is the download usage of a sharing in this process. The counters are
+// loaded from the database when no download of the sharing is running, and
+// written back when each download finishes.
+type traffic struct {
+	mu      sync.Mutex
+	total   int64
+	daily   int64
+	date    string
+	active  int
+	limiter stream.Limiter
+}
+
+var (
+	trafficsMu sync.Mutex
+	traffics   = make(map[string]*traffic)
+)
+
+func init() {
+	// the traffic of a deleted sharing is not needed any more
+	op.RegisterSharingDeleteHook(func(sid string) {
+		trafficsMu.Lock()
+		defer trafficsMu.Unlock()
+		delete(traffics, sid)
+	})
+}
+
+func getTraffic(sid string) *traffic {
+	trafficsMu.Lock()
+	defer trafficsMu.Unlock()
+	t, ok := traffics[sid]
+	if !ok {
+		t = &traffic{}
+		traffics[sid] = t
+	}
+	return t
+}
+
+func today() string {
+	return time.Now().Format(time.DateOnly)
+}
+
+// Download is a running download of a sharing
+type Download struct {
+	sharing *model.Sharing
+	t       *traffic
+	once    sync.Once
+}
+
+// StartDownload checks the concurrency and traffic limits of the sharing,
+// Done must be called when the download is finished
+func StartDownload(s *model.Sharing) (*Download, error) {
+	t := getTraffic(s.ID)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.active == 0 {
+		t.total, t.daily, t.date = s.Traffic, s.DailyTraffic, s.TrafficDate
+	}
+	if t.date != today() {
+		t.daily, t.date = 0, today()
+	}
+	if s.MaxConcurrent > 0 && t.active >= s.MaxConcurrent {
+		return nil, errors.WithStack(errs.SharingTooManyDownloads)
+	}
+	if (s.MaxTraffic > 0 && t.total >= s.MaxTraffic) || (s.MaxDailyTraffic > 0 && t.daily >= s.MaxDailyTraffic) {
+		return nil, errors.WithStack(errs.SharingTrafficExceeded)
+	}
+	if s.SpeedLimit > 0 {
+		limit, burst := rate.Limit(s.SpeedLimit), int(min(s.SpeedLimit, 1<<30))
+		if t.limiter == nil {
+			t.limiter = stream.BlockBurstLimiter{Limiter: rate.NewLimiter(limit, burst)}
+		} else if t.limiter.Limit() != limit {
+			t.limiter.SetLimit(limit)
+			t.limiter.SetBurst(burst)
+		}
+	} else {
+		t.limiter = nil
+	}
+	t.active++
+	return &Download{sharing: s, t: t}, nil
+}
+
+// take reserves up to n bytes of the remaining traffic
+func (d *Download) take(n int64) (int64, stream.Limiter) {
+	t, s := d.t, d.sharing
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.date != today() {
+		t.daily, t.date = 0, today()
+	}
+	if s.MaxTraffic > 0 {
+		n = max(min(n, s.MaxTraffic-t.total), 0)
+	}
+	if s.MaxDailyTraffic > 0 {
+		n = max(min(n, s.MaxDailyTraffic-t.daily), 0)
+	}
+	t.total += n
+	t.daily += n
+	return n, t.limiter
+}
+
+func (d *Download) refund(n int64) {
+	d.t.mu.Lock()
+	defer d.t.mu.Unlock()
+	d.t.total -= n
+	d.t.daily -= n
+}
+
+// Done saves the traffic of the sharing and releases the download slot
+func (d *Download) Done() {
+	d.once.Do(func() {
+		t := d.t
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		d.sharing.Traffic, d.sharing.DailyTraffic, d.sharing.TrafficDate = t.total, t.daily, t.date
+		if err := op.UpdateSharingTraffic(d.sharing); err != nil {
+			log.Errorf("failed save traffic of sharing %s: %+v", d.sharing.ID, err)
+		}
+		t.active--
+	})
+}
+
+// Writer wraps w so that what is written counts in the traffic of the sharing
+// and respects its speed limit
+func (d *Download) Writer(ctx context.Context, w io.Writer) io.Writer {
+	return &downloadWriter{Writer: w, d: d, ctx: ctx}
+}
+
+type downloadWriter struct {
+	io.Writer
+	d   *Download
+	ctx context.Context
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	allowed, limiter := w.d.take(int64(len(p)))
+	n, err := w.Writer.Write(p[:allowed])
+	if int64(n) < allowed {
+		w.d.refund(allowed - int64(n))
+	}
+	if limiter != nil && err == nil {
+		err = limiter.WaitN(w.ctx, n)
+	}
+	if err == nil && n < len(p) {
+		err = errors.WithStack(errs.SharingTrafficExceeded)
+	}
+	return n, err
+}
+
+// ResetTraffic clears the traffic counters of the sharing, running downloads count from zero again
+func ResetTraffic(s *model.Sharing) error {
+	t := getTraffic(s.ID)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total, t.daily, t.date = 0, 0, today()
+	s.Traffic, s.DailyTraffic, s.TrafficDate = t.total, t.daily, t.date
+	return op.UpdateSharingTraffic(s)
+}
@@ -0,0 +1,74 @@
+package sharing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestDownloadLimits(t *testing.T) {
+	s := &model.Sharing{SharingDB: &model.SharingDB{
+		ID:              "limit",
+		MaxConcurrent:   1,
+		MaxDailyTraffic: 10,
+		Traffic:         5,
+		DailyTraffic:    4,
+		TrafficDate:     today(),
+	}}
+	d, err := StartDownload(s)
+	if err != nil {
+		t.Fatalf("failed to start download: %+v", err)
+	}
+	if _, err = StartDownload(s); !errors.Is(err, errs.SharingTooManyDownloads) {
+		t.Errorf("expect too many downloads, got %v", err)
+	}
+	buf := &bytes.Buffer{}
+	n, err := d.Writer(context.Background(), buf).Write([]byte("0123456789"))
+	if n != 6 || !errors.Is(err, errs.SharingTrafficExceeded) {
+		t.Errorf("expect 6 bytes written and traffic exceeded, got %d %v", n, err)
+	}
+	if d.t.total != 11 || d.t.daily != 10 {
+		t.Errorf("unexpected traffic: total %d, daily %d", d.t.total, d.t.daily)
+	}
+}
+
+func TestDeleteSharingTraffic(t *testing.T) {
+	creator := &model.User{Username: "traffic_creator", Role: model.GENERAL}
+	if err := op.CreateUser(creator); err != nil {
+		t.Fatal(err)
+	}
+	id, err := op.CreateSharing(&model.Sharing{
+		SharingDB: &model.SharingDB{SpeedLimit: 1 << 20},
+		Files:     []string{"/shared"},
+		Creator:   creator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := op.GetSharingById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := StartDownload(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.Writer(context.Background(), &bytes.Buffer{}).Write([]byte("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	d.Done()
+	if err = op.DeleteSharing(id); err != nil {
+		t.Fatal(err)
+	}
+	trafficsMu.Lock()
+	_, ok := traffics[id]
+	trafficsMu.Unlock()
+	if ok {
+		t.Errorf("expect the traffic of the deleted sharing to be removed")
+	}
+}
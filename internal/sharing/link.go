@@ -23,6 +23,9 @@ func link(ctx context.Context, sid, path string, args *LinkArgs) (*model.Sharing
 	if !sharing.Verify(args.Pwd) {
 		return sharing, nil, nil, errors.WithStack(errs.WrongShareCode)
 	}
+	if sharing.IsUpload() {
+		return sharing, nil, nil, errors.WithStack(errs.SharingUploadOnly)
+	}
 	path = utils.FixAndCleanPath(path)
 	if len(sharing.Files) == 1 || path != "/" {
 		unwrapPath, err := op.GetSharingUnwrapPath(sharing, path)
@@ -22,6 +22,10 @@ func list(ctx context.Context, sid, path string, args model.SharingListArgs) (*m
 	if !sharing.Verify(args.Pwd) {
 		return sharing, nil, errors.WithStack(errs.WrongShareCode)
 	}
+	if sharing.IsUpload() {
+		// the content of a file request is never exposed
+		return sharing, []model.Obj{}, nil
+	}
 	path = utils.FixAndCleanPath(path)
 	if len(sharing.Files) == 1 || path != "/" {
 		unwrapPath, err := op.GetSharingUnwrapPath(sharing, path)
@@ -45,6 +49,11 @@ func list(ctx context.Context, sid, path string, args model.SharingListArgs) (*m
 		}
 		om := model.NewObjMerge()
 		objs = om.Merge(objs, virtualFiles...)
+		if storage != nil && utils.PathEqual(actualPath, "/") {
+			objs = utils.SliceFilter(objs, func(obj model.Obj) bool {
+				return !op.IsHiddenPath(stdpath.Join(unwrapPath, obj.GetName()))
+			})
+		}
 		model.SortFiles(objs, sharing.OrderBy, sharing.OrderDirection)
 		model.ExtractFolder(objs, sharing.ExtractFolder)
 		return sharing, objs, nil
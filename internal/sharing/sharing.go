@@ -56,3 +56,13 @@ func Link(ctx context.Context, sid, path string, args *LinkArgs) (*model.Sharing
 	}
 	return sharing, res, file, nil
 }
+
+// Upload stores a file sent by a visitor of a file request and returns the name it was stored as
+func Upload(ctx context.Context, sid string, args UploadArgs) (*model.Sharing, string, error) {
+	sharing, name, err := upload(ctx, sid, args)
+	if err != nil {
+		log.Warnf("failed upload to sharing %s: %s", sid, err)
+		return sharing, "", err
+	}
+	return sharing, name, nil
+}
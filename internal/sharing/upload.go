@@ -0,0 +1,146 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/pkg/errors"
+)
+
+type UploadArgs struct {
+	Pwd      string
+	Uploader string
+	Name     string
+	Size     int64 // -1 if unknown
+	Mimetype string
+	Modified time.Time
+	Reader   io.Reader
+}
+
+func sanitizeUploadName(name string) string {
+	return strings.TrimSpace(strings.NewReplacer("/", "_", "\\", "_").Replace(name))
+}
+
+// availableName appends " (n)" to name until no object with the name exists in dir,
+// anonymous uploads never overwrite nor reveal existing files
+func availableName(ctx context.Context, dir, name string) (string, error) {
+	storage, actualDir, err := op.GetStorageAndActualPath(dir)
+	if err != nil {
+		return "", err
+	}
+	ext := stdpath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 1; i <= 100; i++ {
+		if _, err := op.Get(ctx, storage, stdpath.Join(actualDir, candidate)); err != nil {
+			if errs.IsObjectNotFound(err) {
+				return candidate, nil
+			}
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	return "", errors.New("failed find an available file name")
+}
+
+// reserveUpload counts size in the total upload size of the file request before the upload,
+// the check and the update are one statement so that concurrent uploads can't exceed it
+func reserveUpload(s *model.Sharing, size int64) error {
+	ok, err := op.ReserveSharingUpload(s, size)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errs.NewErr(errs.SharingUploadRejected, "the share has not enough space left")
+	}
+	return nil
+}
+
+func releaseUpload(s *model.Sharing, size int64) {
+	if err := op.ReleaseSharingUpload(s, size); err != nil {
+		utils.Log.Warnf("failed release the upload size of sharing [%s]: %+v", s.ID, err)
+	}
+}
+
+func upload(ctx context.Context, sid string, args UploadArgs) (*model.Sharing, string, error) {
+	sharing, err := op.GetSharingById(sid)
+	if err != nil {
+		return nil, "", errors.WithStack(errs.SharingNotFound)
+	}
+	if !sharing.Valid() {
+		return sharing, "", errors.WithStack(errs.InvalidSharing)
+	}
+	if !sharing.Verify(args.Pwd) {
+		return sharing, "", errors.WithStack(errs.WrongShareCode)
+	}
+	if !sharing.IsUpload() {
+		return sharing, "", errors.WithStack(errs.SharingNotUploadable)
+	}
+	name := sanitizeUploadName(args.Name)
+	if name == "" || name == "." || name == ".." {
+		return sharing, "", errs.NewErr(errs.SharingUploadRejected, "invalid file name")
+	}
+	if !sharing.AllowUploadExt(name) {
+		return sharing, "", errs.NewErr(errs.SharingUploadRejected, "file type [%s] is not allowed", utils.Ext(name))
+	}
+	if args.Size < 0 && (sharing.UploadMaxFileSize > 0 || sharing.UploadMaxTotalSize > 0) {
+		return sharing, "", errs.NewErr(errs.SharingUploadRejected, "file size is required")
+	}
+	if sharing.UploadMaxFileSize > 0 && args.Size > sharing.UploadMaxFileSize {
+		return sharing, "", errs.NewErr(errs.SharingUploadRejected, "file is larger than %d bytes", sharing.UploadMaxFileSize)
+	}
+	// the creator must still be allowed to write into the target folder
+	dir := sharing.Files[0]
+	meta, err := op.GetNearestMeta(dir)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return sharing, "", err
+	}
+	if !sharing.Creator.CanWrite() && !common.CanWrite(meta, dir) {
+		return sharing, "", errors.WithStack(errs.PermissionDenied)
+	}
+	if sharing.UploadNamePrefix {
+		uploader := sanitizeUploadName(args.Uploader)
+		if uploader == "" {
+			uploader = "anonymous"
+		}
+		name = fmt.Sprintf("%s_%s_%s", uploader, time.Now().Format("20060102"), name)
+	}
+	if name, err = availableName(ctx, dir, name); err != nil {
+		return sharing, "", err
+	}
+	size := max(args.Size, 0)
+	if err = reserveUpload(sharing, size); err != nil {
+		return sharing, "", err
+	}
+	reader := args.Reader
+	if args.Size >= 0 {
+		reader = io.LimitReader(reader, args.Size)
+	}
+	file := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     args.Size,
+			Modified: args.Modified,
+		},
+		Reader:   reader,
+		Mimetype: args.Mimetype,
+	}
+	ctx = context.WithValue(ctx, conf.UserKey, sharing.Creator)
+	if err = fs.PutDirectly(ctx, dir, file, true); err != nil {
+		releaseUpload(sharing, size)
+		return sharing, "", err
+	}
+	return sharing, name, nil
+}
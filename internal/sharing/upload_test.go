@@ -0,0 +1,84 @@
+package sharing
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestReserveUploadConcurrently(t *testing.T) {
+	creator := &model.User{Username: "upload_creator", Role: model.GENERAL}
+	if err := op.CreateUser(creator); err != nil {
+		t.Fatal(err)
+	}
+	id, err := op.CreateSharing(&model.Sharing{
+		SharingDB: &model.SharingDB{Type: model.SharingTypeUpload, UploadMaxTotalSize: 100, Accessed: 1},
+		Files:     []string{"/upload"},
+		Creator:   creator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var reserved atomic.Int32
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// each upload has its own copy of the sharing, as if it's loaded by another request
+			s, err := op.GetSharingById(id, true)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if reserveUpload(s, 10) == nil {
+				reserved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	s, err := op.GetSharingById(id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reserved.Load() != 10 || s.Uploaded != 100 {
+		t.Errorf("expect 10 uploads reserved to the total size, got %d of %d bytes", reserved.Load(), s.Uploaded)
+	}
+
+	// the other columns updated in the meantime are kept
+	s.Accessed = 5
+	if err = op.UpdateSharing(s, true); err != nil {
+		t.Fatal(err)
+	}
+	releaseUpload(s, 30)
+	if err = reserveUpload(s, 40); err == nil {
+		t.Errorf("expect the upload over the total size to be rejected")
+	}
+	if err = reserveUpload(s, 20); err != nil {
+		t.Errorf("expect the released size to be reusable, got %v", err)
+	}
+	s, err = op.GetSharingById(id, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Uploaded != 90 || s.Accessed != 5 {
+		t.Errorf("expect 90 bytes uploaded and 5 accesses, got %d and %d", s.Uploaded, s.Accessed)
+	}
+}
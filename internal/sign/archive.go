@@ -31,8 +31,12 @@ func NotExpiredArchive(data string) string {
 	return instanceArchive.Sign(data, 0)
 }
 
+// VerifyArchive verifies the sign of SignArchive or SignArchiveUser
 func VerifyArchive(data string, sign string) error {
 	onceArchive.Do(InstanceArchive)
+	if uid, rest := splitUser(sign); uid != 0 {
+		return instanceArchive.Verify(userData(data, uid), rest)
+	}
 	return instanceArchive.Verify(data, sign)
 }
 
@@ -31,8 +31,12 @@ func NotExpired(data string) string {
 	return instance.Sign(data, 0)
 }
 
+// Verify verifies the sign of Sign or SignUser
 func Verify(data string, sign string) error {
 	once.Do(Instance)
+	if uid, rest := splitUser(sign); uid != 0 {
+		return instance.Verify(userData(data, uid), rest)
+	}
 	return instance.Verify(data, sign)
 }
 
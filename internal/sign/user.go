@@ -0,0 +1,53 @@
+package sign
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A sign for a user is the sign of the data and the user ID, prefixed with the ID and a dot,
+// which is not in the base64 of the signs. The downloads of the links signed for a user are
+// counted as the traffic of the user, the links don't give the user any other right.
+
+func userData(data string, userID uint) string {
+	return data + "\x00" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func userPrefix(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10) + "."
+}
+
+// splitUser returns the user ID and the sign without it, or 0 if the sign is not for a user
+func splitUser(sign string) (uint, string) {
+	id, rest, ok := strings.Cut(sign, ".")
+	if !ok {
+		return 0, sign
+	}
+	uid, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || uid == 0 {
+		return 0, sign
+	}
+	return uint(uid), rest
+}
+
+// SignUser returns the sign of the data for the user, it's a Sign if userID is 0
+func SignUser(data string, userID uint) string {
+	if userID == 0 {
+		return Sign(data)
+	}
+	return userPrefix(userID) + Sign(userData(data, userID))
+}
+
+// SignArchiveUser returns the archive sign of the data for the user, it's a SignArchive if userID is 0
+func SignArchiveUser(data string, userID uint) string {
+	if userID == 0 {
+		return SignArchive(data)
+	}
+	return userPrefix(userID) + SignArchive(userData(data, userID))
+}
+
+// UserID returns the ID of the user the sign is for, or 0. It must be called on a verified sign.
+func UserID(sign string) uint {
+	uid, _ := splitUser(sign)
+	return uid
+}
@@ -34,6 +34,40 @@ var (
 	ServerUploadLimit   Limiter
 )
 
+// BlockBurstLimiter waits for the reads and writes larger than the burst block by block
+type BlockBurstLimiter struct {
+	*rate.Limiter
+}
+
+func (l BlockBurstLimiter) WaitN(ctx context.Context, total int) error {
+	for total > 0 {
+		n := l.Burst()
+		if l.Limiter.Limit() == rate.Inf || n > total {
+			n = total
+		}
+		err := l.Limiter.WaitN(ctx, n)
+		if err != nil {
+			return err
+		}
+		total -= n
+	}
+	return nil
+}
+
+// SpeedLimit converts a speed in KB/s, negative for unlimited, to the limit and the burst of a limiter
+func SpeedLimit(kb int) (rate.Limit, int) {
+	if kb < 0 {
+		return rate.Inf, 0
+	}
+	return rate.Limit(kb) * 1024.0, kb * 1024
+}
+
+// NewSpeedLimiter returns a limiter of the speed in KB/s, negative for unlimited
+func NewSpeedLimiter(kb int) Limiter {
+	limit, burst := SpeedLimit(kb)
+	return BlockBurstLimiter{Limiter: rate.NewLimiter(limit, burst)}
+}
+
 type RateLimitReader struct {
 	io.Reader
 	Limiter Limiter
@@ -2,6 +2,10 @@ package task
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
@@ -16,8 +20,37 @@ type TaskExtension struct {
 	endTime    *time.Time
 	TotalBytes int64
 	ApiUrl     string
+	// Paused is set by Pause until Resume, AutoPaused when the pause switch of the transfers did it
+	Paused     pauseFlag `json:"paused"`
+	AutoPaused pauseFlag `json:"auto_paused"`
+	resuming   atomic.Bool
+	// mu guards cancel and the changes of the pause flags, which the handlers
+	// and the setting of the pause switch make while the workers run the task
+	mu     sync.Mutex
+	cancel context.CancelFunc
 }
 
+// pauseFlag is a bool which is safe to read while it's changed, and persisted as a bool
+type pauseFlag struct {
+	atomic.Bool
+}
+
+func (f *pauseFlag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Load())
+}
+
+func (f *pauseFlag) UnmarshalJSON(data []byte) error {
+	var v bool
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f.Store(v)
+	return nil
+}
+
+// ErrPaused is what a pausable task fails with once it's paused, it goes on by Resume and a retry
+var ErrPaused = errors.New("paused")
+
 func (t *TaskExtension) SetCtx(ctx context.Context) {
 	if t.Creator != nil {
 		ctx = context.WithValue(ctx, conf.UserKey, t.Creator)
@@ -28,6 +61,13 @@ func (t *TaskExtension) SetCtx(ctx context.Context) {
 	t.Base.SetCtx(ctx)
 }
 
+func (t *TaskExtension) SetCancelFunc(cancel context.CancelFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancel = cancel
+	t.Base.SetCancelFunc(cancel)
+}
+
 func (t *TaskExtension) SetCreator(creator *model.User) {
 	t.Creator = creator
 	t.Persist()
@@ -79,10 +119,88 @@ func (t *TaskExtension) ReinitCtx() error {
 	return nil
 }
 
+// CanPause reports whether the task is pending or running, so that it can be paused
+func (t *TaskExtension) CanPause() bool {
+	switch t.GetState() {
+	case tache.StatePending, tache.StateRunning, tache.StateWaitingRetry, tache.StateBeforeRetry:
+		return !t.Paused.Load()
+	}
+	return false
+}
+
+// Pause stops the task at its next checkpoint by canceling its ctx, a pending task
+// fails as soon as it runs. auto is true when the pause switch of the transfers pauses it.
+func (t *TaskExtension) Pause(auto bool) {
+	t.mu.Lock()
+	t.AutoPaused.Store(auto)
+	t.Paused.Store(true)
+	cancel := t.cancel
+	t.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	t.Persist()
+}
+
+func (t *TaskExtension) IsPaused() bool {
+	return t.Paused.Load()
+}
+
+func (t *TaskExtension) IsAutoPaused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.Paused.Load() && t.AutoPaused.Load()
+}
+
+// Resume readies the paused task to be run again by the Retry of its manager
+func (t *TaskExtension) Resume() {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.mu.Lock()
+	t.Paused.Store(false)
+	t.AutoPaused.Store(false)
+	t.resuming.Store(true)
+	t.SetCtx(ctx)
+	t.cancel = cancel
+	t.Base.SetCancelFunc(cancel)
+	t.mu.Unlock()
+	t.Persist()
+}
+
+// Resuming is true from Resume to the next run, the tasks use it to tell a resume from a retry
+func (t *TaskExtension) Resuming() bool {
+	return t.resuming.Load()
+}
+
+// Retryable keeps a paused task from being retried at once by its worker
+func (t *TaskExtension) Retryable() bool {
+	return !t.Paused.Load()
+}
+
+// BeforeRun is called first by the Run of a pausable task, it fails with ErrPaused
+// when the task was paused before it started
+func (t *TaskExtension) BeforeRun() error {
+	t.resuming.Store(false)
+	if t.Paused.Load() {
+		return ErrPaused
+	}
+	return nil
+}
+
 type TaskExtensionInfo interface {
 	tache.TaskWithInfo
 	GetCreator() *model.User
 	GetStartTime() *time.Time
 	GetEndTime() *time.Time
 	GetTotalBytes() int64
+	IsPaused() bool
+}
+
+// PausableTask is a task which stops at its checkpoints when it's paused
+// and carries on from the last one when it's resumed
+type PausableTask interface {
+	TaskExtensionInfo
+	CanPause() bool
+	Pause(auto bool)
+	IsAutoPaused() bool
+	Resume()
 }
@@ -0,0 +1,60 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// TestPauseResume runs the pause and resume of the handlers and the pause switch
+// against the checks of the worker, go test -race reports the unguarded accesses
+func TestPauseResume(t *testing.T) {
+	tsk := &TaskExtension{}
+	ctx, cancel := context.WithCancel(context.Background())
+	tsk.SetCtx(ctx)
+	tsk.SetCancelFunc(cancel)
+
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				f()
+			}
+		}()
+	}
+	run(func() { tsk.Pause(false) })
+	run(func() { tsk.Pause(true) })
+	run(func() { tsk.Resume() })
+	run(func() {
+		_ = tsk.BeforeRun()
+		_ = tsk.Retryable()
+		_ = tsk.IsAutoPaused()
+		_ = tsk.Resuming()
+	})
+	run(func() {
+		if _, err := json.Marshal(tsk); err != nil {
+			t.Error(err)
+		}
+	})
+	wg.Wait()
+
+	tsk.Pause(true)
+	data, err := json.Marshal(tsk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := &TaskExtension{}
+	if err = json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.IsPaused() || !restored.IsAutoPaused() {
+		t.Errorf("expect the pause to be persisted, got %s", data)
+	}
+	tsk.Resume()
+	if tsk.IsPaused() || !tsk.Resuming() || tsk.BeforeRun() != nil || tsk.Resuming() {
+		t.Errorf("expect the task to be resumed once")
+	}
+}
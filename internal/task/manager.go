@@ -1,6 +1,8 @@
 package task
 
 import (
+	"errors"
+
 	"github.com/OpenListTeam/tache"
 )
 
@@ -20,3 +22,36 @@ type Manager[T tache.Task] interface {
 	Retry(id string)
 	RetryAllFailed()
 }
+
+// Discarder is a task which keeps what it did to be carried on, like a partial upload,
+// Discard removes it when the task is given up
+type Discarder interface {
+	Discard()
+}
+
+// RemoveTask removes the task from the manager, and discards what it kept unless it succeeded
+func RemoveTask[T tache.Task](manager Manager[T], t T) {
+	manager.Remove(t.GetID())
+	if d, ok := any(t).(Discarder); ok && t.GetState() != tache.StateSucceeded {
+		d.Discard()
+	}
+}
+
+// ResumeTask resumes the paused task of the manager, a running task being paused
+// can be resumed only once it stopped
+func ResumeTask[T PausableTask](manager Manager[T], t T) error {
+	if !t.IsPaused() {
+		return errors.New("task is not paused")
+	}
+	switch t.GetState() {
+	case tache.StatePending:
+		// it's still queued
+		t.Resume()
+	case tache.StateFailed:
+		t.Resume()
+		manager.Retry(t.GetID())
+	default:
+		return errors.New("task is being paused")
+	}
+	return nil
+}
@@ -17,6 +17,21 @@ import (
 
 type SrcPathToRemove string
 
+// SrcPathToKeep is a source left in place by a move, like one skipped for a conflict
+type SrcPathToKeep string
+
+// SrcPathRenamed is a source moved under another name for a conflict
+type SrcPathRenamed struct {
+	Path string
+	Name string
+}
+
+// movedSrc tells what happened to the sources of a move, by their paths
+type movedSrc struct {
+	kept    map[string]bool
+	renamed map[string]string
+}
+
 // ActualPath
 type DstPathToRefresh string
 
@@ -37,6 +52,15 @@ func RefreshAndRemove(dstPath string, payloads ...any) {
 		listLimiter = rate.NewLimiter(rate.Limit(dstHandleHookLimit), 1)
 	}
 	var ctx context.Context
+	moved := movedSrc{kept: map[string]bool{}, renamed: map[string]string{}}
+	for _, payload := range payloads {
+		switch p := payload.(type) {
+		case SrcPathToKeep:
+			moved.kept[string(p)] = true
+		case SrcPathRenamed:
+			moved.renamed[p.Path] = p.Name
+		}
+	}
 	for _, payload := range payloads {
 		switch p := payload.(type) {
 		case DstPathToRefresh:
@@ -65,7 +89,7 @@ func RefreshAndRemove(dstPath string, payloads ...any) {
 				log.Error(errors.WithMessage(err, "failed get src storage"))
 				continue
 			}
-			err = verifyAndRemove(ctx, srcStorage, dstStorage, srcActualPath, dstActualPath, dstNeedRefresh)
+			_, err = verifyAndRemove(ctx, srcStorage, dstStorage, srcActualPath, dstActualPath, dstNeedRefresh, moved)
 			if err != nil {
 				log.Error(err)
 			}
@@ -73,52 +97,67 @@ func RefreshAndRemove(dstPath string, payloads ...any) {
 	}
 }
 
-func verifyAndRemove(ctx context.Context, srcStorage, dstStorage driver.Driver, srcPath, dstPath string, refresh bool) error {
+// verifyAndRemove removes the source once its destination is found, it reports whether
+// a part of the source is kept
+func verifyAndRemove(ctx context.Context, srcStorage, dstStorage driver.Driver, srcPath, dstPath string, refresh bool, moved movedSrc) (bool, error) {
+	srcMountPath := path.Join(srcStorage.GetStorage().MountPath, srcPath)
+	if moved.kept[srcMountPath] {
+		return true, nil
+	}
 	srcObj, err := op.Get(ctx, srcStorage, srcPath)
 	if err != nil {
-		return errors.WithMessagef(err, "failed get src [%s] file", path.Join(srcStorage.GetStorage().MountPath, srcPath))
+		return false, errors.WithMessagef(err, "failed get src [%s] file", srcMountPath)
 	}
 
-	dstObjPath := path.Join(dstPath, srcObj.GetName())
+	dstName := srcObj.GetName()
+	if name, ok := moved.renamed[srcMountPath]; ok {
+		dstName = name
+	}
+	dstObjPath := path.Join(dstPath, dstName)
 	dstObj, err := op.Get(ctx, dstStorage, dstObjPath)
 	if err != nil {
-		return errors.WithMessagef(err, "failed get dst [%s] file", path.Join(dstStorage.GetStorage().MountPath, dstObjPath))
+		return false, errors.WithMessagef(err, "failed get dst [%s] file", path.Join(dstStorage.GetStorage().MountPath, dstObjPath))
 	}
 
 	if !dstObj.IsDir() {
 		err = op.Remove(ctx, srcStorage, srcPath)
 		if err != nil {
-			return fmt.Errorf("failed remove %s: %+v", path.Join(srcStorage.GetStorage().MountPath, srcPath), err)
+			return false, fmt.Errorf("failed remove %s: %+v", srcMountPath, err)
 		}
-		return nil
+		return false, nil
 	}
 
 	// Verify directory
 	srcObjs, err := op.List(ctx, srcStorage, srcPath, model.ListArgs{})
 	if err != nil {
-		return errors.WithMessagef(err, "failed list src [%s] objs", path.Join(srcStorage.GetStorage().MountPath, srcPath))
+		return false, errors.WithMessagef(err, "failed list src [%s] objs", srcMountPath)
 	}
 
 	if refresh {
 		op.Cache.DeleteDirectory(dstStorage, dstObjPath)
 	}
-	hasErr := false
+	hasErr, hasKept := false, false
 	for _, obj := range srcObjs {
 		srcSubPath := path.Join(srcPath, obj.GetName())
-		err := verifyAndRemove(ctx, srcStorage, dstStorage, srcSubPath, dstObjPath, refresh)
+		kept, err := verifyAndRemove(ctx, srcStorage, dstStorage, srcSubPath, dstObjPath, refresh, moved)
 		if err != nil {
 			log.Error(err)
 			hasErr = true
 		}
+		hasKept = hasKept || kept
 	}
 	if hasErr {
-		return errors.Errorf("some subitems of [%s] failed to verify and remove", path.Join(srcStorage.GetStorage().MountPath, srcPath))
+		return hasKept, errors.Errorf("some subitems of [%s] failed to verify and remove", srcMountPath)
+	}
+	if hasKept {
+		// the kept ones are still in the dir
+		return true, nil
 	}
 	err = op.Remove(ctx, srcStorage, srcPath)
 	if err != nil {
-		return fmt.Errorf("failed remove %s: %+v", path.Join(srcStorage.GetStorage().MountPath, srcPath), err)
+		return false, fmt.Errorf("failed remove %s: %+v", srcMountPath, err)
 	}
-	return nil
+	return false, nil
 }
 
 var TransferCoordinator *TaskGroupCoordinator = NewTaskGroupCoordinator("RefreshAndRemove", RefreshAndRemove)
@@ -0,0 +1,183 @@
+package traffic
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the protocols the traffic goes through
+const (
+	ProtocolHTTP   = "http"
+	ProtocolWebDAV = "webdav"
+	ProtocolFTP    = "ftp"
+	ProtocolSFTP   = "sftp"
+	ProtocolS3     = "s3"
+)
+
+// Tags tell where the traffic goes, besides the user
+type Tags struct {
+	Protocol  string
+	StorageID uint // 0 when unknown
+	SharingID string
+}
+
+type tagsKey struct{}
+
+// WithTags returns a copy of ctx which carries the tags
+func WithTags(ctx context.Context, tags Tags) context.Context {
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// TagsFrom returns the tags carried by ctx, the protocol is http by default
+func TagsFrom(ctx context.Context) Tags {
+	tags, _ := ctx.Value(tagsKey{}).(Tags)
+	if tags.Protocol == "" {
+		tags.Protocol = ProtocolHTTP
+	}
+	return tags
+}
+
+type statKey struct {
+	hour   time.Time
+	userID uint
+	Tags
+}
+
+var (
+	statsMu sync.Mutex
+	// stats is the traffic not added to the hourly stats in the database yet
+	stats = make(map[statKey]*model.TrafficStat)
+)
+
+// Record counts n bytes transferred by the user into the stats of the current hour
+func Record(user *model.User, tags Tags, dir Direction, n int64) {
+	if user == nil || n <= 0 {
+		return
+	}
+	if tags.Protocol == "" {
+		tags.Protocol = ProtocolHTTP
+	}
+	key := statKey{hour: time.Now().UTC().Truncate(time.Hour), userID: user.ID, Tags: tags}
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	s, ok := stats[key]
+	if !ok {
+		s = &model.TrafficStat{
+			Hour:      key.hour,
+			UserID:    key.userID,
+			StorageID: tags.StorageID,
+			Protocol:  tags.Protocol,
+			SharingID: tags.SharingID,
+		}
+		stats[key] = s
+	}
+	if dir == Download {
+		s.Download += n
+	} else {
+		s.Upload += n
+	}
+}
+
+func flushStats() {
+	statsMu.Lock()
+	flushing := stats
+	stats = make(map[statKey]*model.TrafficStat)
+	statsMu.Unlock()
+	for key, s := range flushing {
+		if err := db.AddTrafficStat(s); err != nil {
+			log.Errorf("failed save the traffic stat of user %d: %+v", s.UserID, err)
+			statsMu.Lock()
+			if cur, ok := stats[key]; ok {
+				cur.Download += s.Download
+				cur.Upload += s.Upload
+			} else {
+				stats[key] = s
+			}
+			statsMu.Unlock()
+		}
+	}
+}
+
+type SeriesPoint struct {
+	Time     time.Time `json:"time"`
+	Download int64     `json:"download"`
+	Upload   int64     `json:"upload"`
+}
+
+// Series returns the selected traffic by hour, or by day in the local time when daily is true,
+// the hours without traffic are left out
+func Series(f db.TrafficStatFilter, daily bool) ([]SeriesPoint, error) {
+	hours, err := db.GetTrafficSeries(f)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]SeriesPoint, 0, len(hours))
+	for _, h := range hours {
+		t := h.Hour.Local()
+		if daily {
+			y, m, d := t.Date()
+			t = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+			if last := len(points) - 1; last >= 0 && points[last].Time.Equal(t) {
+				points[last].Download += h.Download
+				points[last].Upload += h.Upload
+				continue
+			}
+		}
+		points = append(points, SeriesPoint{Time: t, Download: h.Download, Upload: h.Upload})
+	}
+	return points, nil
+}
+
+// the dimensions of the top queries and their columns
+var topColumns = map[string]string{
+	"user":     "user_id",
+	"storage":  "storage_id",
+	"protocol": "protocol",
+	"sharing":  "sharing_id",
+}
+
+type TopItem struct {
+	db.TrafficTopItem
+	// Name is the username of the users and the mount path of the storages
+	Name string `json:"name,omitempty"`
+}
+
+// Top returns the limit users, storages, protocols or shares, by the dimension, with
+// the most selected traffic
+func Top(f db.TrafficStatFilter, by, order string, limit int) ([]TopItem, error) {
+	column, ok := topColumns[by]
+	if !ok {
+		return nil, errors.Errorf("invalid dimension [%s], expect user, storage, protocol or sharing", by)
+	}
+	items, err := db.GetTrafficTop(f, column, order, limit)
+	if err != nil {
+		return nil, err
+	}
+	top := make([]TopItem, len(items))
+	for i, item := range items {
+		top[i].TrafficTopItem = item
+		id, err := strconv.ParseUint(item.Key, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch by {
+		case "user":
+			if user, err := op.GetUserById(uint(id)); err == nil {
+				top[i].Name = user.Username
+			}
+		case "storage":
+			if storage, err := db.GetStorageById(uint(id)); err == nil {
+				top[i].Name = storage.MountPath
+			}
+		}
+	}
+	return top, nil
+}
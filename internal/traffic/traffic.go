@@ -0,0 +1,393 @@
+// Package traffic caps the speed and the traffic of each user and keeps the traffic stats.
+// The traffic is counted in memory and added to the daily traffic of the users and to the
+// hourly stats in the database every minute.
+package traffic
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type Direction int
+
+const (
+	Download Direction = iota // from the server to the user
+	Upload
+)
+
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+const (
+	dayFormat = "2006-01-02"
+	mb        = 1024 * 1024
+)
+
+// Limits are the speed limits in KB/s and the traffic quota in MB of a user,
+// the negative values are unlimited
+type Limits struct {
+	MaxDownloadSpeed   int    `json:"max_download_speed"`
+	MaxUploadSpeed     int    `json:"max_upload_speed"`
+	TrafficQuota       int64  `json:"traffic_quota"`
+	TrafficQuotaPeriod string `json:"traffic_quota_period"`
+}
+
+func (l *Limits) check() error {
+	switch l.TrafficQuotaPeriod {
+	case "", PeriodDay, PeriodMonth:
+		return nil
+	}
+	return errors.Errorf("invalid traffic quota period [%s], expect %s or %s", l.TrafficQuotaPeriod, PeriodDay, PeriodMonth)
+}
+
+var roleNames = map[string]int{
+	"general": model.GENERAL,
+	"guest":   model.GUEST,
+	"admin":   model.ADMIN,
+}
+
+// ParseRoleLimits parses and checks the default limits of the roles, keyed by general, guest
+// and admin. The omitted roles and values are unlimited.
+func ParseRoleLimits(s string) (map[int]Limits, error) {
+	var named map[string]Limits
+	if s != "" {
+		if err := utils.Json.UnmarshalFromString(s, &named); err != nil {
+			return nil, errors.Wrap(err, "invalid role traffic limits")
+		}
+	}
+	limits := make(map[int]Limits, len(named))
+	for name, l := range named {
+		role, ok := roleNames[name]
+		if !ok {
+			return nil, errors.Errorf("unknown role [%s], expect general, guest or admin", name)
+		}
+		if err := l.check(); err != nil {
+			return nil, errors.WithMessagef(err, "role %s", name)
+		}
+		limits[role] = l
+	}
+	return limits, nil
+}
+
+var (
+	parsedMu  sync.Mutex
+	parsedRaw string
+	parsed    map[int]Limits
+)
+
+func roleLimits() map[int]Limits {
+	raw := setting.GetStr(conf.RoleTrafficLimits)
+	parsedMu.Lock()
+	defer parsedMu.Unlock()
+	if raw != parsedRaw || parsed == nil {
+		limits, err := ParseRoleLimits(raw)
+		if err != nil {
+			log.Warnf("ignore the role traffic limits: %+v", err)
+			limits = map[int]Limits{}
+		}
+		parsedRaw, parsed = raw, limits
+	}
+	return parsed
+}
+
+func pick[T int | int64](own, role T) T {
+	if own != 0 {
+		return own
+	}
+	if role != 0 {
+		return role
+	}
+	return -1
+}
+
+// LimitsOf returns the limits in force for the user, its own ones or those of its role
+func LimitsOf(user *model.User) Limits {
+	role := roleLimits()[user.Role]
+	l := Limits{
+		MaxDownloadSpeed:   pick(user.MaxDownloadSpeed, role.MaxDownloadSpeed),
+		MaxUploadSpeed:     pick(user.MaxUploadSpeed, role.MaxUploadSpeed),
+		TrafficQuota:       pick(user.TrafficQuota, role.TrafficQuota),
+		TrafficQuotaPeriod: utils.GetNoneEmpty(user.TrafficQuotaPeriod, role.TrafficQuotaPeriod, PeriodMonth),
+	}
+	if l.TrafficQuotaPeriod != PeriodDay {
+		l.TrafficQuotaPeriod = PeriodMonth
+	}
+	return l
+}
+
+// periodOf returns the start and the end of the quota period which covers now
+func periodOf(period string, now time.Time) (time.Time, time.Time) {
+	y, m, d := now.Date()
+	if period == PeriodDay {
+		start := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 0, 1)
+	}
+	start := time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 1, 0)
+}
+
+// counter holds the traffic of a user in the current quota period and the speed limiters of the user
+type counter struct {
+	mu       sync.Mutex
+	since    string // the first day of the period, empty before loaded
+	download int64
+	upload   int64
+	downKB   int
+	upKB     int
+	down     stream.Limiter
+	up       stream.Limiter
+}
+
+type pendingKey struct {
+	userID uint
+	day    string
+}
+
+var (
+	countersMu sync.Mutex
+	counters   = make(map[uint]*counter)
+	// pending is the traffic not added to the database yet
+	pendingMu sync.Mutex
+	pending   = make(map[pendingKey]*model.UserTraffic)
+)
+
+func getCounter(userID uint) *counter {
+	countersMu.Lock()
+	defer countersMu.Unlock()
+	c, ok := counters[userID]
+	if !ok {
+		c = &counter{}
+		counters[userID] = c
+	}
+	return c
+}
+
+// load reloads the traffic of the period from the database when another period begins,
+// it must be called with c.mu held
+func (c *counter) load(userID uint, since string) {
+	if c.since == since {
+		return
+	}
+	download, upload, err := db.SumUserTraffic(userID, since)
+	if err != nil {
+		log.Warnf("failed load the traffic of user %d: %+v", userID, err)
+		return
+	}
+	pendingMu.Lock()
+	for k, t := range pending {
+		if k.userID == userID && k.day >= since {
+			download += t.Download
+			upload += t.Upload
+		}
+	}
+	pendingMu.Unlock()
+	c.since, c.download, c.upload = since, download, upload
+}
+
+// limiter returns the limiter of the direction updated to kb, or nil when it's unlimited,
+// it must be called with c.mu held
+func (c *counter) limiter(dir Direction, kb int) stream.Limiter {
+	l, cur := &c.down, &c.downKB
+	if dir == Upload {
+		l, cur = &c.up, &c.upKB
+	}
+	if kb < 0 {
+		return nil
+	}
+	if *l == nil {
+		*l = stream.NewSpeedLimiter(kb)
+	} else if *cur != kb {
+		limit, burst := stream.SpeedLimit(kb)
+		(*l).SetLimit(limit)
+		(*l).SetBurst(burst)
+	}
+	*cur = kb
+	return *l
+}
+
+func addPending(userID uint, day string, dir Direction, n int64) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	key := pendingKey{userID: userID, day: day}
+	t, ok := pending[key]
+	if !ok {
+		t = &model.UserTraffic{UserID: userID, Day: day}
+		pending[key] = t
+	}
+	if dir == Download {
+		t.Download += n
+	} else {
+		t.Upload += n
+	}
+}
+
+// WaitN counts n bytes transferred by the user and waits for the speed limiter of the user.
+// It returns errs.TrafficQuotaExceeded once the traffic of the user is over the quota.
+func WaitN(ctx context.Context, user *model.User, dir Direction, n int) error {
+	if user == nil || n <= 0 {
+		return nil
+	}
+	limits := LimitsOf(user)
+	speed := limits.MaxDownloadSpeed
+	if dir == Upload {
+		speed = limits.MaxUploadSpeed
+	}
+	now := time.Now()
+	start, _ := periodOf(limits.TrafficQuotaPeriod, now)
+	c := getCounter(user.ID)
+	c.mu.Lock()
+	c.load(user.ID, start.Format(dayFormat))
+	if dir == Download {
+		c.download += int64(n)
+	} else {
+		c.upload += int64(n)
+	}
+	exceeded := limits.TrafficQuota >= 0 && c.download+c.upload > limits.TrafficQuota*mb
+	addPending(user.ID, now.Format(dayFormat), dir, int64(n))
+	limiter := c.limiter(dir, speed)
+	c.mu.Unlock()
+	if limiter != nil {
+		if err := limiter.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	if exceeded {
+		return errors.WithStack(errs.TrafficQuotaExceeded)
+	}
+	return nil
+}
+
+// Check returns errs.TrafficQuotaExceeded if the user has used up the quota,
+// it's called before a transfer starts
+func Check(user *model.User) error {
+	if user == nil {
+		return nil
+	}
+	limits := LimitsOf(user)
+	if limits.TrafficQuota < 0 {
+		return nil
+	}
+	start, _ := periodOf(limits.TrafficQuotaPeriod, time.Now())
+	c := getCounter(user.ID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load(user.ID, start.Format(dayFormat))
+	if c.download+c.upload >= limits.TrafficQuota*mb {
+		return errors.WithStack(errs.TrafficQuotaExceeded)
+	}
+	return nil
+}
+
+type Usage struct {
+	Limits
+	// Download and Upload are the bytes transferred in the current period
+	Download int64     `json:"download"`
+	Upload   int64     `json:"upload"`
+	ResetAt  time.Time `json:"reset_at"`
+}
+
+// UsageOf returns the limits and the traffic of the user in the current period
+func UsageOf(user *model.User) Usage {
+	limits := LimitsOf(user)
+	start, end := periodOf(limits.TrafficQuotaPeriod, time.Now())
+	c := getCounter(user.ID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load(user.ID, start.Format(dayFormat))
+	return Usage{Limits: limits, Download: c.download, Upload: c.upload, ResetAt: end}
+}
+
+// Flush adds the pending traffic and stats to the database
+func Flush() {
+	flushStats()
+	pendingMu.Lock()
+	flushing := pending
+	pending = make(map[pendingKey]*model.UserTraffic)
+	pendingMu.Unlock()
+	for _, t := range flushing {
+		if err := db.AddUserTraffic(t); err != nil {
+			log.Errorf("failed save the traffic of user %d: %+v", t.UserID, err)
+			addPending(t.UserID, t.Day, Download, t.Download)
+			addPending(t.UserID, t.Day, Upload, t.Upload)
+		}
+	}
+}
+
+var flushCron *cron.Cron
+
+// Start flushes the traffic every minute
+func Start() {
+	if flushCron != nil {
+		return
+	}
+	flushCron = cron.NewCron(time.Minute)
+	flushCron.Do(Flush)
+}
+
+// Reader counts the bytes read as the upload of the user, N is the bytes read
+type Reader struct {
+	io.Reader
+	Ctx  context.Context
+	User *model.User
+	N    int64
+}
+
+func (r *Reader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	if n > 0 {
+		r.N += int64(n)
+		if werr := WaitN(r.Ctx, r.User, Upload, n); werr != nil {
+			return n, werr
+		}
+	}
+	return
+}
+
+func (r *Reader) Close() error {
+	if c, ok := r.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Writer counts the bytes written as the download of the user, N is the bytes written
+type Writer struct {
+	io.Writer
+	Ctx  context.Context
+	User *model.User
+	N    int64
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+	if n > 0 {
+		w.N += int64(n)
+		if werr := WaitN(w.Ctx, w.User, Download, n); werr != nil {
+			return n, werr
+		}
+	}
+	return
+}
+
+func init() {
+	op.RegisterSettingItemHook(conf.RoleTrafficLimits, func(item *model.SettingItem) error {
+		_, err := ParseRoleLimits(item.Value)
+		return err
+	})
+}
@@ -0,0 +1,116 @@
+package traffic
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestLimits(t *testing.T) {
+	for _, s := range []string{
+		`{"owner":{}}`,
+		`{"guest":{"traffic_quota_period":"week"}}`,
+	} {
+		if _, err := ParseRoleLimits(s); err == nil {
+			t.Errorf("expect %s to be invalid", s)
+		}
+	}
+	items := []model.SettingItem{
+		{Key: conf.RoleTrafficLimits, Value: `{"guest":{"max_download_speed":1024,"traffic_quota":100,"traffic_quota_period":"day"}}`, Type: conf.TypeText, Group: model.TRAFFIC},
+	}
+	if err := op.SaveSettingItems(items); err != nil {
+		t.Fatal(err)
+	}
+	guest := LimitsOf(&model.User{Role: model.GUEST, MaxDownloadSpeed: -1})
+	if guest.MaxDownloadSpeed != -1 || guest.MaxUploadSpeed != -1 || guest.TrafficQuota != 100 || guest.TrafficQuotaPeriod != PeriodDay {
+		t.Errorf("expect the limits of the role under those of the user, got %+v", guest)
+	}
+	general := LimitsOf(&model.User{Role: model.GENERAL})
+	if general.MaxDownloadSpeed != -1 || general.TrafficQuota != -1 || general.TrafficQuotaPeriod != PeriodMonth {
+		t.Errorf("expect the general users to be unlimited, got %+v", general)
+	}
+}
+
+func TestQuota(t *testing.T) {
+	user := &model.User{ID: 100, Role: model.GENERAL, TrafficQuota: 1}
+	ctx := context.Background()
+	if err := WaitN(ctx, user, Download, mb/2); err != nil {
+		t.Fatal(err)
+	}
+	if err := WaitN(ctx, user, Upload, mb/2); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(user); !errors.Is(err, errs.TrafficQuotaExceeded) {
+		t.Errorf("expect the quota to be used up, got %v", err)
+	}
+	if err := WaitN(ctx, user, Download, 1); !errors.Is(err, errs.TrafficQuotaExceeded) {
+		t.Errorf("expect the transfer over the quota to fail, got %v", err)
+	}
+
+	// the traffic is loaded back from the database
+	Flush()
+	counters = make(map[uint]*counter)
+	usage := UsageOf(user)
+	if usage.Download != mb/2+1 || usage.Upload != mb/2 {
+		t.Errorf("expect the traffic to be saved, got %+v", usage)
+	}
+	user.TrafficQuota = 2
+	if err := Check(user); err != nil {
+		t.Errorf("expect the raised quota to allow transfers, got %v", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	alice := &model.User{ID: 201}
+	bob := &model.User{ID: 202}
+	Record(alice, Tags{Protocol: ProtocolWebDAV, StorageID: 1}, Download, 300)
+	Record(alice, Tags{Protocol: ProtocolFTP, StorageID: 2}, Upload, 200)
+	Flush()
+	// added to the same hour
+	Record(alice, Tags{Protocol: ProtocolWebDAV, StorageID: 1}, Download, 100)
+	Record(bob, Tags{StorageID: 1, SharingID: "s1"}, Download, 50)
+	Flush()
+
+	f := db.TrafficStatFilter{StorageID: 1}
+	points, err := Series(f, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 || points[0].Download != 450 || points[0].Upload != 0 {
+		t.Errorf("unexpected series %+v", points)
+	}
+	top, err := Top(db.TrafficStatFilter{}, "user", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) < 2 || top[0].Key != "201" || top[0].Download != 400 || top[0].Upload != 200 {
+		t.Errorf("unexpected top users %+v", top)
+	}
+	top, err = Top(db.TrafficStatFilter{UserID: 202}, "protocol", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].Key != ProtocolHTTP {
+		t.Errorf("expect the traffic out of the protocols to be http, got %+v", top)
+	}
+	if _, err = Top(db.TrafficStatFilter{}, "hour", "", 10); err == nil {
+		t.Errorf("expect the dimension to be checked")
+	}
+}
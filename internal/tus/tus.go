@@ -0,0 +1,306 @@
+// Package tus keeps the state of the resumable uploads of the tus 1.0 endpoint,
+// the chunks received so far are staged in a file of the temp dir
+package tus
+
+import (
+	stderrors "errors"
+	"io"
+	"os"
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/buffer"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotFound       = errors.New("upload not found")
+	ErrExpired        = errors.New("upload expired")
+	ErrLocked         = errors.New("upload is being written by another request")
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+)
+
+// Upload is a resumable upload of a file to Path
+type Upload struct {
+	ID     string
+	UserID uint
+	Path   string
+	Size   int64
+	// the Upload-Metadata header of the creation, echoed back by HEAD
+	Metadata string
+	// what to do with the file once it's complete, as the headers of FsStream say
+	AsTask         bool
+	ConflictPolicy string
+	Modified       time.Time
+	Mimetype       string
+	Hashes         map[*utils.HashType]string
+
+	mu      sync.Mutex // held while a chunk is written
+	file    *os.File
+	offset  int64
+	expires time.Time
+	// reading is the file the stream of Finish reads, until the stream is closed
+	reading *os.File
+	// finishing is set from Finish to Done, done is set once the put succeeded
+	finishing bool
+	done      bool
+}
+
+var (
+	uploads   = make(map[string]*Upload)
+	uploadsMu sync.Mutex
+	sweepCron *cron.Cron
+)
+
+func expiration() time.Duration {
+	hours := setting.GetInt(conf.TusUploadExpiration, 24)
+	if hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Create registers the upload and creates the file its chunks are staged in
+func Create(u *Upload) error {
+	if u.Size < 0 {
+		return errors.New("invalid upload length")
+	}
+	f, err := os.CreateTemp(conf.Conf.TempDir, "tus-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp file")
+	}
+	u.ID = random.String(32)
+	u.file = f
+	u.expires = time.Now().Add(expiration())
+	uploadsMu.Lock()
+	uploads[u.ID] = u
+	uploadsMu.Unlock()
+	return nil
+}
+
+// Get returns the upload of the user
+func Get(id string, userId uint) (*Upload, error) {
+	uploadsMu.Lock()
+	u, ok := uploads[id]
+	uploadsMu.Unlock()
+	if !ok || u.UserID != userId {
+		return nil, ErrNotFound
+	}
+	if time.Now().After(u.Expires()) {
+		return nil, ErrExpired
+	}
+	return u, nil
+}
+
+func (u *Upload) Name() string {
+	return stdpath.Base(u.Path)
+}
+
+func (u *Upload) Offset() int64 {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	return u.offset
+}
+
+func (u *Upload) Expires() time.Time {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	return u.expires
+}
+
+func (u *Upload) Complete() bool {
+	return u.Offset() == u.Size
+}
+
+// Write appends the chunk read from r at offset, which must be the current offset.
+// What is read before an error is kept, so that the client can resume from there.
+func (u *Upload) Write(offset int64, r io.Reader) (int64, error) {
+	if !u.mu.TryLock() {
+		return u.Offset(), ErrLocked
+	}
+	defer u.mu.Unlock()
+	// the upload being put is not written, its data is sent
+	if u.isFinishing() {
+		return u.Offset(), ErrLocked
+	}
+	current := u.Offset()
+	if offset != current {
+		return current, ErrOffsetMismatch
+	}
+	n, err := utils.CopyWithBuffer(io.NewOffsetWriter(u.file, offset), io.LimitReader(r, u.Size-offset))
+	uploadsMu.Lock()
+	u.offset += n
+	u.expires = time.Now().Add(expiration())
+	current = u.offset
+	uploadsMu.Unlock()
+	return current, err
+}
+
+func remove(u *Upload) bool {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	if _, ok := uploads[u.ID]; !ok {
+		return false
+	}
+	delete(uploads, u.ID)
+	return true
+}
+
+func deleteFile(f *os.File) error {
+	return stderrors.Join(f.Close(), os.RemoveAll(f.Name()))
+}
+
+func (u *Upload) isFinishing() bool {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	return u.finishing
+}
+
+// Terminate drops the upload and the data received, but not while it's being put
+func Terminate(u *Upload) error {
+	uploadsMu.Lock()
+	finishing := u.finishing
+	uploadsMu.Unlock()
+	if finishing {
+		return ErrLocked
+	}
+	if !remove(u) {
+		return ErrNotFound
+	}
+	return deleteFile(u.file)
+}
+
+// Finish returns the complete upload as a stream to put, Done must be called with the result
+// of the put. The upload is kept meanwhile, so that a failed put can be finished again.
+func Finish(u *Upload) (*stream.FileStream, error) {
+	if !u.mu.TryLock() {
+		return nil, ErrLocked
+	}
+	defer u.mu.Unlock()
+	if !u.Complete() {
+		return nil, errors.Errorf("upload is incomplete: %d of %d bytes", u.Offset(), u.Size)
+	}
+	uploadsMu.Lock()
+	if _, ok := uploads[u.ID]; !ok {
+		uploadsMu.Unlock()
+		return nil, ErrNotFound
+	}
+	if u.finishing {
+		uploadsMu.Unlock()
+		return nil, ErrLocked
+	}
+	u.finishing = true
+	uploadsMu.Unlock()
+	f, err := os.Open(u.file.Name())
+	if err != nil {
+		Done(u, err)
+		return nil, errors.WithStack(err)
+	}
+	file, err := buffer.NewPeekFile(&buffer.Reader{}, f)
+	if err != nil {
+		_ = f.Close()
+		Done(u, err)
+		return nil, err
+	}
+	uploadsMu.Lock()
+	u.reading = f
+	uploadsMu.Unlock()
+	mimetype := u.Mimetype
+	if mimetype == "" {
+		mimetype = utils.GetMimeType(u.Name())
+	}
+	s := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     u.Name(),
+			Size:     u.Size,
+			Modified: u.Modified,
+			HashInfo: utils.NewHashInfoByMap(u.Hashes),
+		},
+		Reader:       file,
+		Mimetype:     mimetype,
+		WebPutAsTask: u.AsTask,
+	}
+	s.Add(utils.CloseFunc(func() error {
+		return u.closeReading(f)
+	}))
+	return s, nil
+}
+
+// closeReading closes the file the stream read, the staged file is deleted if the put succeeded
+func (u *Upload) closeReading(f *os.File) error {
+	uploadsMu.Lock()
+	if u.reading != f {
+		uploadsMu.Unlock()
+		return nil
+	}
+	u.reading = nil
+	done := u.done
+	uploadsMu.Unlock()
+	err := f.Close()
+	if done {
+		err = stderrors.Join(err, deleteFile(u.file))
+	}
+	return err
+}
+
+// Done ends the put of Finish. The upload is dropped if the put succeeded, and the staged file
+// is deleted once the stream is closed, as a task may still read it. Otherwise the upload
+// is kept until it expires, the client finishes it again by a PATCH at its end.
+func Done(u *Upload, err error) {
+	uploadsMu.Lock()
+	if err != nil {
+		u.finishing = false
+		u.expires = time.Now().Add(expiration())
+		f := u.reading
+		uploadsMu.Unlock()
+		if f != nil {
+			_ = u.closeReading(f)
+		}
+		return
+	}
+	u.done = true
+	delete(uploads, u.ID)
+	reading := u.reading != nil
+	uploadsMu.Unlock()
+	if !reading {
+		if e := deleteFile(u.file); e != nil {
+			log.Warnf("failed to delete the staged upload of %s: %+v", u.Path, e)
+		}
+	}
+}
+
+// PurgeExpired deletes the uploads which were not written for longer than the expiration
+func PurgeExpired() {
+	now := time.Now()
+	var expired []*Upload
+	uploadsMu.Lock()
+	for id, u := range uploads {
+		if now.After(u.expires) && !u.finishing {
+			expired = append(expired, u)
+			delete(uploads, id)
+		}
+	}
+	uploadsMu.Unlock()
+	for _, u := range expired {
+		if err := deleteFile(u.file); err != nil {
+			log.Warnf("failed to delete the expired upload of %s: %+v", u.Path, err)
+		}
+	}
+}
+
+func StartExpirationScheduler() {
+	if sweepCron != nil {
+		return
+	}
+	sweepCron = cron.NewCron(10 * time.Minute)
+	sweepCron.Do(PurgeExpired)
+}
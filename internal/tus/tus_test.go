@@ -0,0 +1,129 @@
+package tus
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+// brokenReader returns an error after the data, like a dropped connection
+type brokenReader struct {
+	io.Reader
+}
+
+func (r brokenReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func TestUpload(t *testing.T) {
+	conf.Conf.TempDir = t.TempDir()
+	u := &Upload{UserID: 1, Path: "/local/dir/a.txt", Size: 10}
+	if err := Create(u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(u.ID, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expect the upload to be hidden from other users, got %v", err)
+	}
+	offset, err := u.Write(0, brokenReader{bytes.NewReader([]byte("0123"))})
+	if !errors.Is(err, io.ErrUnexpectedEOF) || offset != 4 {
+		t.Fatalf("expect the interrupted chunk to be kept, got offset %d: %v", offset, err)
+	}
+	if _, err = u.Write(2, bytes.NewReader([]byte("23456789"))); !errors.Is(err, ErrOffsetMismatch) {
+		t.Errorf("expect an offset mismatch, got %v", err)
+	}
+	if _, err = Finish(u); err == nil {
+		t.Errorf("expect an incomplete upload not to finish")
+	}
+	// the part over the length is not written
+	if offset, err = u.Write(4, bytes.NewReader([]byte("456789xx"))); err != nil || offset != 10 || !u.Complete() {
+		t.Fatalf("expect the upload to be complete, got offset %d: %v", offset, err)
+	}
+	s, err := Finish(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Finish(u); !errors.Is(err, ErrLocked) {
+		t.Errorf("expect the upload being put not to finish twice, got %v", err)
+	}
+	if _, err = u.Write(10, bytes.NewReader(nil)); !errors.Is(err, ErrLocked) {
+		t.Errorf("expect the upload being put not to be written, got %v", err)
+	}
+	if err = Terminate(u); !errors.Is(err, ErrLocked) {
+		t.Errorf("expect the upload being put not to be terminated, got %v", err)
+	}
+	// the failed put is kept to be finished again
+	_ = s.Close()
+	Done(u, errors.New("put failed"))
+	if got, err := Get(u.ID, 1); err != nil || !got.Complete() {
+		t.Fatalf("expect the upload to be kept after a failed put, got %v", err)
+	}
+	if s, err = Finish(u); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(s)
+	if err != nil || string(data) != "0123456789" || s.GetName() != "a.txt" {
+		t.Errorf("unexpected stream %s of %s: %v", data, s.GetName(), err)
+	}
+	// the staged file is kept until the stream is closed, as a task may read it later
+	Done(u, nil)
+	if _, err = Get(u.ID, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expect the finished upload to be removed, got %v", err)
+	}
+	if entries, _ := os.ReadDir(conf.Conf.TempDir); len(entries) != 1 {
+		t.Errorf("expect the staged file to be kept while it's read, got %d files", len(entries))
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(conf.Conf.TempDir); len(entries) != 0 {
+		t.Errorf("expect the staged file to be deleted, got %d files", len(entries))
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	conf.Conf.TempDir = t.TempDir()
+	expired, alive := &Upload{UserID: 1, Size: 1}, &Upload{UserID: 1, Size: 1}
+	for _, u := range []*Upload{expired, alive} {
+		if err := Create(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expired.expires = time.Now().Add(-time.Second)
+	if _, err := Get(expired.ID, 1); !errors.Is(err, ErrExpired) {
+		t.Errorf("expect the upload to be expired, got %v", err)
+	}
+	PurgeExpired()
+	if _, err := Get(expired.ID, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expect the expired upload to be purged, got %v", err)
+	}
+	if entries, _ := os.ReadDir(conf.Conf.TempDir); len(entries) != 1 {
+		t.Errorf("expect only the alive upload to be staged, got %d files", len(entries))
+	}
+	if err := Terminate(alive); err != nil {
+		t.Fatal(err)
+	}
+	if err := Terminate(alive); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expect the upload to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/sign"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// the deliveries kept for each webhook
+	keepDeliveries = 100
+	workers        = 2
+	// the part of the response kept in the delivery
+	maxResponse = 1024
+	// the signature expires after this, so that a captured request can't be replayed later
+	signatureTTL = 5 * time.Minute
+)
+
+var (
+	// the delay before the first retry, doubled for each next one up to maxBackoff
+	retryBackoff = 10 * time.Second
+	maxBackoff   = 10 * time.Minute
+	client       = &http.Client{Timeout: 30 * time.Second}
+)
+
+type delivery struct {
+	hook   model.Webhook
+	record *model.WebhookDelivery
+}
+
+var queue = make(chan *delivery, 1024)
+
+func startWorkers() {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for d := range queue {
+				attempt(d)
+			}
+		}()
+	}
+}
+
+// send records a delivery of the payload and queues it
+func send(hook *model.Webhook, event, body string) (*model.WebhookDelivery, error) {
+	record := &model.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     event,
+		Payload:   body,
+		CreatedAt: time.Now(),
+	}
+	if err := db.CreateWebhookDelivery(record); err != nil {
+		return nil, err
+	}
+	enqueue(&delivery{hook: *hook, record: record})
+	return record, nil
+}
+
+func enqueue(d *delivery) {
+	select {
+	case queue <- d:
+	default:
+		d.record.Error = "the delivery queue is full"
+		finish(d)
+	}
+}
+
+// attempt posts the payload once and plans the next retry if it fails
+func attempt(d *delivery) {
+	r := d.record
+	r.Attempts++
+	r.StatusCode, r.Response, r.Error = 0, "", ""
+	code, resp, err := post(&d.hook, r)
+	r.StatusCode, r.Response = code, resp
+	if err == nil && (code < 200 || code >= 300) {
+		err = errors.Errorf("unexpected status code %d", code)
+	}
+	if err == nil {
+		r.Succeeded = true
+		finish(d)
+		return
+	}
+	r.Error = err.Error()
+	if r.Attempts > d.hook.MaxRetry {
+		finish(d)
+		return
+	}
+	if e := db.UpdateWebhookDelivery(r); e != nil {
+		log.Errorf("failed update webhook delivery: %+v", e)
+	}
+	backoff := retryBackoff << (r.Attempts - 1)
+	if backoff > maxBackoff || backoff <= 0 {
+		backoff = maxBackoff
+	}
+	time.AfterFunc(backoff, func() { enqueue(d) })
+}
+
+func finish(d *delivery) {
+	now := time.Now()
+	d.record.FinishedAt = &now
+	if err := db.UpdateWebhookDelivery(d.record); err != nil {
+		log.Errorf("failed update webhook delivery: %+v", err)
+	}
+	if err := db.DeleteOldWebhookDeliveries(d.hook.ID, keepDeliveries); err != nil {
+		log.Errorf("failed delete old deliveries of webhook %d: %+v", d.hook.ID, err)
+	}
+}
+
+// post sends the payload, signed with the secret of the webhook in the X-OpenList-Signature
+// header. The signature is pkg/sign's HMAC-SHA256 of the body with its expiry:
+// base64url(hmac(body + ":" + expire)) + ":" + expire.
+func post(hook *model.Webhook, r *model.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, hook.URL, bytes.NewBufferString(r.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OpenList-Webhook/"+conf.Version)
+	req.Header.Set("X-OpenList-Event", r.Event)
+	req.Header.Set("X-OpenList-Delivery", strconv.FormatUint(uint64(r.ID), 10))
+	if hook.Secret != "" {
+		expire := time.Now().Add(signatureTTL).Unix()
+		req.Header.Set("X-OpenList-Signature", sign.NewHMACSign([]byte(hook.Secret)).Sign(r.Payload, expire))
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	resp, _ := io.ReadAll(io.LimitReader(res.Body, maxResponse))
+	return res.StatusCode, string(resp), nil
+}
+
+// Test sends a ping to the webhook and waits for the first attempt
+func Test(hook *model.Webhook) (*model.WebhookDelivery, error) {
+	body := fmt.Sprintf(`{"event":%q,"time":%q,"data":{"webhook_id":%d}}`,
+		model.WebhookPing, time.Now().Format(time.RFC3339Nano), hook.ID)
+	record := &model.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     model.WebhookPing,
+		Payload:   body,
+		CreatedAt: time.Now(),
+	}
+	if err := db.CreateWebhookDelivery(record); err != nil {
+		return nil, err
+	}
+	// a ping is not retried
+	h := *hook
+	h.MaxRetry = 0
+	attempt(&delivery{hook: h, record: record})
+	return record, nil
+}
+
+// Redeliver sends the payload of a past delivery again as a new delivery
+func Redeliver(id uint) (*model.WebhookDelivery, error) {
+	old, err := db.GetWebhookDeliveryById(id)
+	if err != nil {
+		return nil, err
+	}
+	hook, err := db.GetWebhookById(old.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	return send(hook, old.Event, old.Payload)
+}
@@ -0,0 +1,192 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/tache"
+	log "github.com/sirupsen/logrus"
+)
+
+// the tasks are checked at this interval to notice the ones which are over
+const taskPollInterval = 5 * time.Second
+
+var started bool
+
+// Start loads the webhooks, resumes the deliveries interrupted by the last stop
+// and starts listening to the events
+func Start() {
+	if started {
+		return
+	}
+	started = true
+	load()
+	startWorkers()
+	resume()
+
+	fsEvents := make(chan op.FsEvent, 1024)
+	op.SubscribeFsEvents(func(e op.FsEvent) {
+		select {
+		case fsEvents <- e:
+		default:
+			log.Warnf("webhook: too many fs events, dropped %s of %s", e.Type, e.Path)
+		}
+	})
+	go func() {
+		for e := range fsEvents {
+			notifyFsEvent(e)
+		}
+	}()
+	op.RegisterStorageStatusHook(func(storage driver.Driver, oldStatus string) {
+		s := storage.GetStorage()
+		go Notify(model.WebhookStorageStatus, map[string]any{
+			"id":         s.ID,
+			"mount_path": s.MountPath,
+			"driver":     s.Driver,
+			"status":     s.Status,
+			"old_status": oldStatus,
+		}, s.MountPath)
+	})
+	w := &taskWatcher{states: make(map[string]tache.State)}
+	cron.NewCron(taskPollInterval).Do(func() {
+		w.check(taskSources())
+	})
+}
+
+func resume() {
+	deliveries, err := db.GetUnfinishedWebhookDeliveries()
+	if err != nil {
+		log.Errorf("failed get unfinished webhook deliveries: %+v", err)
+		return
+	}
+	for i := range deliveries {
+		d := &deliveries[i]
+		hook, err := db.GetWebhookById(d.WebhookID)
+		if err != nil {
+			d.Error = "the webhook is deleted"
+			finish(&delivery{hook: model.Webhook{ID: d.WebhookID}, record: d})
+			continue
+		}
+		enqueue(&delivery{hook: *hook, record: d})
+	}
+}
+
+func notifyFsEvent(e op.FsEvent) {
+	switch e.Type {
+	case op.FsCreated, op.FsUpdated:
+		if !e.IsDir {
+			Notify(model.WebhookUpload, e, e.Path)
+		}
+	case op.FsDeleted:
+		Notify(model.WebhookDelete, e, e.Path)
+	case op.FsRenamed:
+		Notify(model.WebhookRename, e, e.Path, e.OldPath)
+	}
+}
+
+// LoginFailed is called when a user fails to log in
+func LoginFailed(username, ip, userAgent string) {
+	go Notify(model.WebhookLoginFailed, map[string]any{
+		"username":   username,
+		"ip":         ip,
+		"user_agent": userAgent,
+	})
+}
+
+type TaskInfo struct {
+	Kind       string     `json:"kind"`
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	State      string     `json:"state"`
+	Error      string     `json:"error,omitempty"`
+	Creator    string     `json:"creator,omitempty"`
+	StartTime  *time.Time `json:"start_time"`
+	EndTime    *time.Time `json:"end_time"`
+	TotalBytes int64      `json:"total_bytes"`
+}
+
+// taskWatcher notices the tasks which succeeded or failed since the last check
+type taskWatcher struct {
+	states map[string]tache.State
+	seeded bool
+}
+
+func (w *taskWatcher) check(sources map[string][]task.TaskExtensionInfo) {
+	seen := make(map[string]struct{}, len(w.states))
+	for kind, tasks := range sources {
+		for _, t := range tasks {
+			key := kind + "/" + t.GetID()
+			seen[key] = struct{}{}
+			state := t.GetState()
+			old, known := w.states[key]
+			w.states[key] = state
+			// the tasks over before the first check, like the ones restored at startup, are ignored
+			if !w.seeded || (known && old == state) {
+				continue
+			}
+			event := ""
+			switch state {
+			case tache.StateSucceeded:
+				event = model.WebhookTaskSucceeded
+			case tache.StateFailed:
+				event = model.WebhookTaskFailed
+			default:
+				continue
+			}
+			info := TaskInfo{
+				Kind:       kind,
+				ID:         t.GetID(),
+				Name:       t.GetName(),
+				State:      event[len("task_"):],
+				StartTime:  t.GetStartTime(),
+				EndTime:    t.GetEndTime(),
+				TotalBytes: t.GetTotalBytes(),
+			}
+			if err := t.GetErr(); err != nil {
+				info.Error = err.Error()
+			}
+			if creator := t.GetCreator(); creator != nil {
+				info.Creator = creator.Username
+			}
+			Notify(event, info)
+		}
+	}
+	for key := range w.states {
+		if _, ok := seen[key]; !ok {
+			delete(w.states, key)
+		}
+	}
+	w.seeded = true
+}
+
+func addTasks[T task.TaskExtensionInfo](sources map[string][]task.TaskExtensionInfo, kind string, m *tache.Manager[T]) {
+	if m == nil {
+		return
+	}
+	for _, t := range m.GetAll() {
+		sources[kind] = append(sources[kind], t)
+	}
+}
+
+// taskSources returns the tasks by kind, named like the task routes
+func taskSources() map[string][]task.TaskExtensionInfo {
+	sources := make(map[string][]task.TaskExtensionInfo)
+	addTasks(sources, "upload", fs.UploadTaskManager)
+	addTasks(sources, "copy", fs.CopyTaskManager)
+	addTasks(sources, "move", fs.MoveTaskManager)
+	addTasks(sources, "offline_download", tool.DownloadTaskManager)
+	addTasks(sources, "offline_download_transfer", tool.TransferTaskManager)
+	addTasks(sources, "decompress", fs.ArchiveDownloadTaskManager)
+	addTasks(sources, "decompress_upload", fs.ArchiveContentUploadTaskManager.Manager)
+	addTasks(sources, "sync", fs.SyncTaskManager)
+	addTasks(sources, "dedupe", fs.DedupeTaskManager)
+	addTasks(sources, "checksum", fs.ChecksumTaskManager)
+	return sources
+}
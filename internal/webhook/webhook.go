@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// hooks are the saved webhooks, loaded again when they change
+var hooks atomic.Pointer[[]model.Webhook]
+
+func load() {
+	list, err := db.GetWebhooks()
+	if err != nil {
+		log.Errorf("failed get webhooks: %+v", err)
+		return
+	}
+	hooks.Store(&list)
+}
+
+func validate(hook *model.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("invalid url [%s]", hook.URL)
+	}
+	for _, event := range splitEvents(hook.Events) {
+		if !utils.SliceContains(model.WebhookEvents, event) {
+			return errors.Errorf("unknown event [%s]", event)
+		}
+	}
+	if hook.MaxRetry < 0 {
+		return errors.New("max_retry can't be negative")
+	}
+	return nil
+}
+
+func GetWebhooks() ([]model.Webhook, error) {
+	return db.GetWebhooks()
+}
+
+func GetWebhookById(id uint) (*model.Webhook, error) {
+	return db.GetWebhookById(id)
+}
+
+func CreateWebhook(hook *model.Webhook) error {
+	if err := validate(hook); err != nil {
+		return err
+	}
+	if err := db.CreateWebhook(hook); err != nil {
+		return err
+	}
+	load()
+	return nil
+}
+
+func UpdateWebhook(hook *model.Webhook) (*model.Webhook, error) {
+	old, err := db.GetWebhookById(hook.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = validate(hook); err != nil {
+		return nil, err
+	}
+	hook.CreatedAt = old.CreatedAt
+	if err = db.UpdateWebhook(hook); err != nil {
+		return nil, err
+	}
+	load()
+	return hook, nil
+}
+
+func DeleteWebhookById(id uint) error {
+	if err := db.DeleteWebhookById(id); err != nil {
+		return err
+	}
+	load()
+	return nil
+}
+
+func GetDeliveries(hookId uint, pageIndex, pageSize int) ([]model.WebhookDelivery, int64, error) {
+	return db.GetWebhookDeliveries(hookId, pageIndex, pageSize)
+}
+
+func splitEvents(events string) []string {
+	return utils.SliceFilter(strings.Split(events, ","), func(s string) bool { return s != "" })
+}
+
+// matches reports whether the webhook wants the event, one of the paths must be under
+// one of its prefixes when both are given
+func matches(hook *model.Webhook, event string, paths []string) bool {
+	if hook.Disabled {
+		return false
+	}
+	if events := splitEvents(hook.Events); len(events) > 0 && !utils.SliceContains(events, event) {
+		return false
+	}
+	prefixes := utils.SliceFilter(strings.Split(hook.Paths, "\n"), func(s string) bool { return strings.TrimSpace(s) != "" })
+	if len(prefixes) == 0 || len(paths) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		prefix = utils.FixAndCleanPath(strings.TrimSpace(prefix))
+		for _, path := range paths {
+			if path != "" && utils.IsSubPath(prefix, path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Payload is the body posted to the webhooks
+type Payload struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+// Notify sends the event to the webhooks wanting it, paths are the paths the event is about
+func Notify(event string, data any, paths ...string) {
+	list := hooks.Load()
+	if list == nil {
+		return
+	}
+	var body []byte
+	for i := range *list {
+		hook := (*list)[i]
+		if !matches(&hook, event, paths) {
+			continue
+		}
+		if body == nil {
+			var err error
+			body, err = json.Marshal(Payload{Event: event, Time: time.Now(), Data: data})
+			if err != nil {
+				log.Errorf("failed marshal webhook payload of %s: %+v", event, err)
+				return
+			}
+		}
+		if _, err := send(&hook, event, string(body)); err != nil {
+			log.Errorf("failed send %s to webhook %d: %+v", event, hook.ID, err)
+		}
+	}
+}
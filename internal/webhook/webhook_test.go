@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/sign"
+	"github.com/OpenListTeam/tache"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	retryBackoff = 10 * time.Millisecond
+	startWorkers()
+}
+
+type received struct {
+	event     string
+	signature string
+	body      []byte
+}
+
+// receiver fails the first request, then records the next ones
+func receiver(t *testing.T) (*httptest.Server, func() []received) {
+	var mu sync.Mutex
+	var requests []received
+	failed := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !failed {
+			failed = true
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, received{r.Header.Get("X-OpenList-Event"), r.Header.Get("X-OpenList-Signature"), body})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), requests...)
+	}
+}
+
+func waitDeliveries(t *testing.T, hookId uint, n int) []model.WebhookDelivery {
+	for i := 0; i < 200; i++ {
+		deliveries, _, err := GetDeliveries(hookId, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		finished := 0
+		for _, d := range deliveries {
+			if d.FinishedAt != nil {
+				finished++
+			}
+		}
+		if len(deliveries) == n && finished == n {
+			return deliveries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expect %d finished deliveries of webhook %d", n, hookId)
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	srv, requests := receiver(t)
+	if err := CreateWebhook(&model.Webhook{Name: "bad", URL: "ftp://host", Events: model.WebhookUpload}); err == nil {
+		t.Errorf("expect the url to be rejected")
+	}
+	hook := &model.Webhook{Name: "uploads", URL: srv.URL, Secret: "secret", Events: model.WebhookUpload, Paths: "/data\n", MaxRetry: 2}
+	if err := CreateWebhook(hook); err != nil {
+		t.Fatal(err)
+	}
+	Notify(model.WebhookUpload, map[string]string{"path": "/other/a.txt"}, "/other/a.txt")
+	Notify(model.WebhookDelete, map[string]string{"path": "/data/a.txt"}, "/data/a.txt")
+	Notify(model.WebhookUpload, map[string]string{"path": "/data/a.txt"}, "/data/a.txt")
+
+	deliveries := waitDeliveries(t, hook.ID, 1)
+	if d := deliveries[0]; !d.Succeeded || d.Attempts != 2 || d.StatusCode != 200 {
+		t.Errorf("expect the delivery to succeed on retry, got %+v", d)
+	}
+	reqs := requests()
+	if len(reqs) != 1 || reqs[0].event != model.WebhookUpload {
+		t.Fatalf("unexpected requests: %+v", reqs)
+	}
+	if err := sign.NewHMACSign([]byte("secret")).Verify(string(reqs[0].body), reqs[0].signature); err != nil {
+		t.Errorf("invalid signature: %v", err)
+	}
+	var payload struct {
+		Event string            `json:"event"`
+		Data  map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(reqs[0].body, &payload); err != nil || payload.Data["path"] != "/data/a.txt" {
+		t.Errorf("unexpected payload %s: %v", reqs[0].body, err)
+	}
+}
+
+type fakeTask struct {
+	task.TaskExtension
+}
+
+func (t *fakeTask) GetName() string   { return "fake" }
+func (t *fakeTask) GetStatus() string { return "" }
+func (t *fakeTask) Run() error        { return nil }
+
+func TestTaskEvents(t *testing.T) {
+	srv, requests := receiver(t)
+	hook := &model.Webhook{Name: "tasks", URL: srv.URL, Events: model.WebhookTaskSucceeded + "," + model.WebhookTaskFailed, MaxRetry: 1}
+	if err := CreateWebhook(hook); err != nil {
+		t.Fatal(err)
+	}
+	done, running := &fakeTask{}, &fakeTask{}
+	done.SetID("1")
+	done.SetState(tache.StateSucceeded)
+	running.SetID("2")
+	running.SetState(tache.StateRunning)
+	w := &taskWatcher{states: make(map[string]tache.State)}
+	sources := map[string][]task.TaskExtensionInfo{"copy": {done, running}}
+	w.check(sources)
+	running.SetState(tache.StateFailed)
+	w.check(sources)
+	w.check(sources)
+
+	waitDeliveries(t, hook.ID, 1)
+	reqs := requests()
+	if len(reqs) != 1 || reqs[0].event != model.WebhookTaskFailed {
+		t.Fatalf("expect only the failure of task 2, got %+v", reqs)
+	}
+	var payload struct {
+		Data TaskInfo `json:"data"`
+	}
+	if err := json.Unmarshal(reqs[0].body, &payload); err != nil || payload.Data.ID != "2" || payload.Data.Kind != "copy" {
+		t.Errorf("unexpected payload %s: %v", reqs[0].body, err)
+	}
+}
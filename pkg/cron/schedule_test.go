@@ -0,0 +1,40 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	from := time.Date(2024, 2, 28, 23, 59, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 2, 29, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 1-5", time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"5/20 3 * 3 *", time.Date(2024, 3, 1, 3, 5, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("failed to parse %s: %v", tt.expr, err)
+			continue
+		}
+		if next := s.Next(from); !next.Equal(tt.next) {
+			t.Errorf("%s: expect %v, got %v", tt.expr, tt.next, next)
+		}
+	}
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("expect %q to be invalid", expr)
+		}
+	}
+}
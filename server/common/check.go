@@ -8,6 +8,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/dlclark/regexp2"
 )
@@ -58,6 +59,18 @@ func CanAccess(user *model.User, meta *model.Meta, reqPath string, password stri
 	return meta.Password == password
 }
 
+// NeedEnroll2FA reports whether the user must enroll TOTP or WebAuthn before
+// being allowed to use anything other than the enrollment endpoints
+func NeedEnroll2FA(user *model.User) bool {
+	if user.IsGuest() || user.HasTwoFactor() {
+		return false
+	}
+	if setting.GetBool(conf.Require2FAForAll) {
+		return true
+	}
+	return user.IsAdmin() && setting.GetBool(conf.Require2FAForAdmin)
+}
+
 // ShouldProxy TODO need optimize
 // when should be proxy?
 // 1. config.MustProxy()
@@ -5,13 +5,55 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/gin-gonic/gin"
 )
 
-func Sign(obj model.Obj, parent string, encrypt bool) string {
-	if obj.IsDir() || (!encrypt && !setting.GetBool(conf.SignAll)) {
+// signUserID returns the ID the links of the user are signed for, 0 for the guest
+func signUserID(user *model.User) uint {
+	if user == nil || user.IsGuest() {
+		return 0
+	}
+	return user.ID
+}
+
+// SignPath returns the sign of the link of path for the user, or empty if the link needs none.
+// The links of the users other than the guest are always signed, so that their downloads
+// are counted as their traffic.
+func SignPath(user *model.User, path string, required bool) string {
+	uid := signUserID(user)
+	if uid == 0 && !required {
+		return ""
+	}
+	return sign.SignUser(path, uid)
+}
+
+// SignArchivePath is SignPath for the links of the archive contents
+func SignArchivePath(user *model.User, path string, required bool) string {
+	uid := signUserID(user)
+	if uid == 0 && !required {
+		return ""
+	}
+	return sign.SignArchiveUser(path, uid)
+}
+
+// SignUser puts the user a verified sign is for into the request, so that the download
+// is counted for the user. The disabled users are left out.
+func SignUser(c *gin.Context, s string) {
+	uid := sign.UserID(s)
+	if uid == 0 {
+		return
+	}
+	if user, err := op.GetUserById(uid); err == nil && !user.Disabled {
+		GinWithValue(c, conf.UserKey, user)
+	}
+}
+
+func Sign(user *model.User, obj model.Obj, parent string, encrypt bool) string {
+	if obj.IsDir() {
 		return ""
 	}
-	return sign.Sign(stdpath.Join(parent, obj.GetName()))
+	return SignPath(user, stdpath.Join(parent, obj.GetName()), encrypt || setting.GetBool(conf.SignAll))
 }
@@ -18,7 +18,9 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/OpenList/v4/server/ftp"
 	ftpserver "github.com/fclairamb/ftpserverlib"
 )
@@ -132,6 +134,9 @@ func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string)
 	if userObj.Disabled || !userObj.CanFTPAccess() {
 		return nil, errors.New("user is not allowed to access via FTP")
 	}
+	if common.NeedEnroll2FA(userObj) {
+		return nil, errors.New("two-factor authentication is required, enroll 2FA or WebAuthn first")
+	}
 
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, conf.UserKey, userObj)
@@ -142,6 +147,7 @@ func (d *FtpMainDriver) AuthUser(cc ftpserver.ClientContext, user, pass string)
 	}
 	ctx = context.WithValue(ctx, conf.ClientIPKey, cc.RemoteAddr().String())
 	ctx = context.WithValue(ctx, conf.ProxyHeaderKey, d.proxyHeader)
+	ctx = traffic.WithTags(ctx, traffic.Tags{Protocol: traffic.ProtocolFTP})
 	return ftp.NewAferoAdapter(ctx), nil
 }
 
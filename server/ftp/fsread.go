@@ -8,12 +8,14 @@ import (
 	"os"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/blockcache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/pkg/errors"
 )
@@ -36,6 +38,9 @@ func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownl
 	if !common.CanAccess(user, meta, reqPath, ctx.Value(conf.MetaPassKey).(string)) {
 		return nil, errs.PermissionDenied
 	}
+	if err = traffic.Check(user); err != nil {
+		return nil, err
+	}
 
 	// directly use proxy
 	header, _ := ctx.Value(conf.ProxyHeaderKey).(http.Header)
@@ -44,6 +49,9 @@ func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownl
 	if err != nil {
 		return nil, err
 	}
+	if storage, err := fs.GetStorage(reqPath, &fs.GetStoragesArgs{}); err == nil {
+		link = blockcache.Link(storage.GetStorage(), reqPath, obj, "", link)
+	}
 	ss, err := stream.NewSeekableStream(&stream.FileStream{
 		Obj: obj,
 		Ctx: ctx,
@@ -57,7 +65,28 @@ func OpenDownload(ctx context.Context, reqPath string, offset int64) (*FileDownl
 		_ = ss.Close()
 		return nil, err
 	}
-	return &FileDownloadProxy{File: reader, Closer: ss, ctx: ctx}, nil
+	return &FileDownloadProxy{File: reader, Closer: ss, ctx: withStorageTag(ctx, reqPath)}, nil
+}
+
+// withStorageTag tags the traffic of ctx with the storage of the path
+func withStorageTag(ctx context.Context, path string) context.Context {
+	storage, _, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return ctx
+	}
+	tags := traffic.TagsFrom(ctx)
+	tags.StorageID = storage.GetStorage().ID
+	return traffic.WithTags(ctx, tags)
+}
+
+// waitN waits for the limiter and the limits of the user of ctx, and counts the traffic into the stats
+func waitN(ctx context.Context, limiter stream.Limiter, dir traffic.Direction, n int) error {
+	if err := limiter.WaitN(ctx, n); err != nil {
+		return err
+	}
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	traffic.Record(user, traffic.TagsFrom(ctx), dir, int64(n))
+	return traffic.WaitN(ctx, user, dir, n)
 }
 
 func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
@@ -65,7 +94,7 @@ func (f *FileDownloadProxy) Read(p []byte) (n int, err error) {
 	if err != nil {
 		return n, err
 	}
-	err = stream.ClientDownloadLimit.WaitN(f.ctx, n)
+	err = waitN(f.ctx, stream.ClientDownloadLimit, traffic.Download, n)
 	return n, err
 }
 
@@ -74,7 +103,7 @@ func (f *FileDownloadProxy) ReadAt(p []byte, off int64) (n int, err error) {
 	if err != nil {
 		return n, err
 	}
-	err = stream.ClientDownloadLimit.WaitN(f.ctx, n)
+	err = waitN(f.ctx, stream.ClientDownloadLimit, traffic.Download, n)
 	return n, err
 }
 
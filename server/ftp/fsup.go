@@ -17,6 +17,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	ftpserver "github.com/fclairamb/ftpserverlib"
@@ -43,7 +44,7 @@ func uploadAuth(ctx context.Context, path string) error {
 		((user.CanFTPManage() && user.CanWrite()) || common.CanWrite(meta, stdpath.Dir(path)))) {
 		return errs.PermissionDenied
 	}
-	return nil
+	return traffic.Check(user)
 }
 
 func OpenUpload(ctx context.Context, path string, trunc bool) (*FileUploadProxy, error) {
@@ -60,7 +61,7 @@ func OpenUpload(ctx context.Context, path string, trunc bool) (*FileUploadProxy,
 	if err != nil {
 		return nil, err
 	}
-	return &FileUploadProxy{buffer: tmpFile, path: path, ctx: ctx, trunc: trunc}, nil
+	return &FileUploadProxy{buffer: tmpFile, path: path, ctx: withStorageTag(ctx, path), trunc: trunc}, nil
 }
 
 func (f *FileUploadProxy) Read(p []byte) (n int, err error) {
@@ -72,7 +73,7 @@ func (f *FileUploadProxy) Write(p []byte) (n int, err error) {
 	if err != nil {
 		return n, err
 	}
-	err = stream.ClientUploadLimit.WaitN(f.ctx, n)
+	err = waitN(f.ctx, stream.ClientUploadLimit, traffic.Upload, n)
 	return n, err
 }
 
@@ -165,7 +166,7 @@ func OpenUploadWithLength(ctx context.Context, path string, trunc bool, length i
 	if trunc {
 		_ = fs.Remove(ctx, path)
 	}
-	return &FileUploadWithLengthProxy{ctx: ctx, path: path, length: length}, nil
+	return &FileUploadWithLengthProxy{ctx: withStorageTag(ctx, path), path: path, length: length}, nil
 }
 
 func (f *FileUploadWithLengthProxy) Read(p []byte) (n int, err error) {
@@ -224,7 +225,7 @@ func (f *FileUploadWithLengthProxy) Write(p []byte) (n int, err error) {
 	if err != nil {
 		return n, err
 	}
-	err = stream.ClientUploadLimit.WaitN(f.ctx, n)
+	err = waitN(f.ctx, stream.ClientUploadLimit, traffic.Upload, n)
 	return n, err
 }
 
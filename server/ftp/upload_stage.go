@@ -12,6 +12,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
 	log "github.com/sirupsen/logrus"
 	"github.com/tchap/go-patricia/v2/patricia"
 )
@@ -241,7 +242,7 @@ func (f *BorrowedFile) Read(p []byte) (n int, err error) {
 	if err != nil {
 		return n, err
 	}
-	err = stream.ClientDownloadLimit.WaitN(f.ctx, n)
+	err = waitN(f.ctx, stream.ClientDownloadLimit, traffic.Download, n)
 	return n, err
 }
 
@@ -250,7 +251,7 @@ func (f *BorrowedFile) ReadAt(p []byte, off int64) (n int, err error) {
 	if err != nil {
 		return n, err
 	}
-	err = stream.ClientDownloadLimit.WaitN(f.ctx, n)
+	err = waitN(f.ctx, stream.ClientDownloadLimit, traffic.Download, n)
 	return n, err
 }
 
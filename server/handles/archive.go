@@ -13,7 +13,6 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
-	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
@@ -131,10 +130,7 @@ func FsArchiveMeta(c *gin.Context, req *ArchiveMetaReq, user *model.User) {
 		}
 		return
 	}
-	s := ""
-	if isEncrypt(meta, reqPath) || setting.GetBool(conf.SignAll) {
-		s = sign.SignArchive(reqPath)
-	}
+	s := common.SignArchivePath(user, reqPath, isEncrypt(meta, reqPath) || setting.GetBool(conf.SignAll))
 	api := "/ae"
 	if ret.DriverProviding {
 		api = "/ad"
@@ -237,6 +233,8 @@ type ArchiveDecompressReq struct {
 	CacheFull     bool     `json:"cache_full" form:"cache_full"`
 	PutIntoNewDir bool     `json:"put_into_new_dir" form:"put_into_new_dir"`
 	Overwrite     bool     `json:"overwrite" form:"overwrite"`
+	// replaces Overwrite when it's given
+	ConflictPolicy string `json:"conflict_policy" form:"conflict_policy"`
 }
 
 func FsArchiveDecompress(c *gin.Context) {
@@ -264,6 +262,12 @@ func FsArchiveDecompress(c *gin.Context) {
 		common.ErrorResp(c, err, 403)
 		return
 	}
+	if req.ConflictPolicy != "" {
+		if req.ConflictPolicy, err = fs.CheckConflictPolicy(req.ConflictPolicy); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+	}
 	tasks := make([]task.TaskExtensionInfo, 0, len(srcPaths))
 	for _, srcPath := range srcPaths {
 		t, e := fs.ArchiveDecompress(c.Request.Context(), srcPath, dstDir, model.ArchiveDecompressArgs{
@@ -277,9 +281,10 @@ func FsArchiveDecompress(c *gin.Context) {
 				},
 				InnerPath: utils.FixAndCleanPath(req.InnerPath),
 			},
-			CacheFull:     req.CacheFull,
-			PutIntoNewDir: req.PutIntoNewDir,
-			Overwrite:     req.Overwrite,
+			CacheFull:      req.CacheFull,
+			PutIntoNewDir:  req.PutIntoNewDir,
+			Overwrite:      req.Overwrite,
+			ConflictPolicy: req.ConflictPolicy,
 		})
 		if e != nil {
 			if errors.Is(e, errs.WrongArchivePassword) {
@@ -8,6 +8,8 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
+	"github.com/OpenListTeam/OpenList/v4/internal/webhook"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
 	"github.com/pquerna/otp/totp"
@@ -17,6 +19,8 @@ type LoginReq struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password"`
 	OtpCode  string `json:"otp_code"`
+	// RecoveryCode is accepted in place of OtpCode when the authenticator is lost
+	RecoveryCode string `json:"recovery_code"`
 }
 
 // Login Deprecated
@@ -54,19 +58,31 @@ func loginHash(c *gin.Context, req *LoginReq) {
 	if err != nil {
 		common.ErrorResp(c, err, 400)
 		model.LoginCache.Set(ip, count+1)
+		webhook.LoginFailed(req.Username, ip, c.Request.UserAgent())
 		return
 	}
 	// validate password hash
 	if err := user.ValidatePwdStaticHash(req.Password); err != nil {
 		common.ErrorResp(c, err, 400)
 		model.LoginCache.Set(ip, count+1)
+		webhook.LoginFailed(req.Username, ip, c.Request.UserAgent())
 		return
 	}
 	// check 2FA
-	if user.OtpSecret != "" {
-		if !totp.Validate(req.OtpCode, user.OtpSecret) {
+	if user.OtpSecret != "" && !totp.Validate(req.OtpCode, user.OtpSecret) {
+		code := req.RecoveryCode
+		if code == "" {
+			code = req.OtpCode
+		}
+		used, err := op.UseRecoveryCode(user, code)
+		if err != nil {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+		if !used {
 			common.ErrorStrResp(c, "Invalid 2FA code", 402)
 			model.LoginCache.Set(ip, count+1)
+			webhook.LoginFailed(req.Username, ip, c.Request.UserAgent())
 			return
 		}
 	}
@@ -76,13 +92,17 @@ func loginHash(c *gin.Context, req *LoginReq) {
 		common.ErrorResp(c, err, 400, true)
 		return
 	}
-	common.SuccessResp(c, gin.H{"token": token})
+	common.SuccessResp(c, gin.H{"token": token, "enroll_2fa": common.NeedEnroll2FA(user)})
 	model.LoginCache.Del(ip)
 }
 
 type UserResp struct {
 	model.User
-	Otp bool `json:"otp"`
+	Otp               bool `json:"otp"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+	Enroll2FA         bool `json:"enroll_2fa"`
+	// Traffic is the speed limits, the quota and the traffic of the user in the quota period
+	Traffic traffic.Usage `json:"traffic"`
 }
 
 // CurrentUser get current user by token
@@ -96,6 +116,9 @@ func CurrentUser(c *gin.Context) {
 	if userResp.OtpSecret != "" {
 		userResp.Otp = true
 	}
+	userResp.RecoveryCodesLeft = user.RecoveryCodesLeft()
+	userResp.Enroll2FA = common.NeedEnroll2FA(user)
+	userResp.Traffic = traffic.UsageOf(user)
 	common.SuccessResp(c, userResp)
 }
 
@@ -172,11 +195,41 @@ func Verify2FA(c *gin.Context) {
 		return
 	}
 	user.OtpSecret = req.Secret
-	if err := op.UpdateUser(user); err != nil {
+	codes, err := op.GenerateRecoveryCodes(user)
+	if err != nil {
 		common.ErrorResp(c, err, 500)
-	} else {
-		common.SuccessResp(c)
+		return
+	}
+	common.SuccessResp(c, gin.H{"recovery_codes": codes})
+}
+
+type RecoveryCodesReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RegenerateRecoveryCodes invalidates all the recovery codes of the current user
+// and returns a new set, a valid 2FA code is required
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req RecoveryCodesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if user.OtpSecret == "" {
+		common.ErrorStrResp(c, "2FA is not enabled", 400)
+		return
+	}
+	if !totp.Validate(req.Code, user.OtpSecret) {
+		common.ErrorStrResp(c, "Invalid 2FA code", 400)
+		return
+	}
+	codes, err := op.GenerateRecoveryCodes(user)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
 	}
+	common.SuccessResp(c, gin.H{"recovery_codes": codes})
 }
 
 func LogOut(c *gin.Context) {
@@ -1,7 +0,0 @@
-package handles
-
-const (
-	CANCEL    = "cancel"
-	OVERWRITE = "overwrite"
-	SKIP      = "skip"
-)
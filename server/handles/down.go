@@ -7,6 +7,7 @@ import (
 	stdpath "path"
 	"strconv"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/blockcache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -63,14 +64,16 @@ func Proxy(c *gin.Context) {
 				return
 			}
 		}
+		linkType := c.Query("type")
 		link, file, err := fs.Link(c.Request.Context(), rawPath, model.LinkArgs{
 			Header: c.Request.Header,
-			Type:   c.Query("type"),
+			Type:   linkType,
 		})
 		if err != nil {
 			common.ErrorPage(c, err, 500)
 			return
 		}
+		link = blockcache.Link(storage.GetStorage(), rawPath, file, linkType, link)
 		proxy(c, link, file, storage.GetStorage().ProxyRange)
 	} else {
 		common.ErrorPage(c, errors.New("proxy not allowed"), 403)
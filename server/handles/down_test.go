@@ -0,0 +1,65 @@
+package handles
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+// TestProxyTrafficStats downloads a file through /p with the link of a user,
+// the traffic stats of the storage are counted for the user
+func TestProxyTrafficStats(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "file.txt"), []byte(strings.Repeat("a", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	id, err := op.CreateStorage(context.Background(), model.Storage{
+		Driver:    "Local",
+		MountPath: "/traffic_local",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	owner := &model.User{Username: "proxy_owner", Role: model.GENERAL}
+	if err = op.CreateUser(owner); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/p/*path", middlewares.PathParse, middlewares.Down(sign.Verify),
+		middlewares.DownloadRateLimiter(stream.ClientDownloadLimit), Proxy)
+	w := httptest.NewRecorder()
+	s := common.SignPath(owner, "/traffic_local/file.txt", true)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/p/traffic_local/file.txt?sign="+s, nil))
+	if w.Code != 200 || w.Body.Len() != 1000 {
+		t.Fatalf("expect the file to be proxied, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+
+	traffic.Flush()
+	top, err := traffic.Top(db.TrafficStatFilter{StorageID: id}, "user", "download", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].Key != strconv.Itoa(int(owner.ID)) || top[0].Download != 1000 {
+		t.Errorf("expect 1000 bytes of the storage counted for %s, got %+v", owner.Username, top)
+	}
+}
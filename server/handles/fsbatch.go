@@ -60,8 +60,16 @@ func FsRecursiveMove(c *gin.Context) {
 		return
 	}
 
+	policy := fs.ConflictOverwrite
+	if req.ConflictPolicy != "" {
+		if policy, err = fs.CheckConflictPolicy(req.ConflictPolicy); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+	}
+
 	var existingFileNames []string
-	if req.ConflictPolicy != OVERWRITE {
+	if policy != fs.ConflictOverwrite {
 		dstFiles, err := fs.List(c.Request.Context(), dstDir, &fs.ListArgs{})
 		if err != nil {
 			common.ErrorResp(c, err, 500)
@@ -106,13 +114,14 @@ func FsRecursiveMove(c *gin.Context) {
 			}
 
 			if slices.Contains(existingFileNames, movingFile.GetName()) {
-				if req.ConflictPolicy == CANCEL {
+				// the other policies are applied by the moves
+				if policy == fs.ConflictFail {
 					common.ErrorStrResp(c, fmt.Sprintf("file [%s] exists", movingFile.GetName()), 403)
 					return
-				} else if req.ConflictPolicy == SKIP {
+				} else if policy == fs.ConflictSkip {
 					continue
 				}
-			} else if req.ConflictPolicy != OVERWRITE {
+			} else if policy != fs.ConflictOverwrite {
 				existingFileNames = append(existingFileNames, movingFile.GetName())
 			}
 			movingFileNames = append(movingFileNames, movingFileName)
@@ -122,9 +131,10 @@ func FsRecursiveMove(c *gin.Context) {
 	}
 
 	var count = 0
+	ctx := fs.WithConflictPolicy(c.Request.Context(), policy)
 	for i, fileName := range movingFileNames {
 		// move
-		_, err := fs.Move(c.Request.Context(), fileName, dstDir, len(movingFileNames) > i+1)
+		_, err := fs.Move(ctx, fileName, dstDir, len(movingFileNames) > i+1)
 		if err != nil {
 			common.ErrorResp(c, err, 500)
 			return
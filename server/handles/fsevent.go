@@ -0,0 +1,110 @@
+package handles
+
+import (
+	"io"
+	stdpath "path"
+	"sync/atomic"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// the events waiting to be sent to a slow client, the next ones are dropped
+const fsEventsBuffer = 256
+
+const fsEventsPingInterval = 30 * time.Second
+
+// FsEvents streams the fs events under the paths of the query as server-sent events.
+// An `overflow` event tells the client that events were dropped and it should list again.
+func FsEvents(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	paths := c.QueryArray("path")
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+	for i, path := range paths {
+		reqPath, err := user.JoinPath(path)
+		if err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+		paths[i] = reqPath
+	}
+	password := c.Query("password")
+
+	events := make(chan op.FsEvent, fsEventsBuffer)
+	var dropped atomic.Bool
+	unsubscribe := op.SubscribeFsEvents(func(e op.FsEvent) {
+		select {
+		case events <- e:
+		default:
+			dropped.Store(true)
+		}
+	})
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ready", gin.H{"paths": paths})
+	ticker := time.NewTicker(fsEventsPingInterval)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e := <-events:
+			if e, ok := filterFsEvent(user, paths, password, e); ok {
+				c.SSEvent("fs", e)
+			}
+		case <-ticker.C:
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		if dropped.Swap(false) {
+			c.SSEvent("overflow", gin.H{})
+		}
+		return true
+	})
+}
+
+// filterFsEvent keeps the paths of the event the user subscribed to and can access.
+// A rename from or to a path out of reach is seen as a creation or a deletion.
+func filterFsEvent(user *model.User, paths []string, password string, e op.FsEvent) (op.FsEvent, bool) {
+	visible := func(path string) bool {
+		if path == "" {
+			return false
+		}
+		subscribed := false
+		for _, p := range paths {
+			if utils.IsSubPath(p, path) {
+				subscribed = true
+				break
+			}
+		}
+		if !subscribed {
+			return false
+		}
+		meta, err := op.GetNearestMeta(stdpath.Dir(path))
+		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			return false
+		}
+		return common.CanAccess(user, meta, path, password)
+	}
+	switch newOk, oldOk := visible(e.Path), visible(e.OldPath); {
+	case newOk && (oldOk || e.OldPath == ""):
+		return e, true
+	case newOk:
+		e.Type, e.OldPath = op.FsCreated, ""
+		return e, true
+	case oldOk:
+		e.Type, e.Path, e.OldPath = op.FsDeleted, e.OldPath, ""
+		return e, true
+	}
+	return e, false
+}
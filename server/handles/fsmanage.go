@@ -12,7 +12,6 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
-	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 	"github.com/OpenListTeam/OpenList/v4/pkg/generic"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
@@ -63,6 +62,36 @@ type MoveCopyReq struct {
 	Overwrite    bool     `json:"overwrite"`
 	SkipExisting bool     `json:"skip_existing"`
 	Merge        bool     `json:"merge"`
+	// replaces Overwrite and SkipExisting when it's given
+	ConflictPolicy string `json:"conflict_policy"`
+}
+
+func (req *MoveCopyReq) conflictPolicy() (string, error) {
+	if req.ConflictPolicy != "" {
+		return fs.CheckConflictPolicy(req.ConflictPolicy)
+	}
+	if req.Overwrite {
+		return fs.ConflictOverwrite, nil
+	}
+	if req.SkipExisting {
+		return fs.ConflictSkip, nil
+	}
+	return fs.ConflictFail, nil
+}
+
+// checkExisting fails at once when one of the names exists in dstDir with the fail policy,
+// the other policies are applied by the tasks
+func checkExisting(c *gin.Context, policy, dstDir string, names []string) bool {
+	if policy != fs.ConflictFail {
+		return true
+	}
+	for _, name := range names {
+		if res, _ := fs.Get(c.Request.Context(), stdpath.Join(dstDir, name), &fs.GetArgs{NoLog: true}); res != nil {
+			common.ErrorStrResp(c, fmt.Sprintf("file [%s] exists", name), 403)
+			return false
+		}
+	}
+	return true
 }
 
 func FsMove(c *gin.Context) {
@@ -91,25 +120,22 @@ func FsMove(c *gin.Context) {
 		return
 	}
 
-	var validNames []string
-	if !req.Overwrite {
-		for _, name := range req.Names {
-			if res, _ := fs.Get(c.Request.Context(), stdpath.Join(dstDir, name), &fs.GetArgs{NoLog: true}); res != nil && !req.SkipExisting {
-				common.ErrorStrResp(c, fmt.Sprintf("file [%s] exists", name), 403)
-				return
-			} else if res == nil {
-				validNames = append(validNames, name)
-			}
-		}
-	} else {
-		validNames = req.Names
+	policy, err := req.conflictPolicy()
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if !checkExisting(c, policy, dstDir, req.Names) {
+		return
 	}
+	validNames := req.Names
+	ctx := fs.WithConflictPolicy(c.Request.Context(), policy)
 
 	// Create all tasks immediately without any synchronous validation
 	// All validation will be done asynchronously in the background
 	var addedTasks []task.TaskExtensionInfo
 	for i, name := range validNames {
-		t, err := fs.Move(c.Request.Context(), stdpath.Join(srcDir, name), dstDir, len(validNames) > i+1)
+		t, err := fs.Move(ctx, stdpath.Join(srcDir, name), dstDir, len(validNames) > i+1)
 		if t != nil {
 			addedTasks = append(addedTasks, t)
 		}
@@ -158,23 +184,26 @@ func FsCopy(c *gin.Context) {
 		return
 	}
 
+	policy, err := req.conflictPolicy()
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	var validNames []string
-	if !req.Overwrite {
+	if req.Merge && !req.Overwrite {
+		// merge goes into the existing dirs and skips the existing files
 		for _, name := range req.Names {
-			if res, _ := fs.Get(c.Request.Context(), stdpath.Join(dstDir, name), &fs.GetArgs{NoLog: true}); res != nil {
-				if !req.SkipExisting && !req.Merge {
-					common.ErrorStrResp(c, fmt.Sprintf("file [%s] exists", name), 403)
-					return
-				} else if req.Merge && res.IsDir() {
-					validNames = append(validNames, name)
-				}
-			} else {
+			if res, _ := fs.Get(c.Request.Context(), stdpath.Join(dstDir, name), &fs.GetArgs{NoLog: true}); res == nil || res.IsDir() {
 				validNames = append(validNames, name)
 			}
 		}
 	} else {
+		if !req.Merge && !checkExisting(c, policy, dstDir, req.Names) {
+			return
+		}
 		validNames = req.Names
 	}
+	ctx := fs.WithConflictPolicy(c.Request.Context(), policy)
 
 	// Create all tasks immediately without any synchronous validation
 	// All validation will be done asynchronously in the background
@@ -182,9 +211,9 @@ func FsCopy(c *gin.Context) {
 	for i, name := range validNames {
 		var t task.TaskExtensionInfo
 		if req.Merge {
-			t, err = fs.Merge(c.Request.Context(), stdpath.Join(srcDir, name), dstDir, len(validNames) > i+1)
+			t, err = fs.Merge(ctx, stdpath.Join(srcDir, name), dstDir, len(validNames) > i+1)
 		} else {
-			t, err = fs.Copy(c.Request.Context(), stdpath.Join(srcDir, name), dstDir, len(validNames) > i+1)
+			t, err = fs.Copy(ctx, stdpath.Join(srcDir, name), dstDir, len(validNames) > i+1)
 		}
 		if t != nil {
 			addedTasks = append(addedTasks, t)
@@ -208,6 +237,128 @@ func FsCopy(c *gin.Context) {
 	}
 }
 
+type SyncReq struct {
+	SrcDir string `json:"src_dir"`
+	DstDir string `json:"dst_dir"`
+	fs.SyncArgs
+}
+
+// FsSync adds a task making the content of dst_dir the same as the one of src_dir
+func FsSync(c *gin.Context) {
+	var req SyncReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !user.CanCopy() || (req.Delete && !req.DryRun && !user.CanRemove()) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	srcDir, err := user.JoinPath(req.SrcDir)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	dstDir, err := user.JoinPath(req.DstDir)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	t, err := fs.Sync(c.Request.Context(), srcDir, dstDir, req.SyncArgs)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
+
+// FsDedupe adds a task finding the files with the same content in the paths
+func FsDedupe(c *gin.Context) {
+	var req fs.DedupeArgs
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	for i, path := range req.Paths {
+		reqPath, err := user.JoinPath(path)
+		if err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+		req.Paths[i] = reqPath
+	}
+	t, err := fs.FindDuplicates(c.Request.Context(), req)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
+
+type DedupeApplyReq struct {
+	Action string `json:"action"`
+}
+
+// FsDedupeApply adds a task deleting or linking the duplicates found by the dedupe task of the tid query
+func FsDedupeApply(c *gin.Context) {
+	getTargetedHandler(fs.DedupeTaskManager, dedupeApply)(c)
+}
+
+func dedupeApply(c *gin.Context, report *fs.DedupeTask) {
+	var req DedupeApplyReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !user.CanRemove() || req.Action == fs.DedupeLink && !user.CanWrite() {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	t, err := fs.ApplyDuplicates(c.Request.Context(), report, req.Action)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
+
+// FsChecksum adds a task writing the checksum manifest of a folder, or verifying its files against it
+func FsChecksum(c *gin.Context) {
+	var req fs.ChecksumArgs
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !user.CanWrite() {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	req.Path = reqPath
+	t, err := fs.Checksum(c.Request.Context(), req)
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
+
 type RenameReq struct {
 	Path      string `json:"path"`
 	Name      string `json:"name"`
@@ -408,11 +559,12 @@ func Link(c *gin.Context) {
 		return
 	}
 	if storage.Config().NoLinkURL {
+		user := c.Request.Context().Value(conf.UserKey).(*model.User)
 		common.SuccessResp(c, model.Link{
 			URL: fmt.Sprintf("%s/p%s?d&sign=%s",
 				common.GetApiUrl(c),
 				utils.EncodePath(rawPath, true),
-				sign.Sign(rawPath)),
+				common.SignPath(user, rawPath, true)),
 		})
 		return
 	}
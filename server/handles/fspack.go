@@ -0,0 +1,443 @@
+package handles
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/sharing"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/go-cache"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+const (
+	packZip = "zip"
+	packTar = "tar"
+)
+
+// packWriter builds an archive on the fly, entries are written in order and never staged
+type packWriter interface {
+	addDir(name string, modified time.Time) error
+	addFile(name string, obj model.Obj, r io.Reader) error
+	Close() error
+}
+
+type zipPackWriter struct {
+	w *zip.Writer
+	// names are encoded with enc and the EFS flag is left unset when enc is not nil,
+	// for the unzip tools which don't understand UTF-8 names
+	enc encoding.Encoding
+}
+
+func (z *zipPackWriter) header(name string, modified time.Time) *zip.FileHeader {
+	fh := &zip.FileHeader{Name: name, Modified: modified}
+	if z.enc != nil {
+		if encoded, err := z.enc.NewEncoder().String(name); err == nil {
+			fh.Name = encoded
+			fh.NonUTF8 = true
+		}
+	}
+	return fh
+}
+
+func (z *zipPackWriter) addDir(name string, modified time.Time) error {
+	fh := z.header(name+"/", modified)
+	fh.SetMode(os.ModeDir | 0755)
+	_, err := z.w.CreateHeader(fh)
+	return err
+}
+
+func (z *zipPackWriter) addFile(name string, obj model.Obj, r io.Reader) error {
+	fh := z.header(name, obj.ModTime())
+	// files are stored as is, most of them are already compressed
+	fh.Method = zip.Store
+	fh.SetMode(0644)
+	w, err := z.w.CreateHeader(fh)
+	if err != nil {
+		return err
+	}
+	_, err = utils.CopyWithBuffer(w, r)
+	return err
+}
+
+func (z *zipPackWriter) Close() error {
+	return z.w.Close()
+}
+
+type tarPackWriter struct {
+	w *tar.Writer
+}
+
+func (t *tarPackWriter) addDir(name string, modified time.Time) error {
+	return t.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     0755,
+		ModTime:  modified,
+	})
+}
+
+func (t *tarPackWriter) addFile(name string, obj model.Obj, r io.Reader) error {
+	err := t.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     obj.GetSize(),
+		Mode:     0644,
+		ModTime:  obj.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	// the size is announced in the header, so the entry must be exactly that long
+	_, err = utils.CopyWithBuffer(t.w, io.LimitReader(r, obj.GetSize()))
+	return err
+}
+
+func (t *tarPackWriter) Close() error {
+	return t.w.Close()
+}
+
+func newPackWriter(w io.Writer, format string, nonEFS bool) (packWriter, error) {
+	switch format {
+	case packZip:
+		z := &zipPackWriter{w: zip.NewWriter(w)}
+		if nonEFS {
+			enc, err := ianaindex.IANA.Encoding(setting.GetStr(conf.NonEFSZipEncoding))
+			if err == nil && enc != nil {
+				z.enc = enc
+			}
+		}
+		return z, nil
+	case packTar:
+		return &tarPackWriter{w: tar.NewWriter(w)}, nil
+	default:
+		return nil, errors.Errorf("unsupported pack format: %s", format)
+	}
+}
+
+// packSource lists and opens the objects to pack, hiding what the visitor is not allowed to see
+type packSource interface {
+	list(ctx context.Context, path string) ([]model.Obj, error)
+	open(ctx context.Context, path string) (io.ReadCloser, error)
+}
+
+type linkReadCloser struct {
+	io.ReadCloser
+	link *model.Link
+}
+
+func (l *linkReadCloser) Close() error {
+	return stderrors.Join(l.ReadCloser.Close(), l.link.Close())
+}
+
+func openLink(ctx context.Context, link *model.Link, obj model.Obj) (io.ReadCloser, error) {
+	rr, err := stream.GetRangeReaderFromLink(obj.GetSize(), link)
+	if err != nil {
+		_ = link.Close()
+		return nil, err
+	}
+	rc, err := rr.RangeRead(ctx, http_range.Range{Length: obj.GetSize()})
+	if err != nil {
+		_ = link.Close()
+		return nil, err
+	}
+	return &linkReadCloser{ReadCloser: rc, link: link}, nil
+}
+
+type fsPackSource struct {
+	user     *model.User
+	password string
+	ip       string
+	header   map[string][]string
+}
+
+func (s *fsPackSource) list(ctx context.Context, path string) ([]model.Obj, error) {
+	meta, err := op.GetNearestMeta(path)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return nil, err
+	}
+	if !common.CanAccess(s.user, meta, path, s.password) {
+		return nil, errs.PermissionDenied
+	}
+	ctx = context.WithValue(ctx, conf.UserKey, s.user)
+	ctx = context.WithValue(ctx, conf.MetaKey, meta)
+	return fs.List(ctx, path, &fs.ListArgs{NoLog: true})
+}
+
+func (s *fsPackSource) open(ctx context.Context, path string) (io.ReadCloser, error) {
+	link, obj, err := fs.Link(ctx, path, model.LinkArgs{IP: s.ip, Header: s.header})
+	if err != nil {
+		return nil, err
+	}
+	return openLink(ctx, link, obj)
+}
+
+type sharingPackSource struct {
+	sid    string
+	pwd    string
+	ip     string
+	header map[string][]string
+}
+
+func (s *sharingPackSource) list(ctx context.Context, path string) ([]model.Obj, error) {
+	_, objs, err := sharing.List(ctx, s.sid, path, model.SharingListArgs{Pwd: s.pwd})
+	return objs, err
+}
+
+func (s *sharingPackSource) open(ctx context.Context, path string) (io.ReadCloser, error) {
+	_, link, obj, err := sharing.Link(ctx, s.sid, path, &sharing.LinkArgs{
+		SharingListArgs: model.SharingListArgs{Pwd: s.pwd},
+		LinkArgs:        model.LinkArgs{IP: s.ip, Header: s.header},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return openLink(ctx, link, obj)
+}
+
+// packObjs writes the children of dir named in names, or all of them if names is empty.
+// Objects that can't be read are skipped, as the response status is already sent.
+func packObjs(ctx context.Context, w packWriter, src packSource, dir, prefix string, names []string) error {
+	objs, err := src.list(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		selected := make(map[string]struct{}, len(names))
+		for _, name := range names {
+			selected[name] = struct{}{}
+		}
+		objs = utils.SliceFilter(objs, func(obj model.Obj) bool {
+			_, ok := selected[obj.GetName()]
+			return ok
+		})
+	}
+	for _, obj := range objs {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		path := stdpath.Join(dir, obj.GetName())
+		name := stdpath.Join(prefix, obj.GetName())
+		if obj.IsDir() {
+			if err = w.addDir(name, obj.ModTime()); err != nil {
+				return err
+			}
+			if err = packObjs(ctx, w, src, path, name, nil); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Warnf("skip packing dir %s: %v", path, err)
+			}
+			continue
+		}
+		rc, err := src.open(ctx, path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warnf("skip packing file %s: %v", path, err)
+			continue
+		}
+		err = w.addFile(name, obj, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePack streams the archive as the response, errors after the first byte can only be logged
+func writePack(c *gin.Context, src packSource, dir string, names []string, format, fileName string, nonEFS bool) {
+	w, err := newPackWriter(c.Writer, format, nonEFS)
+	if err != nil {
+		common.ErrorPage(c, err, 400)
+		return
+	}
+	c.Header("Content-Disposition", utils.GenerateContentDisposition(fileName+"."+format))
+	c.Header("Content-Type", utils.GetMimeType("."+format))
+	c.Header("Cache-Control", "max-age=0, no-cache, no-store, must-revalidate")
+	c.Status(200)
+	if c.Request.Method == "HEAD" {
+		return
+	}
+	err = packObjs(c.Request.Context(), w, src, dir, "", names)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		log.Warnf("failed to pack %s: %v", dir, err)
+	}
+}
+
+func packFileName(dir string, names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	if name := stdpath.Base(dir); name != "/" && name != "." {
+		return name
+	}
+	return "download"
+}
+
+type FsPackReq struct {
+	Dir      string   `json:"dir" form:"dir"`
+	Names    []string `json:"names" form:"names"`
+	Format   string   `json:"format" form:"format"`
+	Password string   `json:"password" form:"password"`
+	NonEFS   bool     `json:"non_efs" form:"non_efs"`
+}
+
+type packTask struct {
+	FsPackReq
+	User *model.User
+}
+
+var (
+	packCache  = cache.NewMemCache[*packTask]()
+	packExpire = 10 * time.Minute
+)
+
+// FsPack checks the request and returns a short-lived url which streams the archive,
+// so that browsers can download it without sending the token
+func FsPack(c *gin.Context) {
+	var req FsPackReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Format == "" {
+		req.Format = packZip
+	}
+	if req.Format != packZip && req.Format != packTar {
+		common.ErrorStrResp(c, "unsupported pack format", 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	reqDir, err := user.JoinPath(req.Dir)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	for _, name := range req.Names {
+		if name == "" || strings.Contains(name, "/") {
+			common.ErrorStrResp(c, fmt.Sprintf("invalid name [%s]", name), 400)
+			return
+		}
+	}
+	meta, err := op.GetNearestMeta(reqDir)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !common.CanAccess(user, meta, reqDir, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
+		return
+	}
+	obj, err := fs.Get(c.Request.Context(), reqDir, &fs.GetArgs{})
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	if !obj.IsDir() {
+		common.ErrorStrResp(c, "only folders can be packed", 400)
+		return
+	}
+	req.Dir = reqDir
+	key := random.String(32)
+	packCache.Set(key, &packTask{FsPackReq: req, User: user}, cache.WithEx[*packTask](packExpire))
+	common.SuccessResp(c, gin.H{
+		"url": fmt.Sprintf("%s/pk/%s", common.GetApiUrl(c), key),
+	})
+}
+
+// FsPackUser puts the user who created the pack link into the request,
+// the download is done and counted for the user
+func FsPackUser(c *gin.Context) {
+	task, ok := packCache.Get(c.Param("key"))
+	if !ok {
+		common.ErrorPage(c, errors.New("the download link is expired"), 404)
+		c.Abort()
+		return
+	}
+	// the user may have been changed or disabled since the link was created
+	user, err := op.GetUserById(task.User.ID)
+	if err != nil || user.Disabled {
+		common.ErrorPage(c, errors.New("user is unavailable"), 403)
+		c.Abort()
+		return
+	}
+	common.GinWithValue(c, conf.UserKey, user)
+	c.Next()
+}
+
+func FsPackDown(c *gin.Context) {
+	task, ok := packCache.Get(c.Param("key"))
+	if !ok {
+		common.ErrorPage(c, errors.New("the download link is expired"), 404)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	src := &fsPackSource{
+		user:     user,
+		password: task.Password,
+		ip:       c.ClientIP(),
+		header:   c.Request.Header,
+	}
+	writePack(c, src, task.Dir, task.Names, task.Format, packFileName(task.Dir, task.Names), task.NonEFS)
+}
+
+// sharingPack streams the content of a folder of a share, or the selected children in query `name`
+func sharingPack(c *gin.Context, s *model.Sharing, path, format string) {
+	names := c.QueryArray("name")
+	for _, name := range names {
+		if name == "" || strings.Contains(name, "/") {
+			common.ErrorPage(c, fmt.Errorf("invalid name [%s]", name), 400)
+			return
+		}
+	}
+	if path != "/" {
+		_, obj, err := sharing.Get(c.Request.Context(), s.ID, path, model.SharingListArgs{Pwd: c.Query("pwd")})
+		if dealErrorPage(c, err) {
+			return
+		}
+		if !obj.IsDir() {
+			common.ErrorPage(c, errors.New("only folders can be packed"), 400)
+			return
+		}
+	}
+	src := &sharingPackSource{
+		sid:    s.ID,
+		pwd:    c.Query("pwd"),
+		ip:     c.ClientIP(),
+		header: c.Request.Header,
+	}
+	fileName := packFileName(path, names)
+	if fileName == "download" {
+		fileName = s.ID
+	}
+	_, nonEFS := c.GetQuery("non_efs")
+	writePack(c, src, path, names, format, fileName, nonEFS)
+}
@@ -0,0 +1,63 @@
+package handles
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+type memPackSource map[string][]model.Obj
+
+func (m memPackSource) list(_ context.Context, path string) ([]model.Obj, error) {
+	if path == "/secret" {
+		return nil, errs.PermissionDenied
+	}
+	return m[path], nil
+}
+
+func (m memPackSource) open(_ context.Context, path string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(path)), nil
+}
+
+func TestPackObjs(t *testing.T) {
+	src := memPackSource{
+		"/": {
+			&model.Object{Name: "a.txt", Size: 6},
+			&model.Object{Name: "dir", IsFolder: true},
+			&model.Object{Name: "secret", IsFolder: true},
+			&model.Object{Name: "b.txt", Size: 6},
+		},
+		"/dir": {
+			&model.Object{Name: "c.txt", Size: 10},
+		},
+	}
+	buf := &bytes.Buffer{}
+	w, err := newPackWriter(buf, packZip, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = packObjs(context.Background(), w, src, "/", "", []string{"a.txt", "dir", "secret"}); err != nil {
+		t.Fatalf("failed to pack: %+v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	expected := "a.txt,dir/,dir/c.txt,secret/"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("expect entries %s, got %s", expected, strings.Join(names, ","))
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
 func getLastModified(c *gin.Context) time.Time {
@@ -30,6 +31,51 @@ func getLastModified(c *gin.Context) time.Time {
 	return lastModified
 }
 
+// getConflictPolicy returns the policy of the Conflict-Policy header, or the one of the former Overwrite header
+func getConflictPolicy(c *gin.Context) (string, error) {
+	if policy := c.GetHeader("Conflict-Policy"); policy != "" {
+		return fs.CheckConflictPolicy(policy)
+	}
+	if c.GetHeader("Overwrite") == "false" {
+		return fs.ConflictFail, nil
+	}
+	return fs.ConflictOverwrite, nil
+}
+
+// putConflict applies the policy to the file about to be put, it responds and returns ""
+// when the file is not put
+func putConflict(c *gin.Context, policy, dir string, file model.Obj) string {
+	name, err := fs.PutConflict(c.Request.Context(), dir, file, policy)
+	if errors.Is(err, errs.ObjectAlreadyExists) {
+		common.ErrorStrResp(c, "file exists", 403)
+		return ""
+	}
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return ""
+	}
+	if name == "" {
+		common.SuccessResp(c, gin.H{"skipped": true})
+	}
+	return name
+}
+
+// putResp responds with the task of the put, and the name the file was renamed to
+func putResp(c *gin.Context, t task.TaskExtensionInfo, name, renamed string) {
+	data := gin.H{}
+	if t != nil {
+		data["task"] = getTaskInfo(t)
+	}
+	if renamed != name {
+		data["renamed_to"] = renamed
+	}
+	if len(data) == 0 {
+		common.SuccessResp(c)
+		return
+	}
+	common.SuccessResp(c, data)
+}
+
 // shouldIgnoreSystemFile checks if the filename should be ignored based on settings
 func shouldIgnoreSystemFile(filename string) bool {
 	if setting.GetBool(conf.IgnoreSystemFiles) {
@@ -52,19 +98,17 @@ func FsStream(c *gin.Context) {
 		return
 	}
 	asTask := c.GetHeader("As-Task") == "true"
-	overwrite := c.GetHeader("Overwrite") != "false"
+	policy, err := getConflictPolicy(c)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 	path, err = user.JoinPath(path)
 	if err != nil {
 		common.ErrorResp(c, err, 403)
 		return
 	}
-	if !overwrite {
-		if res, _ := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); res != nil {
-			common.ErrorStrResp(c, "file exists", 403)
-			return
-		}
-	}
 	dir, name := stdpath.Split(path)
 	// Check if system file should be ignored
 	if shouldIgnoreSystemFile(name) {
@@ -97,13 +141,17 @@ func FsStream(c *gin.Context) {
 	if len(mimetype) == 0 {
 		mimetype = utils.GetMimeType(name)
 	}
+	obj := &model.Object{
+		Name:     name,
+		Size:     size,
+		Modified: getLastModified(c),
+		HashInfo: utils.NewHashInfoByMap(h),
+	}
+	if obj.Name = putConflict(c, policy, dir, obj); obj.Name == "" {
+		return
+	}
 	s := &stream.FileStream{
-		Obj: &model.Object{
-			Name:     name,
-			Size:     size,
-			Modified: getLastModified(c),
-			HashInfo: utils.NewHashInfoByMap(h),
-		},
+		Obj:          obj,
 		Reader:       c.Request.Body,
 		Mimetype:     mimetype,
 		WebPutAsTask: asTask,
@@ -118,13 +166,7 @@ func FsStream(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	if t == nil {
-		common.SuccessResp(c)
-		return
-	}
-	common.SuccessResp(c, gin.H{
-		"task": getTaskInfo(t),
-	})
+	putResp(c, t, name, obj.Name)
 }
 
 func FsForm(c *gin.Context) {
@@ -141,19 +183,17 @@ func FsForm(c *gin.Context) {
 		return
 	}
 	asTask := c.GetHeader("As-Task") == "true"
-	overwrite := c.GetHeader("Overwrite") != "false"
+	policy, err := getConflictPolicy(c)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 	path, err = user.JoinPath(path)
 	if err != nil {
 		common.ErrorResp(c, err, 403)
 		return
 	}
-	if !overwrite {
-		if res, _ := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); res != nil {
-			common.ErrorStrResp(c, "file exists", 403)
-			return
-		}
-	}
 	storage, err := fs.GetStorage(path, &fs.GetStoragesArgs{})
 	if err != nil {
 		common.ErrorResp(c, err, 400)
@@ -194,13 +234,17 @@ func FsForm(c *gin.Context) {
 	if len(mimetype) == 0 {
 		mimetype = utils.GetMimeType(name)
 	}
+	obj := &model.Object{
+		Name:     name,
+		Size:     file.Size,
+		Modified: getLastModified(c),
+		HashInfo: utils.NewHashInfoByMap(h),
+	}
+	if obj.Name = putConflict(c, policy, dir, obj); obj.Name == "" {
+		return
+	}
 	s := &stream.FileStream{
-		Obj: &model.Object{
-			Name:     name,
-			Size:     file.Size,
-			Modified: getLastModified(c),
-			HashInfo: utils.NewHashInfoByMap(h),
-		},
+		Obj:          obj,
 		Reader:       f,
 		Mimetype:     mimetype,
 		WebPutAsTask: asTask,
@@ -218,11 +262,5 @@ func FsForm(c *gin.Context) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	if t == nil {
-		common.SuccessResp(c)
-		return
-	}
-	common.SuccessResp(c, gin.H{
-		"task": getTaskInfo(t),
-	})
+	putResp(c, t, name, obj.Name)
 }
@@ -0,0 +1,154 @@
+package handles
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"gopkg.in/ldap.v3"
+)
+
+// ldapGroupRule grants a role, permissions and a base path to the members of an LDAP group.
+// Group is either the full DN of the group or only its first RDN value, e.g. its cn.
+type ldapGroupRule struct {
+	Group      string `json:"group"`
+	Admin      bool   `json:"admin"`
+	Permission int32  `json:"permission"`
+	BasePath   string `json:"base_path"`
+}
+
+type ldapGrant struct {
+	Role       int
+	Permission int32
+	BasePath   string
+}
+
+// getLdapGroupRules returns nil when no rule is configured,
+// in which case existing users are left untouched on login
+func getLdapGroupRules() []ldapGroupRule {
+	var rules []ldapGroupRule
+	raw := strings.TrimSpace(setting.GetStr(conf.LdapGroupRules))
+	if raw == "" {
+		return nil
+	}
+	if err := utils.Json.UnmarshalFromString(raw, &rules); err != nil {
+		utils.Log.Errorf("failed to parse ldap group rules: %v", err)
+		return nil
+	}
+	return rules
+}
+
+// ldapUserGroups collects the groups of the user from the group attribute of
+// its entry and, if a group search base is configured, from a group search
+func ldapUserGroups(l *ldap.Conn, entry *ldap.Entry) []string {
+	var groups []string
+	if attr := setting.GetStr(conf.LdapGroupAttribute); attr != "" {
+		groups = append(groups, entry.GetAttributeValues(attr)...)
+	}
+	base := setting.GetStr(conf.LdapGroupSearchBase)
+	if base == "" {
+		return groups
+	}
+	filter := strings.ReplaceAll(setting.GetStr(conf.LdapGroupSearchFilter), "%s", ldap.EscapeFilter(entry.DN))
+	sr, err := l.Search(ldap.NewSearchRequest(
+		base,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter,
+		[]string{"dn"},
+		nil,
+	))
+	if err != nil {
+		utils.Log.Errorf("LDAP group search failed: %v", err)
+		return groups
+	}
+	for _, g := range sr.Entries {
+		groups = append(groups, g.DN)
+	}
+	return groups
+}
+
+func ldapGroupMatch(rule, group string) bool {
+	if strings.EqualFold(rule, group) {
+		return true
+	}
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return false
+	}
+	return strings.EqualFold(rule, dn.RDNs[0].Attributes[0].Value)
+}
+
+// matchLdapGroupRules combines all the rules matching the groups of the user,
+// falling back to the LDAP defaults when none matches
+func matchLdapGroupRules(rules []ldapGroupRule, groups []string) ldapGrant {
+	grant := ldapGrant{Role: model.GENERAL}
+	matched := false
+	for _, rule := range rules {
+		for _, group := range groups {
+			if !ldapGroupMatch(rule.Group, group) {
+				continue
+			}
+			matched = true
+			if rule.Admin {
+				grant.Role = model.ADMIN
+			}
+			grant.Permission |= rule.Permission
+			if grant.BasePath == "" {
+				grant.BasePath = rule.BasePath
+			}
+			break
+		}
+	}
+	if !matched {
+		grant.Permission = int32(setting.GetInt(conf.LdapDefaultPermission, 0))
+	}
+	if grant.BasePath == "" {
+		grant.BasePath = setting.GetStr(conf.LdapDefaultDir)
+	}
+	return grant
+}
+
+// adoptLdapUser marks an existing user who logged in with LDAP as an LDAP user, so that the
+// group rules apply to it. The users registered by the LDAP login before the users had a source
+// are recognized by their random password which was never hashed, the other users are only
+// adopted when conf.LdapAdoptUsers is enabled.
+func adoptLdapUser(user *model.User) error {
+	if user.Source != "" || user.IsGuest() {
+		return nil
+	}
+	if admin, err := op.GetAdmin(); err == nil && admin.ID == user.ID {
+		return nil
+	}
+	registered := user.PwdHash == "" && user.Password != ""
+	if !registered && !setting.GetBool(conf.LdapAdoptUsers) {
+		return nil
+	}
+	user.Source = model.UserSourceLdap
+	utils.Log.Infof("user %s is marked as an LDAP user", user.Username)
+	return op.UpdateUser(user)
+}
+
+// applyLdapGrant updates an existing LDAP user, see adoptLdapUser, to match the grant.
+// The other users, e.g. the built-in admin or a local user with the same name,
+// are managed in OpenList and never changed by the LDAP groups.
+func applyLdapGrant(user *model.User, grant ldapGrant) error {
+	if user.Source != model.UserSourceLdap || user.IsGuest() {
+		return nil
+	}
+	if admin, err := op.GetAdmin(); err == nil && admin.ID == user.ID {
+		return nil
+	}
+	basePath := utils.FixAndCleanPath(grant.BasePath)
+	if user.Role == grant.Role && user.Permission == grant.Permission && user.BasePath == basePath {
+		return nil
+	}
+	user.Role = grant.Role
+	user.Permission = grant.Permission
+	user.BasePath = basePath
+	utils.Log.Infof("LDAP groups changed user %s to role %d, permission %d, base path %s",
+		user.Username, grant.Role, grant.Permission, basePath)
+	return op.UpdateUser(user)
+}
@@ -0,0 +1,158 @@
+package handles
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestMatchLdapGroupRules(t *testing.T) {
+	items := []model.SettingItem{
+		{Key: conf.LdapDefaultPermission, Value: "1", Type: conf.TypeNumber, Group: model.LDAP},
+		{Key: conf.LdapDefaultDir, Value: "/ldap", Type: conf.TypeString, Group: model.LDAP},
+	}
+	if err := op.SaveSettingItems(items); err != nil {
+		t.Fatal(err)
+	}
+	rules := []ldapGroupRule{
+		{Group: "admins", Admin: true, Permission: 0xff, BasePath: "/"},
+		{Group: "cn=editors,ou=groups,dc=example,dc=com", Permission: 8 | 16, BasePath: "/team"},
+		{Group: "viewers", Permission: 4},
+	}
+	tests := []struct {
+		name   string
+		groups []string
+		expect ldapGrant
+	}{
+		{"no group", nil, ldapGrant{Role: model.GENERAL, Permission: 1, BasePath: "/ldap"}},
+		{"unknown group", []string{"cn=others,dc=example,dc=com"}, ldapGrant{Role: model.GENERAL, Permission: 1, BasePath: "/ldap"}},
+		{"first rdn", []string{"CN=Admins,ou=groups,dc=example,dc=com"}, ldapGrant{Role: model.ADMIN, Permission: 0xff, BasePath: "/"}},
+		{"full dn", []string{"cn=editors,ou=groups,dc=example,dc=com"}, ldapGrant{Role: model.GENERAL, Permission: 8 | 16, BasePath: "/team"}},
+		{"rule without base path", []string{"viewers"}, ldapGrant{Role: model.GENERAL, Permission: 4, BasePath: "/ldap"}},
+		{"combined", []string{"viewers", "cn=editors,ou=groups,dc=example,dc=com"}, ldapGrant{Role: model.GENERAL, Permission: 4 | 8 | 16, BasePath: "/team"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchLdapGroupRules(rules, tt.groups); got != tt.expect {
+				t.Errorf("expect %+v, got %+v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestApplyLdapGrant(t *testing.T) {
+	admin := &model.User{Username: "ldap_admin", Role: model.ADMIN, Permission: 0xff, BasePath: "/"}
+	if err := op.CreateUser(admin); err != nil {
+		t.Fatal(err)
+	}
+	grant := ldapGrant{Role: model.ADMIN, Permission: 4, BasePath: "/team"}
+	tests := []struct {
+		name   string
+		user   model.User
+		expect model.User
+	}{
+		{
+			"ldap user",
+			model.User{Username: "ldap_user", Role: model.GENERAL, Permission: 1, BasePath: "/", Source: model.UserSourceLdap},
+			model.User{Role: model.ADMIN, Permission: 4, BasePath: "/team"},
+		},
+		{
+			"local user",
+			model.User{Username: "local_user", Role: model.GENERAL, Permission: 1, BasePath: "/home"},
+			model.User{Role: model.GENERAL, Permission: 1, BasePath: "/home"},
+		},
+		{
+			"guest",
+			model.User{Username: "ldap_guest", Role: model.GUEST, Permission: 1, BasePath: "/", Source: model.UserSourceLdap},
+			model.User{Role: model.GUEST, Permission: 1, BasePath: "/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := tt.user
+			if err := op.CreateUser(&user); err != nil {
+				t.Fatal(err)
+			}
+			if err := applyLdapGrant(&user, grant); err != nil {
+				t.Fatal(err)
+			}
+			got, err := op.GetUserById(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Role != tt.expect.Role || got.Permission != tt.expect.Permission || got.BasePath != tt.expect.BasePath {
+				t.Errorf("expect role %d, permission %d, base path %s, got %d, %d, %s",
+					tt.expect.Role, tt.expect.Permission, tt.expect.BasePath, got.Role, got.Permission, got.BasePath)
+			}
+		})
+	}
+
+	// the admin is never changed, even if it's marked as created by LDAP
+	admin.Source = model.UserSourceLdap
+	if err := applyLdapGrant(admin, ldapGrant{Role: model.GENERAL, Permission: 1, BasePath: "/ldap"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := op.GetUserById(admin.ID); got.Role != model.ADMIN || got.Permission != 0xff || got.BasePath != "/" {
+		t.Errorf("expect the admin to be kept, got %+v", got)
+	}
+}
+
+func TestAdoptLdapUser(t *testing.T) {
+	grant := ldapGrant{Role: model.GENERAL, Permission: 4, BasePath: "/team"}
+	login := func(user *model.User) *model.User {
+		if err := adoptLdapUser(user); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyLdapGrant(user, grant); err != nil {
+			t.Fatal(err)
+		}
+		got, err := op.GetUserById(user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	// registered by the LDAP login of an older version, with the random password never hashed
+	upgraded := &model.User{Username: "ldap_upgraded", Password: "0123456789abcdef", Role: model.GENERAL, Permission: 1, BasePath: "/"}
+	if err := op.CreateUser(upgraded); err != nil {
+		t.Fatal(err)
+	}
+	if got := login(upgraded); got.Source != model.UserSourceLdap || got.Permission != 4 || got.BasePath != "/team" {
+		t.Errorf("expect the upgraded LDAP user to be adopted, got %+v", got)
+	}
+
+	local := &model.User{Username: "ldap_local", Role: model.GENERAL, Permission: 1, BasePath: "/home"}
+	local.SetPassword("password")
+	if err := op.CreateUser(local); err != nil {
+		t.Fatal(err)
+	}
+	if got := login(local); got.Source != "" || got.Permission != 1 || got.BasePath != "/home" {
+		t.Errorf("expect the local user to be kept, got %+v", got)
+	}
+	if err := op.SaveSettingItems([]model.SettingItem{
+		{Key: conf.LdapAdoptUsers, Value: "true", Type: conf.TypeBool, Group: model.LDAP},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer op.SaveSettingItems([]model.SettingItem{
+		{Key: conf.LdapAdoptUsers, Value: "false", Type: conf.TypeBool, Group: model.LDAP},
+	})
+	if got := login(local); got.Source != model.UserSourceLdap || got.Permission != 4 || got.BasePath != "/team" {
+		t.Errorf("expect the local user to be adopted when enabled, got %+v", got)
+	}
+}
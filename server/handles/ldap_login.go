@@ -11,6 +11,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/webhook"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
@@ -69,11 +70,15 @@ func loginLdap(c *gin.Context, req *LoginReq) {
 	}
 
 	// Search for the given username
+	attributes := []string{"dn"}
+	if groupAttr := setting.GetStr(conf.LdapGroupAttribute); groupAttr != "" {
+		attributes = append(attributes, groupAttr)
+	}
 	searchRequest := ldap.NewSearchRequest(
 		ldapUserSearchBase,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		fmt.Sprintf(ldapUserSearchFilter, req.Username),
-		[]string{"dn"},
+		attributes,
 		nil,
 	)
 	sr, err := l.Search(searchRequest)
@@ -87,7 +92,8 @@ func loginLdap(c *gin.Context, req *LoginReq) {
 		common.ErrorResp(c, err, 500)
 		return
 	}
-	userDN := sr.Entries[0].DN
+	userEntry := sr.Entries[0]
+	userDN := userEntry.DN
 
 	// Bind as the user to verify their password
 	err = l.Bind(userDN, req.Password)
@@ -95,20 +101,45 @@ func loginLdap(c *gin.Context, req *LoginReq) {
 		utils.Log.Errorf("Failed to auth. %v", err)
 		common.ErrorResp(c, err, 400)
 		model.LoginCache.Set(ip, count+1)
+		webhook.LoginFailed(req.Username, ip, c.Request.UserAgent())
 		return
 	} else {
 		utils.Log.Infof("Auth successful username:%s", req.Username)
 	}
 	// Auth finished
 
+	// Map groups to role and permissions, the rebind above may have dropped
+	// the read permission of the manager, so bind as manager again if possible
+	var grant *ldapGrant
+	if rules := getLdapGroupRules(); len(rules) > 0 {
+		if ldapManagerDN != "" && ldapManagerPassword != "" {
+			if err = l.Bind(ldapManagerDN, ldapManagerPassword); err != nil {
+				utils.Log.Errorf("Failed to rebind to LDAP: %v", err)
+			}
+		}
+		g := matchLdapGroupRules(rules, ldapUserGroups(l, userEntry))
+		grant = &g
+	}
+
 	user, err := op.GetUserByName(req.Username)
 	if err != nil {
-		user, err = ladpRegister(req.Username)
+		user, err = ladpRegister(req.Username, grant)
 		if err != nil {
 			common.ErrorResp(c, err, 400)
 			model.LoginCache.Set(ip, count+1)
 			return
 		}
+	} else {
+		if err = adoptLdapUser(user); err != nil {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+		if grant != nil {
+			if err = applyLdapGrant(user, *grant); err != nil {
+				common.ErrorResp(c, err, 500, true)
+				return
+			}
+		}
 	}
 
 	// generate token
@@ -117,22 +148,30 @@ func loginLdap(c *gin.Context, req *LoginReq) {
 		common.ErrorResp(c, err, 400, true)
 		return
 	}
-	common.SuccessResp(c, gin.H{"token": token})
+	common.SuccessResp(c, gin.H{"token": token, "enroll_2fa": common.NeedEnroll2FA(user)})
 	model.LoginCache.Del(ip)
 }
 
-func ladpRegister(username string) (*model.User, error) {
+func ladpRegister(username string, grant *ldapGrant) (*model.User, error) {
 	if username == "" {
 		return nil, errors.New("cannot get username from ldap provider")
 	}
+	if grant == nil {
+		grant = &ldapGrant{
+			Role:       model.GENERAL,
+			Permission: int32(setting.GetInt(conf.LdapDefaultPermission, 0)),
+			BasePath:   setting.GetStr(conf.LdapDefaultDir),
+		}
+	}
 	user := &model.User{
 		ID:         0,
 		Username:   username,
 		Password:   random.String(16),
-		Permission: int32(setting.GetInt(conf.LdapDefaultPermission, 0)),
-		BasePath:   setting.GetStr(conf.LdapDefaultDir),
-		Role:       0,
+		Permission: grant.Permission,
+		BasePath:   utils.FixAndCleanPath(grant.BasePath),
+		Role:       grant.Role,
 		Disabled:   false,
+		Source:     model.UserSourceLdap,
 	}
 	if err := db.CreateUser(user); err != nil {
 		return nil, err
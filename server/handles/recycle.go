@@ -0,0 +1,99 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// ListRecycleItems lists the recycle bin, users other than admins only see what they deleted
+func ListRecycleItems(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	var userId uint
+	if !user.IsAdmin() {
+		userId = user.ID
+	}
+	items, total, err := op.GetRecycleItems(userId, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: items,
+		Total:   total,
+	})
+}
+
+type RecycleReq struct {
+	IDs []uint `json:"ids"`
+}
+
+func getRecycleItems(c *gin.Context) ([]*model.RecycleItem, bool) {
+	var req RecycleReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return nil, false
+	}
+	if len(req.IDs) == 0 {
+		common.ErrorStrResp(c, "Empty recycle item ids", 400)
+		return nil, false
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !user.CanRemove() {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return nil, false
+	}
+	items := make([]*model.RecycleItem, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		item, err := op.GetRecycleItemById(id)
+		if err != nil {
+			common.ErrorResp(c, err, 404)
+			return nil, false
+		}
+		if !user.IsAdmin() && (item.UserID != user.ID || !utils.IsSubPath(user.BasePath, item.Path)) {
+			common.ErrorResp(c, errs.PermissionDenied, 403)
+			return nil, false
+		}
+		items = append(items, item)
+	}
+	return items, true
+}
+
+func RestoreRecycleItems(c *gin.Context) {
+	items, ok := getRecycleItems(c)
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		if err := op.RestoreRecycleItem(c.Request.Context(), item); err != nil {
+			common.ErrorResp(c, errors.WithMessagef(err, "failed to restore [%s]", item.Path), 500)
+			return
+		}
+	}
+	common.SuccessResp(c)
+}
+
+func PurgeRecycleItems(c *gin.Context) {
+	items, ok := getRecycleItems(c)
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		if err := op.PurgeRecycleItem(c.Request.Context(), item); err != nil {
+			common.ErrorResp(c, errors.WithMessagef(err, "failed to purge [%s]", item.Path), 500)
+			return
+		}
+	}
+	common.SuccessResp(c)
+}
@@ -9,6 +9,8 @@ import (
 type ManualScanReq struct {
 	Path  string  `json:"path"`
 	Limit float64 `json:"limit"`
+	// Depth limits the levels of the directories to warm, 0 for all of them
+	Depth int `json:"depth"`
 }
 
 func StartManualScan(c *gin.Context) {
@@ -17,7 +19,11 @@ func StartManualScan(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	if err := op.BeginManualScan(req.Path, req.Limit); err != nil {
+	if req.Depth < 0 {
+		common.ErrorStrResp(c, "depth must not be negative", 400)
+		return
+	}
+	if err := op.BeginManualScan(req.Path, req.Limit, req.Depth); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
@@ -0,0 +1,128 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/job"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func ListScheduledJobs(c *gin.Context) {
+	jobs, err := job.GetJobs()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, jobs)
+}
+
+func getScheduledJob(c *gin.Context) (*model.ScheduledJob, bool) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return nil, false
+	}
+	j, err := job.GetJobById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return nil, false
+	}
+	return j, true
+}
+
+func GetScheduledJob(c *gin.Context) {
+	if j, ok := getScheduledJob(c); ok {
+		common.SuccessResp(c, j)
+	}
+}
+
+func CreateScheduledJob(c *gin.Context) {
+	var req model.ScheduledJob
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	j := &model.ScheduledJob{
+		Name:     req.Name,
+		Kind:     req.Kind,
+		Cron:     req.Cron,
+		Args:     req.Args,
+		Disabled: req.Disabled,
+	}
+	if err := job.CreateJob(j); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, j)
+}
+
+func UpdateScheduledJob(c *gin.Context) {
+	var req model.ScheduledJob
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	j, err := job.UpdateJob(&req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, j)
+}
+
+func DeleteScheduledJob(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := job.DeleteJobById(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// RunScheduledJob runs the job now, out of its schedule
+func RunScheduledJob(c *gin.Context) {
+	j, ok := getScheduledJob(c)
+	if !ok {
+		return
+	}
+	run, err := job.Trigger(j, true)
+	if err != nil {
+		if errors.Is(err, job.ErrRunning) {
+			common.ErrorResp(c, err, 409)
+		} else {
+			common.ErrorResp(c, err, 500, true)
+		}
+		return
+	}
+	common.SuccessResp(c, run)
+}
+
+func ListScheduledJobRuns(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	runs, total, err := job.GetJobRuns(uint(id), req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: runs,
+		Total:   total,
+	})
+}
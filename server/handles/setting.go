@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/bandwidth"
 	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap/data"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
@@ -145,3 +146,8 @@ func DeleteSetting(c *gin.Context) {
 func PublicSettings(c *gin.Context) {
 	common.SuccessResp(c, op.GetPublicSettingsMap())
 }
+
+// BandwidthStatus shows the period of the bandwidth schedule and the limits in force
+func BandwidthStatus(c *gin.Context) {
+	common.SuccessResp(c, bandwidth.Status())
+}
@@ -2,19 +2,25 @@ package handles
 
 import (
 	"fmt"
+	"io"
+	"net/url"
 	stdpath "path"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/blockcache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/sharing"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
 	"github.com/OpenListTeam/go-cache"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
@@ -34,6 +40,7 @@ func SharingGet(c *gin.Context, req *FsGetReq) {
 		return
 	}
 	_ = countAccess(c.ClientIP(), s)
+	logSharing(c, s, model.SharingLogAccess, path, 0)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	url := ""
 	if !obj.IsDir() {
@@ -80,6 +87,7 @@ func SharingList(c *gin.Context, req *ListReq) {
 		return
 	}
 	_ = countAccess(c.ClientIP(), s)
+	logSharing(c, s, model.SharingLogAccess, path, 0)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	total, objs := pagination(objs, &req.PageReq)
 	common.SuccessResp(c, FsListResp{
@@ -103,7 +111,7 @@ func SharingList(c *gin.Context, req *ListReq) {
 		Total:    int64(total),
 		Readme:   s.Readme,
 		Header:   s.Header,
-		Write:    false,
+		Write:    s.IsUpload(),
 		Provider: "unknown",
 	})
 }
@@ -136,6 +144,7 @@ func SharingArchiveMeta(c *gin.Context, req *ArchiveMetaReq) {
 		return
 	}
 	_ = countAccess(c.ClientIP(), s)
+	logSharing(c, s, model.SharingLogAccess, path, 0)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	url := fmt.Sprintf("%s/sad%s", common.GetApiUrl(c), utils.EncodePath(fakePath, true))
 	if s.Pwd != "" {
@@ -182,6 +191,7 @@ func SharingArchiveList(c *gin.Context, req *ArchiveListReq) {
 		return
 	}
 	_ = countAccess(c.ClientIP(), s)
+	logSharing(c, s, model.SharingLogAccess, path, 0)
 	total, objs := pagination(objs, &req.PageReq)
 	ret, _ := utils.SliceConvert(objs, func(src model.Obj) (ObjResp, error) {
 		return toObjsRespWithoutSignAndThumb(src), nil
@@ -203,13 +213,26 @@ func SharingDown(c *gin.Context) {
 			err = errs.InvalidSharing
 		} else if !s.Verify(pwd) {
 			err = errs.WrongShareCode
-		} else if len(s.Files) != 1 && path == "/" {
+		} else if s.IsUpload() {
+			err = errs.SharingUploadOnly
+		} else if len(s.Files) != 1 && path == "/" && c.Query("pack") == "" {
 			err = errors.New("cannot get sharing root link")
 		}
 	}
 	if dealErrorPage(c, err) {
 		return
 	}
+	if format := c.Query("pack"); format != "" {
+		dl, ok := limitSharingDownload(c, s)
+		if !ok {
+			return
+		}
+		defer dl.Done()
+		_ = countAccess(c.ClientIP(), s)
+		sharingPack(c, s, path, format)
+		logSharing(c, s, model.SharingLogDownload, path, int64(c.Writer.Size()))
+		return
+	}
 	unwrapPath, err := op.GetSharingUnwrapPath(s, path)
 	if err != nil {
 		common.ErrorPage(c, errors.New("failed get sharing unwrap path"), 500)
@@ -219,11 +242,12 @@ func SharingDown(c *gin.Context) {
 	if dealErrorPage(c, err) {
 		return
 	}
-	if setting.GetBool(conf.ShareForceProxy) || common.ShouldProxy(storage, stdpath.Base(actualPath)) {
-		if _, ok := c.GetQuery("d"); !ok {
+	if setting.GetBool(conf.ShareForceProxy) || s.MustProxy() || common.ShouldProxy(storage, stdpath.Base(actualPath)) {
+		if _, ok := c.GetQuery("d"); !ok && !s.MustProxy() {
 			if url := common.GenerateDownProxyURL(storage.GetStorage(), unwrapPath); url != "" {
 				c.Redirect(302, url)
 				_ = countAccess(c.ClientIP(), s)
+				logSharing(c, s, model.SharingLogRedirect, path, 0)
 				return
 			}
 		}
@@ -235,8 +259,16 @@ func SharingDown(c *gin.Context) {
 			common.ErrorPage(c, errors.WithMessage(err, "failed get sharing link"), 500)
 			return
 		}
+		dl, ok := limitSharingDownload(c, s)
+		if !ok {
+			_ = link.Close()
+			return
+		}
+		defer dl.Done()
 		_ = countAccess(c.ClientIP(), s)
+		link = blockcache.Link(storage.GetStorage(), stdpath.Join(storage.GetStorage().MountPath, actualPath), obj, c.Query("type"), link)
 		proxy(c, link, obj, storage.GetStorage().ProxyRange)
+		logSharing(c, s, model.SharingLogDownload, path, int64(c.Writer.Size()))
 	} else {
 		link, _, err := op.Link(c.Request.Context(), storage, actualPath, model.LinkArgs{
 			IP:       c.ClientIP(),
@@ -250,6 +282,7 @@ func SharingDown(c *gin.Context) {
 		}
 		_ = countAccess(c.ClientIP(), s)
 		redirect(c, link)
+		logSharing(c, s, model.SharingLogRedirect, path, 0)
 	}
 }
 
@@ -270,6 +303,8 @@ func SharingArchiveExtract(c *gin.Context) {
 			err = errs.InvalidSharing
 		} else if !s.Verify(pwd) {
 			err = errs.WrongShareCode
+		} else if s.IsUpload() {
+			err = errs.SharingUploadOnly
 		} else if len(s.Files) != 1 && path == "/" {
 			err = errors.New("cannot extract sharing root")
 		}
@@ -297,12 +332,19 @@ func SharingArchiveExtract(c *gin.Context) {
 		InnerPath: innerPath,
 	}
 	if _, ok := storage.(driver.ArchiveReader); ok {
-		if setting.GetBool(conf.ShareForceProxy) || common.ShouldProxy(storage, stdpath.Base(actualPath)) {
+		if setting.GetBool(conf.ShareForceProxy) || s.MustProxy() || common.ShouldProxy(storage, stdpath.Base(actualPath)) {
 			link, obj, err := op.DriverExtract(c.Request.Context(), storage, actualPath, args)
 			if dealErrorPage(c, err) {
 				return
 			}
+			dl, ok := limitSharingDownload(c, s)
+			if !ok {
+				_ = link.Close()
+				return
+			}
+			defer dl.Done()
 			proxy(c, link, obj, storage.GetStorage().ProxyRange)
+			logSharing(c, s, model.SharingLogDownload, stdpath.Join(path, innerPath), int64(c.Writer.Size()))
 		} else {
 			args.Redirect = true
 			link, _, err := op.DriverExtract(c.Request.Context(), storage, actualPath, args)
@@ -310,14 +352,22 @@ func SharingArchiveExtract(c *gin.Context) {
 				return
 			}
 			redirect(c, link)
+			logSharing(c, s, model.SharingLogRedirect, stdpath.Join(path, innerPath), 0)
 		}
 	} else {
 		rc, size, err := op.InternalExtract(c.Request.Context(), storage, actualPath, args)
 		if dealErrorPage(c, err) {
 			return
 		}
+		dl, ok := limitSharingDownload(c, s)
+		if !ok {
+			_ = rc.Close()
+			return
+		}
+		defer dl.Done()
 		fileName := stdpath.Base(innerPath)
 		proxyInternalExtract(c, rc, size, fileName)
+		logSharing(c, s, model.SharingLogDownload, stdpath.Join(path, innerPath), int64(c.Writer.Size()))
 	}
 }
 
@@ -330,6 +380,9 @@ func dealError(c *gin.Context, err error) bool {
 		common.ErrorStrResp(c, "the share has expired or is no longer valid", 500)
 	} else if errors.Is(err, errs.WrongShareCode) {
 		common.ErrorResp(c, err, 403)
+	} else if errors.Is(err, errs.SharingUploadOnly) || errors.Is(err, errs.SharingNotUploadable) ||
+		errors.Is(err, errs.SharingUploadRejected) || errors.Is(err, errs.PermissionDenied) {
+		common.ErrorResp(c, err, 403)
 	} else if errors.Is(err, errs.WrongArchivePassword) {
 		common.ErrorResp(c, err, 202)
 	} else {
@@ -345,8 +398,11 @@ func dealErrorPage(c *gin.Context, err error) bool {
 		common.ErrorPage(c, errors.New("the share does not exist"), 500)
 	} else if errors.Is(err, errs.InvalidSharing) {
 		common.ErrorPage(c, errors.New("the share has expired or is no longer valid"), 500)
-	} else if errors.Is(err, errs.WrongShareCode) {
+	} else if errors.Is(err, errs.WrongShareCode) || errors.Is(err, errs.SharingUploadOnly) ||
+		errors.Is(err, errs.SharingTrafficExceeded) {
 		common.ErrorPage(c, err, 403)
+	} else if errors.Is(err, errs.SharingTooManyDownloads) {
+		common.ErrorPage(c, err, 429)
 	} else if errors.Is(err, errs.WrongArchivePassword) {
 		common.ErrorPage(c, err, 202)
 	} else {
@@ -417,6 +473,19 @@ type UpdateSharingReq struct {
 	Remark      string     `json:"remark"`
 	Readme      string     `json:"readme"`
 	Header      string     `json:"header"`
+	Type        int        `json:"type"`
+	// file request options
+	UploadMaxFileSize  int64  `json:"upload_max_file_size"`
+	UploadMaxTotalSize int64  `json:"upload_max_total_size"`
+	UploadAllowedExts  string `json:"upload_allowed_exts"`
+	UploadNamePrefix   bool   `json:"upload_name_prefix"`
+	// download limits
+	MaxTraffic      int64 `json:"max_traffic"`
+	MaxDailyTraffic int64 `json:"max_daily_traffic"`
+	SpeedLimit      int64 `json:"speed_limit"`
+	MaxConcurrent   int   `json:"max_concurrent"`
+	ForceProxy      bool  `json:"force_proxy"`
+	ResetTraffic    bool  `json:"reset_traffic"`
 	model.Sort
 	CreatorName string `json:"creator"`
 	Accessed    int    `json:"accessed"`
@@ -465,6 +534,9 @@ func UpdateSharing(c *gin.Context) {
 	if reqUser.IsAdmin() && req.CreatorName == "" {
 		user = s.Creator
 	}
+	if !checkUploadSharing(c, &req, user) || !checkSharingLimits(c, &req) {
+		return
+	}
 	s.Files = req.Files
 	s.Expires = req.Expires
 	s.Pwd = req.Pwd
@@ -475,8 +547,24 @@ func UpdateSharing(c *gin.Context) {
 	s.Header = req.Header
 	s.Readme = req.Readme
 	s.Remark = req.Remark
+	s.Type = req.Type
+	s.UploadMaxFileSize = req.UploadMaxFileSize
+	s.UploadMaxTotalSize = req.UploadMaxTotalSize
+	s.UploadAllowedExts = req.UploadAllowedExts
+	s.UploadNamePrefix = req.UploadNamePrefix
+	s.MaxTraffic = req.MaxTraffic
+	s.MaxDailyTraffic = req.MaxDailyTraffic
+	s.SpeedLimit = req.SpeedLimit
+	s.MaxConcurrent = req.MaxConcurrent
+	s.ForceProxy = req.ForceProxy
 	s.Creator = user
-	if err = op.UpdateSharing(s); err != nil {
+	if req.ResetTraffic {
+		err = sharing.ResetTraffic(s)
+	}
+	if err == nil {
+		err = op.UpdateSharing(s)
+	}
+	if err != nil {
 		common.ErrorResp(c, err, 500)
 	} else {
 		common.SuccessResp(c, SharingResp{
@@ -521,18 +609,31 @@ func CreateSharing(c *gin.Context) {
 			return
 		}
 	}
+	if !checkUploadSharing(c, &req, user) || !checkSharingLimits(c, &req) {
+		return
+	}
 	s := &model.Sharing{
 		SharingDB: &model.SharingDB{
-			ID:          req.ID,
-			Expires:     req.Expires,
-			Pwd:         req.Pwd,
-			Accessed:    req.Accessed,
-			MaxAccessed: req.MaxAccessed,
-			Disabled:    req.Disabled,
-			Sort:        req.Sort,
-			Remark:      req.Remark,
-			Readme:      req.Readme,
-			Header:      req.Header,
+			ID:                 req.ID,
+			Expires:            req.Expires,
+			Pwd:                req.Pwd,
+			Accessed:           req.Accessed,
+			MaxAccessed:        req.MaxAccessed,
+			Disabled:           req.Disabled,
+			Sort:               req.Sort,
+			Remark:             req.Remark,
+			Readme:             req.Readme,
+			Header:             req.Header,
+			Type:               req.Type,
+			UploadMaxFileSize:  req.UploadMaxFileSize,
+			UploadMaxTotalSize: req.UploadMaxTotalSize,
+			UploadAllowedExts:  req.UploadAllowedExts,
+			UploadNamePrefix:   req.UploadNamePrefix,
+			MaxTraffic:         req.MaxTraffic,
+			MaxDailyTraffic:    req.MaxDailyTraffic,
+			SpeedLimit:         req.SpeedLimit,
+			MaxConcurrent:      req.MaxConcurrent,
+			ForceProxy:         req.ForceProxy,
 		},
 		Files:   req.Files,
 		Creator: user,
@@ -550,6 +651,112 @@ func CreateSharing(c *gin.Context) {
 	}
 }
 
+// checkUploadSharing validates a file request: it must target exactly one
+// folder that the creator is allowed to write into
+func checkUploadSharing(c *gin.Context, req *UpdateSharingReq, user *model.User) bool {
+	if req.Type == model.SharingTypeRead {
+		return true
+	}
+	if req.Type != model.SharingTypeUpload {
+		common.ErrorStrResp(c, "invalid sharing type", 400)
+		return false
+	}
+	if len(req.Files) != 1 {
+		common.ErrorStrResp(c, "a file request must target exactly 1 folder", 400)
+		return false
+	}
+	obj, err := fs.Get(c.Request.Context(), req.Files[0], &fs.GetArgs{NoLog: true})
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return false
+	}
+	if !obj.IsDir() {
+		common.ErrorStrResp(c, "a file request must target a folder", 400)
+		return false
+	}
+	meta, err := op.GetNearestMeta(req.Files[0])
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		common.ErrorResp(c, err, 500, true)
+		return false
+	}
+	if !user.CanWrite() && !common.CanWrite(meta, req.Files[0]) {
+		common.ErrorStrResp(c, "permission denied to upload into the folder", 403)
+		return false
+	}
+	if req.UploadMaxFileSize < 0 || req.UploadMaxTotalSize < 0 {
+		common.ErrorStrResp(c, "upload limits must not be negative", 400)
+		return false
+	}
+	return true
+}
+
+func checkSharingLimits(c *gin.Context, req *UpdateSharingReq) bool {
+	if req.MaxTraffic < 0 || req.MaxDailyTraffic < 0 || req.SpeedLimit < 0 || req.MaxConcurrent < 0 {
+		common.ErrorStrResp(c, "download limits must not be negative", 400)
+		return false
+	}
+	return true
+}
+
+// SharingUpload receives a file from a visitor of a file request.
+// The file name is sent in the File-Name header, the optional uploader name
+// in the Uploader header, both url-encoded.
+func SharingUpload(c *gin.Context) {
+	defer func() {
+		if n, _ := io.ReadFull(c.Request.Body, []byte{0}); n == 1 {
+			_, _ = utils.CopyWithBuffer(io.Discard, c.Request.Body)
+		}
+		_ = c.Request.Body.Close()
+	}()
+	sid := c.Request.Context().Value(conf.SharingIDKey).(string)
+	name, err := url.PathUnescape(c.GetHeader("File-Name"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	uploader, err := url.PathUnescape(c.GetHeader("Uploader"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if shouldIgnoreSystemFile(name) {
+		common.ErrorStrResp(c, errs.IgnoredSystemFile.Error(), 403)
+		return
+	}
+	size := c.Request.ContentLength
+	if size < 0 {
+		if sizeStr := c.GetHeader("X-File-Size"); sizeStr != "" {
+			size, err = strconv.ParseInt(sizeStr, 10, 64)
+			if err != nil {
+				common.ErrorResp(c, err, 400)
+				return
+			}
+		}
+	}
+	mimetype := c.GetHeader("Content-Type")
+	if len(mimetype) == 0 {
+		mimetype = utils.GetMimeType(name)
+	}
+	pwd := c.GetHeader("Password")
+	if pwd == "" {
+		pwd = c.Query("pwd")
+	}
+	s, name, err := sharing.Upload(c.Request.Context(), sid, sharing.UploadArgs{
+		Pwd:      pwd,
+		Uploader: uploader,
+		Name:     name,
+		Size:     size,
+		Mimetype: mimetype,
+		Modified: getLastModified(c),
+		Reader:   c.Request.Body,
+	})
+	if dealError(c, err) {
+		return
+	}
+	logSharing(c, s, model.SharingLogUpload, name, size)
+	common.SuccessResp(c, gin.H{"name": name})
+}
+
 func DeleteSharing(c *gin.Context) {
 	sid := c.Query("id")
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
@@ -583,6 +790,20 @@ func SetEnableSharing(disable bool) func(ctx *gin.Context) {
 	}
 }
 
+// limitSharingDownload applies the download limits of the share to the response
+// and counts its traffic, Done must be called on the returned download
+func limitSharingDownload(c *gin.Context, s *model.Sharing) (*sharing.Download, bool) {
+	dl, err := sharing.StartDownload(s)
+	if dealErrorPage(c, err) {
+		return nil, false
+	}
+	c.Writer = &middlewares.ResponseWriterWrapper{
+		ResponseWriter: c.Writer,
+		WrapWriter:     dl.Writer(c, c.Writer),
+	}
+	return dl, true
+}
+
 var (
 	AccessCache      = cache.NewMemCache[interface{}]()
 	AccessCountDelay = 30 * time.Minute
@@ -594,6 +815,7 @@ func countAccess(ip string, s *model.Sharing) error {
 	if !ok {
 		AccessCache.Set(key, struct{}{}, cache.WithEx[interface{}](AccessCountDelay))
 		s.Accessed += 1
+		logFirstOpen(ip, s)
 		return op.UpdateSharing(s, true)
 	}
 	return nil
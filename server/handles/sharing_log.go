@@ -0,0 +1,129 @@
+package handles
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// logSharing records a visit of a share, path is the path inside the share
+func logSharing(c *gin.Context, s *model.Sharing, typ, path string, bytes int64) {
+	if !setting.GetBool(conf.ShareAccessLog) {
+		return
+	}
+	err := op.CreateSharingAccessLog(&model.SharingAccessLog{
+		SharingID: s.ID,
+		Type:      typ,
+		Path:      utils.FixAndCleanPath(path),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Bytes:     max(bytes, 0),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		utils.Log.Warnf("failed to record access of sharing [%s]: %+v", s.ID, err)
+	}
+}
+
+// logFirstOpen adds a system log for the creator when a share is opened for the first time
+func logFirstOpen(ip string, s *model.Sharing) {
+	if s.Accessed != 1 || s.Creator == nil || !setting.GetBool(conf.ShareFirstOpenLog) {
+		return
+	}
+	err := op.AddSystemLog(s.Creator, model.SystemLog{
+		Type:    "info",
+		Message: fmt.Sprintf("sharing [%s] was opened for the first time", s.ID),
+		Source:  "sharing",
+		IP:      ip,
+	})
+	if err != nil {
+		utils.Log.Warnf("failed to add first open log of sharing [%s]: %+v", s.ID, err)
+	}
+}
+
+func getOwnSharing(c *gin.Context) (*model.Sharing, bool) {
+	sid := c.Query("id")
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	s, err := op.GetSharingById(sid)
+	if err != nil || (!user.IsAdmin() && s.CreatorId != user.ID) {
+		common.ErrorStrResp(c, "sharing not found", 404)
+		return nil, false
+	}
+	return s, true
+}
+
+func SharingAccessLogs(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	s, ok := getOwnSharing(c)
+	if !ok {
+		return
+	}
+	logs, total, err := op.GetSharingAccessLogs(s.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: logs,
+		Total:   total,
+	})
+}
+
+type SharingStatsReq struct {
+	Days int `json:"days" form:"days"`
+	Top  int `json:"top" form:"top"`
+}
+
+type SharingStatsResp struct {
+	Accessed int                         `json:"accessed"`
+	Timeline []model.SharingTimelineItem `json:"timeline"`
+	TopFiles []model.SharingTopFile      `json:"top_files"`
+}
+
+// SharingStats returns the daily timeline and the most downloaded files of a share
+func SharingStats(c *gin.Context) {
+	var req SharingStatsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if req.Days <= 0 || req.Days > 365 {
+		req.Days = 30
+	}
+	if req.Top <= 0 || req.Top > 100 {
+		req.Top = 10
+	}
+	s, ok := getOwnSharing(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day()-req.Days+1, 0, 0, 0, 0, now.Location())
+	timeline, err := op.GetSharingTimeline(s.ID, since)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	top, err := op.GetSharingTopFiles(s.ID, since, req.Top)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, SharingStatsResp{
+		Accessed: s.Accessed,
+		Timeline: timeline,
+		TopFiles: top,
+	})
+}
@@ -208,3 +208,8 @@ func LoadAllStorages(c *gin.Context) {
 	}(storages)
 	common.SuccessResp(c)
 }
+
+// StorageAPIStats returns the API limits of the loaded storages and the calls to their drivers
+func StorageAPIStats(c *gin.Context) {
+	common.SuccessResp(c, op.GetStorageAPIStats())
+}
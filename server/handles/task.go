@@ -28,6 +28,7 @@ type TaskInfo struct {
 	EndTime     *time.Time  `json:"end_time"`
 	TotalBytes  int64       `json:"total_bytes"`
 	Error       string      `json:"error"`
+	Paused      bool        `json:"paused"`
 }
 
 func getTaskInfo[T task.TaskExtensionInfo](task T) TaskInfo {
@@ -58,6 +59,7 @@ func getTaskInfo[T task.TaskExtensionInfo](task T) TaskInfo {
 		EndTime:     task.GetEndTime(),
 		TotalBytes:  task.GetTotalBytes(),
 		Error:       errMsg,
+		Paused:      task.IsPaused(),
 	}
 }
 
@@ -134,9 +136,9 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 		}
 		common.SuccessResp(c, getTaskInfos(manager.GetByCondition(func(task T) bool {
 			// avoid directly passing the user object into the function to reduce closure size
-			return (isAdmin || uid == task.GetCreator().ID) &&
+			return (isAdmin || uid == task.GetCreator().ID) && (task.IsPaused() ||
 				argsContains(task.GetState(), tache.StatePending, tache.StateRunning, tache.StateCanceling,
-					tache.StateErrored, tache.StateFailing, tache.StateWaitingRetry, tache.StateBeforeRetry)
+					tache.StateErrored, tache.StateFailing, tache.StateWaitingRetry, tache.StateBeforeRetry))
 		})))
 	})
 	g.GET("/done", func(c *gin.Context) {
@@ -147,7 +149,7 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 			return
 		}
 		common.SuccessResp(c, getTaskInfos(manager.GetByCondition(func(task T) bool {
-			return (isAdmin || uid == task.GetCreator().ID) &&
+			return (isAdmin || uid == task.GetCreator().ID) && !task.IsPaused() &&
 				argsContains(task.GetState(), tache.StateCanceled, tache.StateFailed, tache.StateSucceeded)
 		})))
 	})
@@ -158,22 +160,28 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 		manager.Cancel(task.GetID())
 		common.SuccessResp(c)
 	}))
-	g.POST("/delete", getTargetedHandler(manager, func(c *gin.Context, task T) {
-		manager.Remove(task.GetID())
+	g.POST("/delete", getTargetedHandler(manager, func(c *gin.Context, t T) {
+		task.RemoveTask(manager, t)
 		common.SuccessResp(c)
 	}))
 	g.POST("/retry", getTargetedHandler(manager, func(c *gin.Context, task T) {
+		if task.IsPaused() {
+			common.ErrorStrResp(c, "task is paused, resume it instead", 400)
+			return
+		}
 		manager.Retry(task.GetID())
 		common.SuccessResp(c)
 	}))
 	g.POST("/cancel_some", getBatchHandler(manager, func(task T) {
 		manager.Cancel(task.GetID())
 	}))
-	g.POST("/delete_some", getBatchHandler(manager, func(task T) {
-		manager.Remove(task.GetID())
+	g.POST("/delete_some", getBatchHandler(manager, func(t T) {
+		task.RemoveTask(manager, t)
 	}))
 	g.POST("/retry_some", getBatchHandler(manager, func(task T) {
-		manager.Retry(task.GetID())
+		if !task.IsPaused() {
+			manager.Retry(task.GetID())
+		}
 	}))
 	g.POST("/clear_done", func(c *gin.Context) {
 		isAdmin, uid, ok := getUserInfo(c)
@@ -182,10 +190,13 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 			common.ErrorStrResp(c, "user invalid", 401)
 			return
 		}
-		manager.RemoveByCondition(func(task T) bool {
-			return (isAdmin || uid == task.GetCreator().ID) &&
+		tasks := manager.GetByCondition(func(task T) bool {
+			return (isAdmin || uid == task.GetCreator().ID) && !task.IsPaused() &&
 				argsContains(task.GetState(), tache.StateCanceled, tache.StateFailed, tache.StateSucceeded)
 		})
+		for _, t := range tasks {
+			task.RemoveTask(manager, t)
+		}
 		common.SuccessResp(c)
 	})
 	g.POST("/clear_succeeded", func(c *gin.Context) {
@@ -208,7 +219,7 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 			return
 		}
 		tasks := manager.GetByCondition(func(task T) bool {
-			return (isAdmin || uid == task.GetCreator().ID) && task.GetState() == tache.StateFailed
+			return (isAdmin || uid == task.GetCreator().ID) && task.GetState() == tache.StateFailed && !task.IsPaused()
 		})
 		for _, t := range tasks {
 			manager.Retry(t.GetID())
@@ -217,12 +228,73 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 	})
 }
 
+// pauseRoute adds the routes of the managers whose tasks can be paused
+func pauseRoute[T task.PausableTask](g *gin.RouterGroup, manager task.Manager[T]) {
+	g.POST("/pause", getTargetedHandler(manager, func(c *gin.Context, t T) {
+		if !t.CanPause() {
+			common.ErrorStrResp(c, "task can't be paused", 400)
+			return
+		}
+		t.Pause(false)
+		common.SuccessResp(c)
+	}))
+	g.POST("/resume", getTargetedHandler(manager, func(c *gin.Context, t T) {
+		if err := task.ResumeTask(manager, t); err != nil {
+			common.ErrorResp(c, err, 400)
+			return
+		}
+		common.SuccessResp(c)
+	}))
+	g.POST("/pause_some", getBatchHandler(manager, func(t T) {
+		if t.CanPause() {
+			t.Pause(false)
+		}
+	}))
+	g.POST("/resume_some", getBatchHandler(manager, func(t T) {
+		_ = task.ResumeTask(manager, t)
+	}))
+}
+
 func SetupTaskRoute(g *gin.RouterGroup) {
-	taskRoute(g.Group("/upload"), fs.UploadTaskManager)
-	taskRoute(g.Group("/copy"), fs.CopyTaskManager)
-	taskRoute(g.Group("/move"), fs.MoveTaskManager)
+	uploadGroup := g.Group("/upload")
+	taskRoute(uploadGroup, fs.UploadTaskManager)
+	pauseRoute(uploadGroup, fs.UploadTaskManager)
+	copyGroup := g.Group("/copy")
+	taskRoute(copyGroup, fs.CopyTaskManager)
+	pauseRoute(copyGroup, fs.CopyTaskManager)
+	copyGroup.POST("/result", getTargetedHandler(fs.CopyTaskManager, func(c *gin.Context, task *fs.FileTransferTask) {
+		common.SuccessResp(c, task.GetResult())
+	}))
+	moveGroup := g.Group("/move")
+	taskRoute(moveGroup, fs.MoveTaskManager)
+	pauseRoute(moveGroup, fs.MoveTaskManager)
+	moveGroup.POST("/result", getTargetedHandler(fs.MoveTaskManager, func(c *gin.Context, task *fs.FileTransferTask) {
+		common.SuccessResp(c, task.GetResult())
+	}))
 	taskRoute(g.Group("/offline_download"), tool.DownloadTaskManager)
 	taskRoute(g.Group("/offline_download_transfer"), tool.TransferTaskManager)
-	taskRoute(g.Group("/decompress"), fs.ArchiveDownloadTaskManager)
-	taskRoute(g.Group("/decompress_upload"), fs.ArchiveContentUploadTaskManager)
+	decompressGroup := g.Group("/decompress")
+	taskRoute(decompressGroup, fs.ArchiveDownloadTaskManager)
+	pauseRoute(decompressGroup, fs.ArchiveDownloadTaskManager)
+	decompressGroup.POST("/result", getTargetedHandler(fs.ArchiveDownloadTaskManager, func(c *gin.Context, task *fs.ArchiveDownloadTask) {
+		common.SuccessResp(c, task.GetResult())
+	}))
+	decompressUploadGroup := g.Group("/decompress_upload")
+	taskRoute(decompressUploadGroup, fs.ArchiveContentUploadTaskManager)
+	pauseRoute(decompressUploadGroup, fs.ArchiveContentUploadTaskManager)
+	syncGroup := g.Group("/sync")
+	taskRoute(syncGroup, fs.SyncTaskManager)
+	syncGroup.POST("/result", getTargetedHandler(fs.SyncTaskManager, func(c *gin.Context, task *fs.SyncTask) {
+		common.SuccessResp(c, task.GetResult())
+	}))
+	dedupeGroup := g.Group("/dedupe")
+	taskRoute(dedupeGroup, fs.DedupeTaskManager)
+	dedupeGroup.POST("/result", getTargetedHandler(fs.DedupeTaskManager, func(c *gin.Context, task *fs.DedupeTask) {
+		common.SuccessResp(c, task.GetResult())
+	}))
+	checksumGroup := g.Group("/checksum")
+	taskRoute(checksumGroup, fs.ChecksumTaskManager)
+	checksumGroup.POST("/result", getTargetedHandler(fs.ChecksumTaskManager, func(c *gin.Context, task *fs.ChecksumTask) {
+		common.SuccessResp(c, task.GetResult())
+	}))
 }
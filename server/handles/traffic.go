@@ -0,0 +1,79 @@
+package handles
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// trafficFilter reads the filter of the traffic stats from the query, the last 7 days by default
+func trafficFilter(c *gin.Context) (db.TrafficStatFilter, error) {
+	start, end, err := parseRange(c)
+	if err != nil {
+		return db.TrafficStatFilter{}, err
+	}
+	if start == nil {
+		t := time.Now().AddDate(0, 0, -7)
+		start = &t
+	}
+	f := db.TrafficStatFilter{
+		Start:     start,
+		End:       end,
+		Protocol:  c.Query("protocol"),
+		SharingID: c.Query("sharing_id"),
+	}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return f, err
+		}
+		f.UserID = uint(id)
+	}
+	if v := c.Query("storage_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return f, err
+		}
+		f.StorageID = uint(id)
+	}
+	return f, nil
+}
+
+// TrafficSeries returns the traffic by hour, or by day with interval=day
+func TrafficSeries(c *gin.Context) {
+	f, err := trafficFilter(c)
+	if err != nil {
+		common.ErrorResp(c, err, http.StatusBadRequest)
+		return
+	}
+	points, err := traffic.Series(f, c.Query("interval") == "day")
+	if err != nil {
+		common.ErrorResp(c, err, http.StatusInternalServerError, true)
+		return
+	}
+	common.SuccessResp(c, points)
+}
+
+// TrafficTop returns the users, storages, protocols or shares with the most traffic
+func TrafficTop(c *gin.Context) {
+	f, err := trafficFilter(c)
+	if err != nil {
+		common.ErrorResp(c, err, http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+	top, err := traffic.Top(f, c.DefaultQuery("by", "user"), c.Query("order"), limit)
+	if err != nil {
+		common.ErrorResp(c, err, http.StatusBadRequest)
+		return
+	}
+	common.SuccessResp(c, top)
+}
@@ -0,0 +1,314 @@
+package handles
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/tus"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the tus 1.0 endpoint, the clients speak http status codes and headers
+// instead of the json responses of the other handles
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+	tusChunkType  = "application/offset+octet-stream"
+)
+
+func tusError(c *gin.Context, code int, err error) {
+	if code >= 500 {
+		log.Errorf("tus: %+v", err)
+	}
+	c.String(code, err.Error())
+	c.Abort()
+}
+
+// tusResumable checks the protocol version of the request
+func tusResumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		tusError(c, http.StatusPreconditionFailed, errors.New("unsupported tus version"))
+		return false
+	}
+	return true
+}
+
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, " ")
+		v, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, errors.Errorf("invalid metadata [%s]", key)
+		}
+		metadata[key] = string(v)
+	}
+	return metadata, nil
+}
+
+func setTusUploadHeaders(c *gin.Context, u *tus.Upload) {
+	c.Header("Upload-Offset", strconv.FormatInt(u.Offset(), 10))
+	c.Header("Upload-Expires", u.Expires().UTC().Format(http.TimeFormat))
+}
+
+func getTusUpload(c *gin.Context) (*tus.Upload, bool) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	u, err := tus.Get(c.Param("id"), user.ID)
+	switch {
+	case errors.Is(err, tus.ErrExpired):
+		tusError(c, http.StatusGone, err)
+	case err != nil:
+		tusError(c, http.StatusNotFound, err)
+	default:
+		return u, true
+	}
+	return nil, false
+}
+
+// checkTusTarget does the checks of FsUp and FsStream on the path of a new upload
+func checkTusTarget(c *gin.Context, user *model.User, path string, policy string) (int, error) {
+	meta, err := op.GetNearestMeta(stdpath.Dir(path))
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return http.StatusInternalServerError, err
+	}
+	if !(common.CanAccess(user, meta, path, c.GetHeader("Password")) && (user.CanWrite() || common.CanWrite(meta, stdpath.Dir(path)))) {
+		return http.StatusForbidden, errs.PermissionDenied
+	}
+	storage, err := fs.GetStorage(path, &fs.GetStoragesArgs{})
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if storage.Config().NoUpload {
+		return http.StatusMethodNotAllowed, errs.UploadNotSupported
+	}
+	if shouldIgnoreSystemFile(stdpath.Base(path)) {
+		return http.StatusForbidden, errs.IgnoredSystemFile
+	}
+	// the other policies are applied once the file is complete
+	if policy == fs.ConflictFail {
+		if res, _ := fs.Get(c.Request.Context(), path, &fs.GetArgs{NoLog: true}); res != nil {
+			return http.StatusConflict, errors.New("file exists")
+		}
+	}
+	return 0, nil
+}
+
+// finishTusUpload puts the complete upload like FsStream does
+func finishTusUpload(c *gin.Context, u *tus.Upload) bool {
+	dir := stdpath.Dir(u.Path)
+	name, err := fs.PutConflict(c.Request.Context(), dir, &model.Object{Name: u.Name(), Size: u.Size, Modified: u.Modified}, u.ConflictPolicy)
+	if err != nil || name == "" {
+		// a skipped file is dropped as if it was put
+		_ = tus.Terminate(u)
+		if errors.Is(err, errs.ObjectAlreadyExists) {
+			tusError(c, http.StatusConflict, errors.New("file exists"))
+			return false
+		} else if err != nil {
+			tusError(c, http.StatusInternalServerError, err)
+			return false
+		}
+		return true
+	}
+	u.Path = stdpath.Join(dir, name)
+	s, err := tus.Finish(u)
+	if errors.Is(err, tus.ErrLocked) {
+		tusError(c, http.StatusLocked, err)
+		return false
+	}
+	if err != nil {
+		tusError(c, http.StatusInternalServerError, err)
+		return false
+	}
+	// the put goes on if the client goes away, a failed one is kept for the client to finish it
+	// again by a PATCH at the end of the upload, instead of sending the file again
+	ctx := context.WithoutCancel(c.Request.Context())
+	if u.AsTask {
+		_, err = fs.PutAsTask(ctx, dir, s)
+		if err != nil {
+			_ = s.Close()
+		}
+	} else {
+		err = fs.PutDirectly(ctx, dir, s, true)
+	}
+	tus.Done(u, err)
+	if err != nil {
+		tusError(c, http.StatusInternalServerError, err)
+		return false
+	}
+	return true
+}
+
+func TusOptions(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Status(http.StatusNoContent)
+}
+
+// TusCreate creates an upload to the File-Path header, As-Task, Conflict-Policy,
+// Last-Modified and the hash headers work as for FsStream
+func TusCreate(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		tusError(c, http.StatusBadRequest, errors.New("invalid Upload-Length"))
+		return
+	}
+	metadata, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		tusError(c, http.StatusBadRequest, err)
+		return
+	}
+	path, err := url.PathUnescape(c.GetHeader("File-Path"))
+	if err != nil || path == "" {
+		tusError(c, http.StatusBadRequest, errors.New("invalid File-Path"))
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	path, err = user.JoinPath(path)
+	if err != nil {
+		tusError(c, http.StatusForbidden, err)
+		return
+	}
+	policy, err := getConflictPolicy(c)
+	if err != nil {
+		tusError(c, http.StatusBadRequest, err)
+		return
+	}
+	if code, err := checkTusTarget(c, user, path, policy); err != nil {
+		tusError(c, code, err)
+		return
+	}
+	h := make(map[*utils.HashType]string)
+	if md5 := c.GetHeader("X-File-Md5"); md5 != "" {
+		h[utils.MD5] = md5
+	}
+	if sha1 := c.GetHeader("X-File-Sha1"); sha1 != "" {
+		h[utils.SHA1] = sha1
+	}
+	if sha256 := c.GetHeader("X-File-Sha256"); sha256 != "" {
+		h[utils.SHA256] = sha256
+	}
+	u := &tus.Upload{
+		UserID:         user.ID,
+		Path:           path,
+		Size:           size,
+		Metadata:       c.GetHeader("Upload-Metadata"),
+		AsTask:         c.GetHeader("As-Task") == "true",
+		ConflictPolicy: policy,
+		Modified:       getLastModified(c),
+		Mimetype:       metadata["filetype"],
+		Hashes:         h,
+	}
+	if err = tus.Create(u); err != nil {
+		tusError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Header("Location", common.GetApiUrl(c)+"/api/fs/tus/"+u.ID)
+	setTusUploadHeaders(c, u)
+	if u.Complete() && !finishTusUpload(c, u) {
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+func TusHead(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	u, ok := getTusUpload(c)
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Length", strconv.FormatInt(u.Size, 10))
+	if u.Metadata != "" {
+		c.Header("Upload-Metadata", u.Metadata)
+	}
+	setTusUploadHeaders(c, u)
+	c.Status(http.StatusOK)
+}
+
+// TusPatch appends a chunk, the file is put once the last byte is received
+func TusPatch(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	u, ok := getTusUpload(c)
+	if !ok {
+		return
+	}
+	if c.ContentType() != tusChunkType {
+		tusError(c, http.StatusUnsupportedMediaType, errors.Errorf("Content-Type must be %s", tusChunkType))
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		tusError(c, http.StatusBadRequest, errors.New("invalid Upload-Offset"))
+		return
+	}
+	if c.Request.ContentLength > u.Size-offset {
+		tusError(c, http.StatusRequestEntityTooLarge, errors.New("the chunk exceeds Upload-Length"))
+		return
+	}
+	_, err = u.Write(offset, c.Request.Body)
+	setTusUploadHeaders(c, u)
+	switch {
+	case errors.Is(err, tus.ErrLocked):
+		tusError(c, http.StatusLocked, err)
+		return
+	case errors.Is(err, tus.ErrOffsetMismatch):
+		tusError(c, http.StatusConflict, err)
+		return
+	case err != nil:
+		// mostly the client went away, what was received is kept for it to resume
+		log.Debugf("tus: chunk of %s interrupted: %+v", u.Path, err)
+		tusError(c, http.StatusBadRequest, err)
+		return
+	}
+	if u.Complete() && !finishTusUpload(c, u) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func TusTerminate(c *gin.Context) {
+	if !tusResumable(c) {
+		return
+	}
+	u, ok := getTusUpload(c)
+	if !ok {
+		return
+	}
+	if err := tus.Terminate(u); errors.Is(err, tus.ErrLocked) {
+		tusError(c, http.StatusLocked, err)
+		return
+	} else if err != nil {
+		tusError(c, http.StatusNotFound, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -74,6 +74,8 @@ func UpdateUser(c *gin.Context) {
 	if req.OtpSecret == "" {
 		req.OtpSecret = user.OtpSecret
 	}
+	req.RecoveryCodes = user.RecoveryCodes
+	req.Source = user.Source
 	if req.Disabled && req.IsAdmin() {
 		common.ErrorStrResp(c, "admin user can not be disabled", 400)
 		return
@@ -0,0 +1,197 @@
+package handles
+
+import (
+	"fmt"
+	stdpath "path"
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type FsVersionsReq struct {
+	Path     string `json:"path" form:"path"`
+	Password string `json:"password" form:"password"`
+}
+
+type FileVersionResp struct {
+	model.FileVersion
+	URL string `json:"url"`
+}
+
+func versionSignData(id uint) string {
+	return fmt.Sprintf("version:%d", id)
+}
+
+// FsVersions lists the kept versions of a file, newest first
+func FsVersions(c *gin.Context) {
+	var req FsVersionsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	reqPath, err := user.JoinPath(req.Path)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	meta, err := op.GetNearestMeta(stdpath.Dir(reqPath))
+	if err != nil {
+		if !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			common.ErrorResp(c, err, 500, true)
+			return
+		}
+	}
+	if !common.CanAccess(user, meta, reqPath, req.Password) {
+		common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
+		return
+	}
+	storage, actualPath, err := op.GetStorageAndActualPath(reqPath)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	versions, err := op.GetFileVersions(storage, actualPath)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	resp := make([]FileVersionResp, 0, len(versions))
+	for _, v := range versions {
+		resp = append(resp, FileVersionResp{
+			FileVersion: v,
+			URL:         fmt.Sprintf("%s/vd/%d?sign=%s", common.GetApiUrl(c), v.ID, common.SignPath(user, versionSignData(v.ID), true)),
+		})
+	}
+	common.SuccessResp(c, resp)
+}
+
+// FileVersionSignCheck verifies the signed link of FsVersions before the download is counted,
+// for the user it was signed for
+func FileVersionSignCheck(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ErrorPage(c, err, 400)
+		c.Abort()
+		return
+	}
+	s := c.Query("sign")
+	if err = sign.Verify(versionSignData(uint(id)), s); err != nil {
+		common.ErrorPage(c, err, 401)
+		c.Abort()
+		return
+	}
+	common.SignUser(c, s)
+	c.Next()
+}
+
+// FileVersionDown serves the content of a version through the signed link of FsVersions
+func FileVersionDown(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ErrorPage(c, err, 400)
+		return
+	}
+	v, err := op.GetFileVersionById(uint(id))
+	if err != nil {
+		common.ErrorPage(c, err, 404)
+		return
+	}
+	storage, err := op.GetFileVersionStorage(v)
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return
+	}
+	link, file, err := op.Link(c.Request.Context(), storage, v.VersionPath, model.LinkArgs{
+		Header: c.Request.Header,
+	})
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return
+	}
+	proxy(c, link, &model.ObjWrapName{Name: stdpath.Base(v.Path), Obj: file}, storage.GetStorage().ProxyRange)
+}
+
+type FileVersionsReq struct {
+	IDs      []uint `json:"ids"`
+	Password string `json:"password"`
+}
+
+func getFileVersions(c *gin.Context, allowed func(user *model.User) bool) ([]*model.FileVersion, *model.User, bool) {
+	var req FileVersionsReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return nil, nil, false
+	}
+	if len(req.IDs) == 0 {
+		common.ErrorStrResp(c, "Empty version ids", 400)
+		return nil, nil, false
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !allowed(user) {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return nil, nil, false
+	}
+	versions := make([]*model.FileVersion, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		v, err := op.GetFileVersionById(id)
+		if err != nil {
+			common.ErrorResp(c, err, 404)
+			return nil, nil, false
+		}
+		if !utils.IsSubPath(user.BasePath, v.Path) {
+			common.ErrorResp(c, errs.PermissionDenied, 403)
+			return nil, nil, false
+		}
+		meta, err := op.GetNearestMeta(stdpath.Dir(v.Path))
+		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			common.ErrorResp(c, err, 500, true)
+			return nil, nil, false
+		}
+		if !common.CanAccess(user, meta, v.Path, req.Password) {
+			common.ErrorStrResp(c, "password is incorrect or you have no permission", 403)
+			return nil, nil, false
+		}
+		versions = append(versions, v)
+	}
+	return versions, user, true
+}
+
+func RestoreFileVersion(c *gin.Context) {
+	versions, user, ok := getFileVersions(c, (*model.User).CanWrite)
+	if !ok {
+		return
+	}
+	if len(versions) != 1 {
+		common.ErrorStrResp(c, "only one version can be restored at a time", 400)
+		return
+	}
+	v := versions[0]
+	if err := op.RestoreFileVersion(c.Request.Context(), v, user); err != nil {
+		common.ErrorResp(c, errors.WithMessagef(err, "failed to restore [%s]", v.Path), 500)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func DeleteFileVersions(c *gin.Context) {
+	versions, _, ok := getFileVersions(c, (*model.User).CanRemove)
+	if !ok {
+		return
+	}
+	for _, v := range versions {
+		if err := op.DeleteFileVersion(c.Request.Context(), v); err != nil {
+			common.ErrorResp(c, errors.WithMessagef(err, "failed to delete version of [%s]", v.Path), 500)
+			return
+		}
+	}
+	common.SuccessResp(c)
+}
@@ -0,0 +1,132 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/webhook"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+func ListWebhooks(c *gin.Context) {
+	hooks, err := webhook.GetWebhooks()
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, hooks)
+}
+
+func getWebhook(c *gin.Context) (*model.Webhook, bool) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return nil, false
+	}
+	hook, err := webhook.GetWebhookById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 404)
+		return nil, false
+	}
+	return hook, true
+}
+
+func GetWebhook(c *gin.Context) {
+	if hook, ok := getWebhook(c); ok {
+		common.SuccessResp(c, hook)
+	}
+}
+
+func CreateWebhook(c *gin.Context) {
+	var req model.Webhook
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.ID = 0
+	if err := webhook.CreateWebhook(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, req)
+}
+
+func UpdateWebhook(c *gin.Context) {
+	var req model.Webhook
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	hook, err := webhook.UpdateWebhook(&req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, hook)
+}
+
+func DeleteWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := webhook.DeleteWebhookById(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+// TestWebhook sends a ping to the webhook and returns the delivery
+func TestWebhook(c *gin.Context) {
+	hook, ok := getWebhook(c)
+	if !ok {
+		return
+	}
+	delivery, err := webhook.Test(hook)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, delivery)
+}
+
+func ListWebhookDeliveries(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	deliveries, total, err := webhook.GetDeliveries(uint(id), req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: deliveries,
+		Total:   total,
+	})
+}
+
+// RedeliverWebhook sends the payload of a delivery again
+func RedeliverWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	delivery, err := webhook.Redeliver(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, delivery)
+}
@@ -129,6 +129,19 @@ func Authn(c *gin.Context) {
 	c.Next()
 }
 
+// Enrolled2FA rejects users who are required to enroll 2FA but have not done so yet.
+// It must be placed after Auth, and the admin token is always allowed.
+func Enrolled2FA(c *gin.Context) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	token := c.GetHeader("Authorization")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(setting.GetStr(conf.Token))) != 1 && common.NeedEnroll2FA(user) {
+		common.ErrorStrResp(c, "Two-factor authentication is required, enroll 2FA or WebAuthn first", 403)
+		c.Abort()
+	} else {
+		c.Next()
+	}
+}
+
 func AuthNotGuest(c *gin.Context) {
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
 	if user.IsGuest() {
@@ -33,15 +33,19 @@ func Down(verifyFunc func(string, string) error) func(c *gin.Context) {
 		}
 		common.GinWithValue(c, conf.MetaKey, meta)
 		// verify sign
+		s := strings.TrimSuffix(c.Query("sign"), "/")
 		if needSign(meta, rawPath) {
-			s := c.Query("sign")
-			err = verifyFunc(rawPath, strings.TrimSuffix(s, "/"))
+			err = verifyFunc(rawPath, s)
 			if err != nil {
 				common.ErrorPage(c, err, 401)
 				c.Abort()
 				return
 			}
+		} else if s != "" && verifyFunc(rawPath, s) != nil {
+			// the optional sign of the links of a user is ignored if it's invalid
+			s = ""
 		}
+		common.SignUser(c, s)
 		c.Next()
 	}
 }
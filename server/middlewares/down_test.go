@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/sign"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	gin.SetMode(gin.TestMode)
+}
+
+func TestSignedDownTraffic(t *testing.T) {
+	owner := &model.User{Username: "sign_owner", Role: model.GENERAL}
+	if err := op.CreateUser(owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.SaveSettingItems([]model.SettingItem{
+		{Key: conf.SignAll, Value: "true", Type: conf.TypeBool, Group: model.GLOBAL},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/d/*path", PathParse, Down(sign.Verify), UserDownloadLimiter, func(c *gin.Context) {
+		c.String(200, strings.Repeat("a", 1000))
+	})
+	down := func(path, s string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/d"+path+"?sign="+s, nil))
+		return w.Code
+	}
+
+	if code := down("/file", common.SignPath(owner, "/file", true)); code != 200 {
+		t.Fatalf("expect the signed download to succeed, got %d", code)
+	}
+	if got := traffic.UsageOf(owner).Download; got != 1000 {
+		t.Errorf("expect the download to be counted for the owner, got %d bytes", got)
+	}
+	// the sign of the owner can't be used for another path
+	if code := down("/other", common.SignPath(owner, "/file", true)); code != 401 {
+		t.Errorf("expect the sign of another path to be rejected, got %d", code)
+	}
+	// the links not bound to a user are not counted for the owner
+	if code := down("/file", sign.Sign("/file")); code != 200 {
+		t.Fatalf("expect the unbound sign to be valid, got %d", code)
+	}
+	if got := traffic.UsageOf(owner).Download; got != 1000 {
+		t.Errorf("expect the unbound download not to be counted for the owner, got %d bytes", got)
+	}
+}
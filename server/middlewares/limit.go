@@ -18,6 +18,7 @@ func MaxAllowed(n int) gin.HandlerFunc {
 	}
 }
 
+// UploadRateLimiter caps the upload speed with the limiter and the limits of the user
 func UploadRateLimiter(limiter stream.Limiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Request.Body = &stream.RateLimitReader{
@@ -25,7 +26,7 @@ func UploadRateLimiter(limiter stream.Limiter) gin.HandlerFunc {
 			Limiter: limiter,
 			Ctx:     c,
 		}
-		c.Next()
+		UserUploadLimiter(c)
 	}
 }
 
@@ -38,6 +39,7 @@ func (w *ResponseWriterWrapper) Write(p []byte) (n int, err error) {
 	return w.WrapWriter.Write(p)
 }
 
+// DownloadRateLimiter caps the download speed with the limiter and the limits of the user
 func DownloadRateLimiter(limiter stream.Limiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer = &ResponseWriterWrapper{
@@ -48,6 +50,6 @@ func DownloadRateLimiter(limiter stream.Limiter) gin.HandlerFunc {
 				Ctx:     c,
 			},
 		}
-		c.Next()
+		UserDownloadLimiter(c)
 	}
 }
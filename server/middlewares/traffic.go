@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/url"
+	stdpath "path"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// trafficUser returns the user the traffic of the request is counted for: the user of the
+// request or of its signed link, the creator of the share, the user of the optional token
+// of the signed links, or the guest at last
+func trafficUser(c *gin.Context) *model.User {
+	if user, ok := c.Request.Context().Value(conf.UserKey).(*model.User); ok && user != nil {
+		return user
+	}
+	if sid, ok := c.Request.Context().Value(conf.SharingIDKey).(string); ok {
+		if s, err := op.GetSharingById(sid); err == nil && s.Creator != nil {
+			return s.Creator
+		}
+	}
+	if token := c.GetHeader("Authorization"); token != "" {
+		if claims, err := common.ParseToken(token); err == nil {
+			if user, err := op.GetUserByName(claims.Username); err == nil && user.PwdTS == claims.PwdTS {
+				return user
+			}
+		}
+	}
+	guest, _ := op.GetGuest()
+	return guest
+}
+
+// TrafficProtocol tags the traffic of the requests with the protocol
+func TrafficProtocol(protocol string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tags := traffic.TagsFrom(c.Request.Context())
+		tags.Protocol = protocol
+		c.Request = c.Request.WithContext(traffic.WithTags(c.Request.Context(), tags))
+		c.Next()
+	}
+}
+
+// trafficPath returns the path the request transfers, or empty when it's unknown
+func trafficPath(c *gin.Context, user *model.User, tags *traffic.Tags) string {
+	ctx := c.Request.Context()
+	rawPath, _ := ctx.Value(conf.PathKey).(string)
+	if sid, ok := ctx.Value(conf.SharingIDKey).(string); ok {
+		tags.SharingID = sid
+		s, err := op.GetSharingById(sid)
+		if err != nil {
+			return ""
+		}
+		p, _ := op.GetSharingUnwrapPath(s, utils.FixAndCleanPath(rawPath))
+		return p
+	}
+	if rawPath != "" {
+		return rawPath
+	}
+	reqPath := ""
+	if tags.Protocol == traffic.ProtocolWebDAV {
+		reqPath = strings.TrimPrefix(c.Request.URL.Path, stdpath.Join(conf.URL.Path, "/dav"))
+	} else if h := c.GetHeader("File-Path"); h != "" {
+		reqPath, _ = url.PathUnescape(h)
+	}
+	if reqPath == "" {
+		return ""
+	}
+	p, _ := user.JoinPath(reqPath)
+	return p
+}
+
+// recordTraffic adds the bytes transferred by the request to the traffic stats
+func recordTraffic(c *gin.Context, user *model.User, dir traffic.Direction, n int64) {
+	if user == nil || n <= 0 {
+		return
+	}
+	tags := traffic.TagsFrom(c.Request.Context())
+	if tags.StorageID == 0 {
+		if p := trafficPath(c, user, &tags); p != "" {
+			if storage, _, err := op.GetStorageAndActualPath(p); err == nil {
+				tags.StorageID = storage.GetStorage().ID
+			}
+		}
+	}
+	traffic.Record(user, tags, dir, n)
+}
+
+// UserDownloadLimiter caps the download speed of the user of the request and counts the
+// traffic into the quota and the stats, the downloads are refused once the user has used up the quota
+func UserDownloadLimiter(c *gin.Context) {
+	user := trafficUser(c)
+	if c.Request.Method == http.MethodGet {
+		if err := traffic.Check(user); err != nil {
+			common.ErrorPage(c, err, http.StatusTooManyRequests)
+			c.Abort()
+			return
+		}
+	}
+	w := &traffic.Writer{
+		Writer: c.Writer,
+		Ctx:    c,
+		User:   user,
+	}
+	c.Writer = &ResponseWriterWrapper{
+		ResponseWriter: c.Writer,
+		WrapWriter:     w,
+	}
+	c.Next()
+	recordTraffic(c, user, traffic.Download, w.N)
+}
+
+// UserUploadLimiter caps the upload speed of the user of the request and counts the
+// traffic into the quota and the stats, the uploads are refused once the user has used up the quota
+func UserUploadLimiter(c *gin.Context) {
+	user := trafficUser(c)
+	if c.Request.ContentLength != 0 {
+		if err := traffic.Check(user); err != nil {
+			common.ErrorResp(c, err, http.StatusTooManyRequests)
+			c.Abort()
+			return
+		}
+	}
+	r := &traffic.Reader{
+		Reader: c.Request.Body,
+		Ctx:    c,
+		User:   user,
+	}
+	c.Request.Body = r
+	c.Next()
+	recordTraffic(c, user, traffic.Upload, r.N)
+}
@@ -41,6 +41,7 @@ func Init(e *gin.Engine) {
 	}
 	WebDav(g.Group("/dav"))
 	S3(g.Group("/s3"))
+	Scim(g.Group("/scim/v2"))
 
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	signCheck := middlewares.Down(sign.Verify)
@@ -64,6 +65,10 @@ func Init(e *gin.Engine) {
 	g.GET("/sad/:sid/*path", middlewares.PathParse, middlewares.SharingIdParse, downloadLimiter, handles.SharingArchiveExtract)
 	g.HEAD("/sad/:sid", middlewares.EmptyPathParse, middlewares.SharingIdParse, handles.SharingArchiveExtract)
 	g.HEAD("/sad/:sid/*path", middlewares.PathParse, middlewares.SharingIdParse, handles.SharingArchiveExtract)
+	g.GET("/pk/:key", handles.FsPackUser, downloadLimiter, handles.FsPackDown)
+	g.GET("/vd/:id", handles.FileVersionSignCheck, downloadLimiter, handles.FileVersionDown)
+	g.HEAD("/pk/:key", handles.FsPackUser, handles.FsPackDown)
+	g.PUT("/su/:sid", middlewares.SharingIdParse, middlewares.UploadRateLimiter(stream.ClientUploadLimit), handles.SharingUpload)
 
 	api := g.Group("/api")
 	auth := api.Group("", middlewares.Auth(false))
@@ -74,11 +79,12 @@ func Init(e *gin.Engine) {
 	api.POST("/auth/login/ldap", handles.LoginLdap)
 	auth.GET("/me", handles.CurrentUser)
 	auth.POST("/me/update", handles.UpdateCurrent)
-	auth.GET("/me/sshkey/list", handles.ListMyPublicKey)
-	auth.POST("/me/sshkey/add", handles.AddMyPublicKey)
-	auth.POST("/me/sshkey/delete", handles.DeleteMyPublicKey)
+	auth.GET("/me/sshkey/list", middlewares.Enrolled2FA, handles.ListMyPublicKey)
+	auth.POST("/me/sshkey/add", middlewares.Enrolled2FA, handles.AddMyPublicKey)
+	auth.POST("/me/sshkey/delete", middlewares.Enrolled2FA, handles.DeleteMyPublicKey)
 	auth.POST("/auth/2fa/generate", handles.Generate2FA)
 	auth.POST("/auth/2fa/verify", handles.Verify2FA)
+	auth.POST("/auth/2fa/recovery_codes", handles.RegenerateRecoveryCodes)
 	auth.GET("/auth/logout", handles.LogOut)
 
 	// auth
@@ -113,11 +119,11 @@ func Init(e *gin.Engine) {
 	devicesApi := api.Group("/devices", middlewares.WebdavBasicAPI)
 	devicesApi.POST("", handles.UpsertDevice)
 
-	_fs(auth.Group("/fs"))
-	fsAndShare(api.Group("/fs", middlewares.Auth(true)))
-	_task(auth.Group("/task", middlewares.AuthNotGuest))
-	_sharing(auth.Group("/share", middlewares.AuthNotGuest))
-	admin(auth.Group("/admin", middlewares.AuthAdmin))
+	_fs(auth.Group("/fs", middlewares.Enrolled2FA))
+	fsAndShare(api.Group("/fs", middlewares.Auth(true), middlewares.Enrolled2FA))
+	_task(auth.Group("/task", middlewares.AuthNotGuest, middlewares.Enrolled2FA))
+	_sharing(auth.Group("/share", middlewares.AuthNotGuest, middlewares.Enrolled2FA))
+	admin(auth.Group("/admin", middlewares.AuthAdmin, middlewares.Enrolled2FA))
 	if flags.Debug || flags.Dev {
 		debug(g.Group("/debug"))
 	}
@@ -165,6 +171,7 @@ func admin(g *gin.RouterGroup) {
 	storage.POST("/enable", handles.EnableStorage)
 	storage.POST("/disable", handles.DisableStorage)
 	storage.POST("/load_all", handles.LoadAllStorages)
+	storage.GET("/api_stats", handles.StorageAPIStats)
 
 	driver := g.Group("/driver")
 	driver.GET("/list", handles.ListDriverInfo)
@@ -178,6 +185,7 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/delete", handles.DeleteSetting)
 	setting.POST("/default", handles.DefaultSettings)
 	setting.POST("/reset_token", handles.ResetToken)
+	setting.GET("/bandwidth", handles.BandwidthStatus)
 	setting.POST("/set_aria2", handles.SetAria2)
 	setting.POST("/set_qbit", handles.SetQbittorrent)
 	setting.POST("/set_transmission", handles.SetTransmission)
@@ -192,6 +200,25 @@ func admin(g *gin.RouterGroup) {
 	// retain /admin/task API to ensure compatibility with legacy automation scripts
 	_task(g.Group("/task"))
 
+	scheduledJob := g.Group("/task/scheduled_job")
+	scheduledJob.GET("/list", handles.ListScheduledJobs)
+	scheduledJob.GET("/get", handles.GetScheduledJob)
+	scheduledJob.POST("/create", handles.CreateScheduledJob)
+	scheduledJob.POST("/update", handles.UpdateScheduledJob)
+	scheduledJob.POST("/delete", handles.DeleteScheduledJob)
+	scheduledJob.POST("/run", handles.RunScheduledJob)
+	scheduledJob.GET("/runs", handles.ListScheduledJobRuns)
+
+	webhook := g.Group("/webhook")
+	webhook.GET("/list", handles.ListWebhooks)
+	webhook.GET("/get", handles.GetWebhook)
+	webhook.POST("/create", handles.CreateWebhook)
+	webhook.POST("/update", handles.UpdateWebhook)
+	webhook.POST("/delete", handles.DeleteWebhook)
+	webhook.POST("/test", handles.TestWebhook)
+	webhook.GET("/deliveries", handles.ListWebhookDeliveries)
+	webhook.POST("/redeliver", handles.RedeliverWebhook)
+
 	ms := g.Group("/message")
 	ms.POST("/get", message.HttpInstance.GetHandle)
 	ms.POST("/send", message.HttpInstance.SendHandle)
@@ -222,6 +249,10 @@ func admin(g *gin.RouterGroup) {
 	monitor.POST("/devices/upload_script", handles.UploadDeviceScriptHandle)
 	monitor.POST("/devices/apply_heartbeat", handles.ApplyHeartbeatHandle)
 	monitor.POST("/devices/delete_script", handles.DeleteDeviceScriptHandle)
+
+	trafficStats := g.Group("/traffic")
+	trafficStats.GET("/series", handles.TrafficSeries)
+	trafficStats.GET("/top", handles.TrafficTop)
 }
 
 func fsAndShare(g *gin.RouterGroup) {
@@ -236,6 +267,7 @@ func _fs(g *gin.RouterGroup) {
 	g.Any("/search", middlewares.SearchIndex, handles.Search)
 	g.Any("/other", handles.FsOther)
 	g.Any("/dirs", handles.FsDirs)
+	g.GET("/events", handles.FsEvents)
 	g.POST("/mkdir", handles.FsMkdir)
 	g.POST("/rename", handles.FsRename)
 	g.POST("/batch_rename", handles.FsBatchRename)
@@ -243,11 +275,29 @@ func _fs(g *gin.RouterGroup) {
 	g.POST("/move", handles.FsMove)
 	g.POST("/recursive_move", handles.FsRecursiveMove)
 	g.POST("/copy", handles.FsCopy)
+	g.POST("/sync", handles.FsSync)
+	g.POST("/dedupe", handles.FsDedupe)
+	g.POST("/dedupe/apply", handles.FsDedupeApply)
+	g.POST("/checksum", handles.FsChecksum)
 	g.POST("/remove", handles.FsRemove)
 	g.POST("/remove_empty_directory", handles.FsRemoveEmptyDirectory)
+	g.POST("/pack", handles.FsPack)
+	g.Any("/recycle/list", handles.ListRecycleItems)
+	g.POST("/recycle/restore", handles.RestoreRecycleItems)
+	g.POST("/recycle/purge", handles.PurgeRecycleItems)
+	g.Any("/versions", handles.FsVersions)
+	g.POST("/versions/restore", handles.RestoreFileVersion)
+	g.POST("/versions/delete", handles.DeleteFileVersions)
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	g.PUT("/put", middlewares.FsUp, uploadLimiter, handles.FsStream)
 	g.PUT("/form", middlewares.FsUp, uploadLimiter, handles.FsForm)
+	// resumable uploads of the tus protocol
+	g.OPTIONS("/tus", handles.TusOptions)
+	g.OPTIONS("/tus/:id", handles.TusOptions)
+	g.POST("/tus", handles.TusCreate)
+	g.HEAD("/tus/:id", handles.TusHead)
+	g.PATCH("/tus/:id", uploadLimiter, handles.TusPatch)
+	g.DELETE("/tus/:id", handles.TusTerminate)
 	g.POST("/link", middlewares.AuthAdmin, handles.Link)
 	// g.POST("/add_aria2", handles.AddOfflineDownload)
 	// g.POST("/add_qbit", handles.AddQbittorrent)
@@ -270,6 +320,8 @@ func _sharing(g *gin.RouterGroup) {
 	g.POST("/delete", handles.DeleteSharing)
 	g.POST("/enable", handles.SetEnableSharing(false))
 	g.POST("/disable", handles.SetEnableSharing(true))
+	g.Any("/access_logs", handles.SharingAccessLogs)
+	g.GET("/stats", handles.SharingStats)
 }
 
 func Cors(r *gin.Engine) {
@@ -278,6 +330,9 @@ func Cors(r *gin.Engine) {
 	config.AllowOrigins = conf.Conf.Cors.AllowOrigins
 	config.AllowHeaders = conf.Conf.Cors.AllowHeaders
 	config.AllowMethods = conf.Conf.Cors.AllowMethods
+	// the tus clients in the browsers read these
+	config.ExposeHeaders = []string{"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension",
+		"Upload-Offset", "Upload-Length", "Upload-Metadata", "Upload-Expires"}
 	r.Use(cors.New(config))
 }
 
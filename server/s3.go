@@ -6,7 +6,12 @@ import (
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
 	"github.com/OpenListTeam/OpenList/v4/server/s3"
 	"github.com/gin-gonic/gin"
 )
@@ -29,11 +34,38 @@ func S3(g *gin.RouterGroup) {
 	g.Any("/*path", func(c *gin.Context) {
 		adjustedPath := strings.TrimPrefix(c.Request.URL.Path, path.Join(conf.URL.Path, "/s3"))
 		c.Request.URL.Path = adjustedPath
-		gin.WrapH(h)(c)
-	})
+		c.Next()
+	}, s3Traffic, middlewares.UserUploadLimiter, middlewares.UserDownloadLimiter, gin.WrapH(h))
 }
 
 func S3Server(g *gin.RouterGroup) {
 	h, _ := s3.NewServer(context.Background())
-	g.Any("/*path", gin.WrapH(h))
+	g.Any("/*path", s3Traffic, middlewares.UserUploadLimiter, middlewares.UserDownloadLimiter, gin.WrapH(h))
+}
+
+// s3Traffic counts the traffic of the S3 server for the user of the S3 traffic user setting,
+// or the admin if it's not set
+func s3Traffic(c *gin.Context) {
+	user, err := s3TrafficUser()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		c.Abort()
+		return
+	}
+	common.GinWithValue(c, conf.UserKey, user)
+	tags := traffic.Tags{Protocol: traffic.ProtocolS3}
+	if p := s3.ObjectPath(c.Request.URL.Path); p != "" {
+		if storage, _, err := op.GetStorageAndActualPath(p); err == nil {
+			tags.StorageID = storage.GetStorage().ID
+		}
+	}
+	c.Request = c.Request.WithContext(traffic.WithTags(c.Request.Context(), tags))
+	c.Next()
+}
+
+func s3TrafficUser() (*model.User, error) {
+	if name := setting.GetStr(conf.S3TrafficUser); name != "" {
+		return op.GetUserByName(name)
+	}
+	return op.GetAdmin()
 }
@@ -5,6 +5,7 @@ package s3
 import (
 	"context"
 	"encoding/json"
+	"path"
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
@@ -42,6 +43,16 @@ func getBucketByName(name string) (Bucket, error) {
 	return Bucket{}, gofakes3.BucketNotFound(name)
 }
 
+// ObjectPath returns the path of the object of a path-style request, or empty when the bucket is unknown
+func ObjectPath(reqPath string) string {
+	bucketName, objectName, _ := strings.Cut(strings.TrimPrefix(reqPath, "/"), "/")
+	bucket, err := getBucketByName(bucketName)
+	if err != nil {
+		return ""
+	}
+	return path.Join(bucket.Path, objectName)
+}
+
 func getDirEntries(path string) ([]model.Obj, error) {
 	ctx := context.Background()
 	meta, _ := op.GetNearestMeta(path)
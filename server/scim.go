@@ -0,0 +1,19 @@
+package server
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/server/scim"
+	"github.com/gin-gonic/gin"
+)
+
+func Scim(g *gin.RouterGroup) {
+	g.Use(scim.Auth)
+	g.GET("/ServiceProviderConfig", scim.ServiceProviderConfig)
+	g.GET("/ResourceTypes", scim.ResourceTypes)
+	users := g.Group("/Users")
+	users.GET("", scim.ListUsers)
+	users.POST("", scim.CreateUser)
+	users.GET("/:id", scim.GetUser)
+	users.PUT("/:id", scim.ReplaceUser)
+	users.PATCH("/:id", scim.PatchUser)
+	users.DELETE("/:id", scim.DeleteUser)
+}
@@ -0,0 +1,151 @@
+// Package scim implements the subset of SCIM 2.0 (RFC 7643/7644) needed by
+// identity providers to provision OpenList users.
+// OpenList has no group concept, so only the Users resource is exposed.
+package scim
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	SchemaUser            = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaListResponse    = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp         = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError           = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProvider = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType    = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	contentType           = "application/scim+json"
+	defaultCount          = 100
+	maxCount              = 1000
+	resourceTypeUser      = "User"
+	resourceEndpointUsers = "/Users"
+	documentationURI      = "https://datatracker.ietf.org/doc/html/rfc7644"
+
+	errTypeInvalidFilter = "invalidFilter"
+	errTypeInvalidSyntax = "invalidSyntax"
+	errTypeInvalidValue  = "invalidValue"
+	errTypeMutability    = "mutability"
+	errTypeNoTarget      = "noTarget"
+	errTypeUniqueness    = "uniqueness"
+)
+
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []User   `json:"Resources"`
+}
+
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+	Status   string   `json:"status"`
+}
+
+func resp(c *gin.Context, code int, data any) {
+	c.Header("Content-Type", contentType)
+	c.JSON(code, data)
+}
+
+func errorResp(c *gin.Context, code int, scimType, detail string) {
+	resp(c, code, Error{
+		Schemas:  []string{SchemaError},
+		ScimType: scimType,
+		Detail:   detail,
+		Status:   strconv.Itoa(code),
+	})
+	c.Abort()
+}
+
+// Auth checks the dedicated SCIM bearer token, which is unrelated to the
+// admin token so that it can be rotated independently
+func Auth(c *gin.Context) {
+	token := setting.GetStr(conf.SCIMToken)
+	if !setting.GetBool(conf.SCIMEnabled) || token == "" {
+		errorResp(c, http.StatusForbidden, "", "SCIM is not enabled")
+		return
+	}
+	bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+		errorResp(c, http.StatusUnauthorized, "", "invalid SCIM token")
+		return
+	}
+	c.Next()
+}
+
+func toSCIMUser(c *gin.Context, u *model.User) User {
+	active := !u.Disabled
+	id := strconv.FormatUint(uint64(u.ID), 10)
+	return User{
+		Schemas:     []string{SchemaUser},
+		ID:          id,
+		ExternalID:  u.SsoID,
+		UserName:    u.Username,
+		DisplayName: u.Username,
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: resourceTypeUser,
+			Location:     common.GetApiUrl(c) + "/scim/v2" + resourceEndpointUsers + "/" + id,
+		},
+	}
+}
+
+func ServiceProviderConfig(c *gin.Context) {
+	supported := func(b bool) gin.H { return gin.H{"supported": b} }
+	resp(c, http.StatusOK, gin.H{
+		"schemas":          []string{SchemaServiceProvider},
+		"documentationUri": documentationURI,
+		"patch":            supported(true),
+		"bulk":             gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           gin.H{"supported": true, "maxResults": maxCount},
+		"changePassword":   supported(false),
+		"sort":             supported(false),
+		"etag":             supported(false),
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with the SCIM token configured in the SSO settings",
+		}},
+	})
+}
+
+func ResourceTypes(c *gin.Context) {
+	resp(c, http.StatusOK, gin.H{
+		"schemas":      []string{SchemaListResponse},
+		"totalResults": 1,
+		"Resources": []gin.H{{
+			"schemas":  []string{SchemaResourceType},
+			"id":       resourceTypeUser,
+			"name":     resourceTypeUser,
+			"endpoint": resourceEndpointUsers,
+			"schema":   SchemaUser,
+		}},
+	})
+}
@@ -0,0 +1,280 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/gin-gonic/gin"
+)
+
+// only the `attr eq "value"` form is supported, which is what identity
+// providers use to look up a user before provisioning it
+var filterRegexp = regexp.MustCompile(`(?i)^\s*(\w+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+func parseFilter(filter string) (username, externalID string, err error) {
+	if filter == "" {
+		return "", "", nil
+	}
+	m := filterRegexp.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", fmt.Errorf("unsupported filter: %s", filter)
+	}
+	value := strings.ReplaceAll(m[2], `\"`, `"`)
+	switch strings.ToLower(m[1]) {
+	case "username":
+		return value, "", nil
+	case "externalid":
+		return "", value, nil
+	default:
+		return "", "", fmt.Errorf("unsupported filter attribute: %s", m[1])
+	}
+}
+
+func ListUsers(c *gin.Context) {
+	username, externalID, err := parseFilter(c.Query("filter"))
+	if err != nil {
+		errorResp(c, http.StatusBadRequest, errTypeInvalidFilter, err.Error())
+		return
+	}
+	startIndex, _ := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(defaultCount)))
+	if err != nil || count < 0 {
+		count = defaultCount
+	}
+	count = min(count, maxCount)
+	users, total, err := op.FindUsers(model.GENERAL, username, externalID, startIndex-1, count)
+	if err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resources := make([]User, 0, len(users))
+	for i := range users {
+		resources = append(resources, toSCIMUser(c, &users[i]))
+	}
+	resp(c, http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// getUser loads the user addressed by the :id param.
+// Admin and guest users are never managed through SCIM and are reported as missing.
+func getUser(c *gin.Context) *model.User {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errorResp(c, http.StatusNotFound, "", "user not found")
+		return nil
+	}
+	user, err := op.GetUserById(uint(id))
+	if err != nil || user.Role != model.GENERAL {
+		errorResp(c, http.StatusNotFound, "", "user not found")
+		return nil
+	}
+	return user
+}
+
+func GetUser(c *gin.Context) {
+	user := getUser(c)
+	if user == nil {
+		return
+	}
+	resp(c, http.StatusOK, toSCIMUser(c, user))
+}
+
+func checkUsername(c *gin.Context, username string, self uint) bool {
+	if username == "" {
+		errorResp(c, http.StatusBadRequest, errTypeInvalidValue, "userName is required")
+		return false
+	}
+	if exist, err := op.GetUserByName(username); err == nil && exist.ID != self {
+		errorResp(c, http.StatusConflict, errTypeUniqueness, "userName already exists")
+		return false
+	}
+	return true
+}
+
+func CreateUser(c *gin.Context) {
+	var req User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, errTypeInvalidSyntax, err.Error())
+		return
+	}
+	if !checkUsername(c, req.UserName, 0) {
+		return
+	}
+	user := &model.User{
+		Username:   req.UserName,
+		Permission: int32(setting.GetInt(conf.SSODefaultPermission, 0)),
+		BasePath:   setting.GetStr(conf.SSODefaultDir),
+		Role:       model.GENERAL,
+		Disabled:   req.Active != nil && !*req.Active,
+		SsoID:      req.ExternalID,
+		Authn:      "[]",
+	}
+	user.SetPassword(random.String(16))
+	if err := op.CreateUser(user); err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	utils.Log.Infof("[scim] user %s created", user.Username)
+	resp(c, http.StatusCreated, toSCIMUser(c, user))
+}
+
+func ReplaceUser(c *gin.Context) {
+	user := getUser(c)
+	if user == nil {
+		return
+	}
+	var req User
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, errTypeInvalidSyntax, err.Error())
+		return
+	}
+	if !checkUsername(c, req.UserName, user.ID) {
+		return
+	}
+	user.Username = req.UserName
+	user.SsoID = req.ExternalID
+	if req.Active != nil {
+		user.Disabled = !*req.Active
+	}
+	updateUser(c, user)
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// parseBool accepts both JSON booleans and the "True"/"False" strings sent by some providers
+func parseBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(s)
+}
+
+func applyAttr(user *model.User, attr string, raw json.RawMessage, remove bool) (string, error) {
+	switch strings.ToLower(attr) {
+	case "active":
+		if remove {
+			return errTypeMutability, fmt.Errorf("active can not be removed")
+		}
+		active, err := parseBool(raw)
+		if err != nil {
+			return errTypeInvalidValue, fmt.Errorf("invalid active value: %s", raw)
+		}
+		user.Disabled = !active
+	case "username":
+		var username string
+		if remove || json.Unmarshal(raw, &username) != nil || username == "" {
+			return errTypeInvalidValue, fmt.Errorf("invalid userName value")
+		}
+		user.Username = username
+	case "externalid":
+		var externalID string
+		if !remove && json.Unmarshal(raw, &externalID) != nil {
+			return errTypeInvalidValue, fmt.Errorf("invalid externalId value: %s", raw)
+		}
+		user.SsoID = externalID
+	default:
+		// attributes OpenList does not store (name, emails, ...) are ignored
+	}
+	return "", nil
+}
+
+func PatchUser(c *gin.Context) {
+	user := getUser(c)
+	if user == nil {
+		return
+	}
+	var req PatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResp(c, http.StatusBadRequest, errTypeInvalidSyntax, err.Error())
+		return
+	}
+	username := user.Username
+	for _, operation := range req.Operations {
+		opName := strings.ToLower(operation.Op)
+		if opName != "add" && opName != "replace" && opName != "remove" {
+			errorResp(c, http.StatusBadRequest, errTypeInvalidSyntax, "unsupported patch op: "+operation.Op)
+			return
+		}
+		remove := opName == "remove"
+		if operation.Path != "" {
+			if scimType, err := applyAttr(user, operation.Path, operation.Value, remove); err != nil {
+				errorResp(c, http.StatusBadRequest, scimType, err.Error())
+				return
+			}
+			continue
+		}
+		if remove {
+			errorResp(c, http.StatusBadRequest, errTypeNoTarget, "path is required for remove")
+			return
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			errorResp(c, http.StatusBadRequest, errTypeInvalidValue, "value must be an object when path is omitted")
+			return
+		}
+		for attr, raw := range values {
+			if scimType, err := applyAttr(user, attr, raw, false); err != nil {
+				errorResp(c, http.StatusBadRequest, scimType, err.Error())
+				return
+			}
+		}
+	}
+	if user.Username != username && !checkUsername(c, user.Username, user.ID) {
+		return
+	}
+	updateUser(c, user)
+}
+
+func updateUser(c *gin.Context, user *model.User) {
+	if err := op.UpdateUser(user); err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	utils.Log.Infof("[scim] user %s updated, disabled: %t", user.Username, user.Disabled)
+	resp(c, http.StatusOK, toSCIMUser(c, user))
+}
+
+func DeleteUser(c *gin.Context) {
+	user := getUser(c)
+	if user == nil {
+		return
+	}
+	if err := op.DeleteUserById(user.ID); err != nil {
+		errorResp(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	utils.Log.Infof("[scim] user %s deleted", user.Username)
+	c.Status(http.StatusNoContent)
+}
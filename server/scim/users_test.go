@@ -0,0 +1,38 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestParseFilter(t *testing.T) {
+	username, externalID, err := parseFilter(`userName eq "alice"`)
+	if err != nil || username != "alice" || externalID != "" {
+		t.Errorf("unexpected result: %q %q %v", username, externalID, err)
+	}
+	username, externalID, err = parseFilter(`externalId EQ "a\"b"`)
+	if err != nil || username != "" || externalID != `a"b` {
+		t.Errorf("unexpected result: %q %q %v", username, externalID, err)
+	}
+	if _, _, err = parseFilter(`userName sw "a"`); err == nil {
+		t.Errorf("expect error for unsupported operator")
+	}
+}
+
+func TestApplyAttr(t *testing.T) {
+	u := &model.User{Username: "alice"}
+	for _, raw := range []string{`false`, `"False"`} {
+		u.Disabled = false
+		if _, err := applyAttr(u, "active", json.RawMessage(raw), false); err != nil || !u.Disabled {
+			t.Errorf("expect %s to disable user, err: %v", raw, err)
+		}
+	}
+	if _, err := applyAttr(u, "userName", json.RawMessage(`""`), false); err == nil {
+		t.Errorf("expect error for empty userName")
+	}
+	if _, err := applyAttr(u, "name.givenName", json.RawMessage(`"Alice"`), false); err != nil {
+		t.Errorf("expect unknown attributes to be ignored, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/davsession"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/traffic"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/OpenList/v4/server/middlewares"
 
@@ -30,7 +31,7 @@ func WebDav(dav *gin.RouterGroup) {
 			log.Errorf("%s %s %+v", request.Method, request.URL.Path, err)
 		},
 	}
-	dav.Use(WebDAVAuth)
+	dav.Use(WebDAVAuth, middlewares.TrafficProtocol(traffic.ProtocolWebDAV))
 	uploadLimiter := middlewares.UploadRateLimiter(stream.ClientUploadLimit)
 	downloadLimiter := middlewares.DownloadRateLimiter(stream.ClientDownloadLimit)
 	dav.Any("/*path", uploadLimiter, downloadLimiter, ServeWebDAV)
@@ -114,7 +115,7 @@ func WebDAVAuth(c *gin.Context) {
 	}
 	// at least auth is successful till here
 	model.LoginCache.Del(ip)
-	if user.Disabled || !user.CanWebdavRead() {
+	if user.Disabled || !user.CanWebdavRead() || common.NeedEnroll2FA(user) {
 		if c.Request.Method == "OPTIONS" {
 			common.GinWithValue(c, conf.UserKey, guest)
 			c.Next()
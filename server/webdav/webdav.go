@@ -18,6 +18,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/blockcache"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/net"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
@@ -267,6 +268,7 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request) (sta
 		return http.StatusInternalServerError, err
 	}
 	defer link.Close()
+	link = blockcache.Link(storage.GetStorage(), reqPath, fi, "", link)
 
 	if storage.GetStorage().ProxyRange {
 		link = common.ProxyRange(ctx, link, fi.GetSize())